@@ -1,36 +1,49 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"github.com/daariikk/MyHelp/services/account-service/internal/api/response"
+	"github.com/daariikk/MyHelp/services/account-service/internal/api/rest/helper"
+	"github.com/daariikk/MyHelp/services/account-service/internal/lib/audit"
 	"log/slog"
 	"net/http"
 	"strconv"
 )
 
 type DeletePatientWrapper interface {
-	DeletePatientById(int) (bool, error)
+	DeletePatientById(context.Context, int) (bool, error)
 }
 
-func DeletePatientHandler(logger *slog.Logger, wrapper DeletePatientWrapper) func(http.ResponseWriter, *http.Request) {
+func DeletePatientHandler(logger *slog.Logger, wrapper DeletePatientWrapper, recorder *audit.Recorder) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Debug("DeletePatientHandler starting...")
+		logger.DebugContext(r.Context(), "DeletePatientHandler starting...")
 
-		patientIDStr := r.URL.Query().Get("patientID")
-		patientID, err := strconv.ParseInt(patientIDStr, 10, 64)
+		patientID, err := helper.PatientID(r)
+		if err != nil {
+			response.SendFailureResponse(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
 
-		logger.Debug("Handling DELETE patient request for patient", "patientID", patientID)
+		logger.DebugContext(r.Context(), "Handling DELETE patient request for patient", "patientID", patientID)
 
-		isDeleted, err := wrapper.DeletePatientById(int(patientID))
+		isDeleted, err := wrapper.DeletePatientById(r.Context(), patientID)
 		if err != nil {
 			response.SendFailureResponse(w, "Error deleting patient: "+err.Error(), http.StatusInternalServerError)
+			return
 		}
 
 		if isDeleted {
+			recorder.Record(r, audit.Event{
+				Action:     "patient.delete",
+				TargetType: "patient",
+				TargetID:   strconv.Itoa(patientID),
+				Changes:    audit.Diff(map[string]bool{"is_deleted": false}, map[string]bool{"is_deleted": true}),
+			})
 			response.SendSuccessResponse(w, fmt.Sprintf("Patient with patientID=%v deleted", patientID), http.StatusNoContent)
 		} else {
 			response.SendSuccessResponse(w, fmt.Sprintf("Patient with patientID=%v not found", patientID), http.StatusNoContent)
 		}
-		logger.Info("DeletePatientHandler works successful")
+		logger.InfoContext(r.Context(), "DeletePatientHandler works successful")
 	}
 }
@@ -1,45 +1,51 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/daariikk/MyHelp/services/account-service/internal/api/response"
+	"github.com/daariikk/MyHelp/services/account-service/internal/api/rest/helper"
 	"github.com/daariikk/MyHelp/services/account-service/internal/domain"
 	"github.com/daariikk/MyHelp/services/account-service/internal/lib/logger/sl"
 	"github.com/daariikk/MyHelp/services/account-service/internal/repository"
 	"log/slog"
 	"net/http"
-	"strconv"
 )
 
 type GetPatientWrapper interface {
-	GetPatientById(int) (domain.Patient, error)
-	GetAppointmentByPatientId(int) ([]domain.Appointment, error)
+	GetPatientById(context.Context, int) (domain.Patient, error)
+	GetAppointmentByPatientId(context.Context, int) ([]domain.Appointment, error)
 }
 
 func GetPatientByIdHandler(logger *slog.Logger, wrapper GetPatientWrapper) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Debug("GetPatientByIdHandler starting...")
+		logger.DebugContext(r.Context(), "GetPatientByIdHandler starting...")
 
-		patientIDStr := r.URL.Query().Get("patientID")
-		patientID, err := strconv.ParseInt(patientIDStr, 10, 64)
+		patientID, err := helper.PatientID(r)
+		if err != nil {
+			logger.DebugContext(r.Context(), "Patient identity is not provided", sl.Err(err))
+			response.SendFailureResponse(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
 
-		logger.Debug("Handling GET patient request for patient", "patientID", patientID)
+		logger.DebugContext(r.Context(), "Handling GET patient request for patient", "patientID", patientID)
 
-		patient, err := wrapper.GetPatientById(int(patientID))
+		patient, err := wrapper.GetPatientById(r.Context(), patientID)
 		if err != nil {
 			if errors.Is(err, repository.ErrorNotFound) {
-				logger.Debug("Patient not found", sl.Err(err))
+				logger.DebugContext(r.Context(), "Patient not found", sl.Err(err))
 				response.SendFailureResponse(w, fmt.Sprintf("Patient with patientID=%v not found", patientID), http.StatusNotFound)
 			} else {
-				logger.Debug(fmt.Sprintf("Error get info for patient with patientID=%v", patientID), sl.Err(err))
+				logger.DebugContext(r.Context(), fmt.Sprintf("Error get info for patient with patientID=%v", patientID), sl.Err(err))
 				response.SendFailureResponse(w, "Failed to get patient", http.StatusInternalServerError)
 			}
+			return
 		}
 
-		appointments, err := wrapper.GetAppointmentByPatientId(int(patientID))
+		appointments, err := wrapper.GetAppointmentByPatientId(r.Context(), patientID)
 		if err != nil {
-			logger.Debug(fmt.Sprintf("Error get appointment by patient with patientID=%v", patientID), sl.Err(err))
+			logger.DebugContext(r.Context(), fmt.Sprintf("Error get appointment by patient with patientID=%v", patientID), sl.Err(err))
 		}
 
 		formattedAppointments := make([]domain.AppointmentDTO, len(appointments))
@@ -68,7 +74,7 @@ func GetPatientByIdHandler(logger *slog.Logger, wrapper GetPatientWrapper) func(
 			Appointments: formattedAppointments,
 		}
 
-		logger.Info("GetPatientByIdHandler works successful")
+		logger.InfoContext(r.Context(), "GetPatientByIdHandler works successful")
 
 		response.SendSuccessResponse(w, patientInfo, http.StatusOK)
 	}
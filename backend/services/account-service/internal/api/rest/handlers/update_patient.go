@@ -1,26 +1,33 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/daariikk/MyHelp/services/account-service/internal/api/response"
+	"github.com/daariikk/MyHelp/services/account-service/internal/api/rest/helper"
 	"github.com/daariikk/MyHelp/services/account-service/internal/domain"
+	"github.com/daariikk/MyHelp/services/account-service/internal/lib/audit"
 	"log/slog"
 	"net/http"
 	"strconv"
 )
 
 type UpdatePatientWrapper interface {
-	UpdatePatientById(domain.Patient) (domain.Patient, error)
+	GetPatientById(context.Context, int) (domain.Patient, error)
+	UpdatePatientById(context.Context, domain.Patient) (domain.Patient, error)
 }
 
-func UpdatePatientInfoHandler(logger *slog.Logger, wrapper UpdatePatientWrapper) func(http.ResponseWriter, *http.Request) {
+func UpdatePatientInfoHandler(logger *slog.Logger, wrapper UpdatePatientWrapper, recorder *audit.Recorder) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Debug("UpdatePatientInfoHandler starting...")
+		logger.DebugContext(r.Context(), "UpdatePatientInfoHandler starting...")
 
-		patientIDStr := r.URL.Query().Get("patientID")
-		patientID, err := strconv.ParseInt(patientIDStr, 10, 64)
+		patientID, err := helper.PatientID(r)
+		if err != nil {
+			response.SendFailureResponse(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
 
-		logger.Debug("Handling UPDATE patient request for patient", "patientID", patientID)
+		logger.DebugContext(r.Context(), "Handling UPDATE patient request for patient", "patientID", patientID)
 
 		var patient domain.Patient
 		err = json.NewDecoder(r.Body).Decode(&patient)
@@ -29,16 +36,31 @@ func UpdatePatientInfoHandler(logger *slog.Logger, wrapper UpdatePatientWrapper)
 			return
 		}
 
-		patient.Id = int(patientID)
-		updatedPatient, err := wrapper.UpdatePatientById(patient)
+		// Прежние данные нужны для журнала аудита
+		before, err := wrapper.GetPatientById(r.Context(), patientID)
+		if err != nil {
+			response.SendFailureResponse(w, "Error updating patient: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		patient.Id = patientID
+		updatedPatient, err := wrapper.UpdatePatientById(r.Context(), patient)
 
-		logger.Debug("updatedPatient", "updatedPatient", updatedPatient)
+		logger.DebugContext(r.Context(), "updatedPatient", "updatedPatient", updatedPatient)
 
 		if err != nil {
 			response.SendFailureResponse(w, "Error updating patient: "+err.Error(), http.StatusInternalServerError)
+			return
 		}
 
-		logger.Info("UpdatePatientInfoHandler works successful")
+		recorder.Record(r, audit.Event{
+			Action:     "patient.update",
+			TargetType: "patient",
+			TargetID:   strconv.Itoa(patientID),
+			Changes:    audit.Diff(before, updatedPatient),
+		})
+
+		logger.InfoContext(r.Context(), "UpdatePatientInfoHandler works successful")
 		response.SendSuccessResponse(w, updatedPatient, http.StatusOK)
 	}
 }
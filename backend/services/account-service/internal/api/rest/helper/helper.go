@@ -1,6 +1,10 @@
 package helper
 
-import "net/http"
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
 
 func CopyHeaders(dst, src http.Header) {
 	for key, values := range src {
@@ -9,3 +13,21 @@ func CopyHeaders(dst, src http.Header) {
 		}
 	}
 }
+
+// HeaderPatientID выставляет api-gateway после проверки access токена
+const HeaderPatientID = "X-Patient-ID"
+
+// PatientID возвращает id пациента, проверенный api-gateway
+func PatientID(r *http.Request) (int, error) {
+	value := r.Header.Get(HeaderPatientID)
+	if value == "" {
+		return 0, errors.New("patient identity is missing")
+	}
+
+	patientID, err := strconv.Atoi(value)
+	if err != nil || patientID <= 0 {
+		return 0, errors.New("patient identity is invalid")
+	}
+
+	return patientID, nil
+}
@@ -3,19 +3,40 @@ package api
 import (
 	"github.com/daariikk/MyHelp/services/account-service/internal/api/rest/handlers"
 	"github.com/daariikk/MyHelp/services/account-service/internal/config"
+	"github.com/daariikk/MyHelp/services/account-service/internal/lib/audit"
+	"github.com/daariikk/MyHelp/services/account-service/internal/lib/health"
+	"github.com/daariikk/MyHelp/services/jwtverify"
+	"github.com/daariikk/MyHelp/services/account-service/internal/lib/metrics"
+	"github.com/daariikk/MyHelp/services/account-service/internal/lib/requestid"
+	"github.com/daariikk/MyHelp/services/account-service/internal/lib/tracing"
 	"github.com/daariikk/MyHelp/services/account-service/internal/repository/postgres"
 	"github.com/go-chi/chi/v5"
 	"log/slog"
 )
 
-func NewRouter(cfg *config.Config, logger *slog.Logger, storage *postgres.Storage) *chi.Mux {
+// Имя сервиса в журнале аудита
+const auditService = "account-service"
+
+func NewRouter(cfg *config.Config, logger *slog.Logger, storage *postgres.Storage, checker *health.Checker, verifier *jwtverify.Verifier) *chi.Mux {
 	router := chi.NewRouter()
-	router.Use(handlers.CorsMiddleware)
+	router.Use(requestid.Middleware)
+	router.Use(tracing.Middleware)
+	router.Use(metrics.Middleware)
+
+	// Пробы оркестратора проходят без токена
+	router.Get("/healthz", checker.LivenessHandler())
+	router.Get("/readyz", checker.ReadinessHandler())
+
+	router.Group(func(r chi.Router) {
+		r.Use(handlers.AuthMiddleware(logger, verifier))
+
+		recorder := audit.New(auditService, storage, logger, cfg.TrustedProxies)
 
-	router.Route("/MyHelp/account", func(r chi.Router) {
-		r.Get("/", handlers.GetPatientByIdHandler(logger, storage))
-		r.Put("/", handlers.UpdatePatientInfoHandler(logger, storage))
-		r.Delete("/", handlers.DeletePatientHandler(logger, storage))
+		r.Route("/MyHelp/account", func(r chi.Router) {
+			r.Get("/", handlers.GetPatientByIdHandler(logger, storage))
+			r.Put("/", handlers.UpdatePatientInfoHandler(logger, storage, recorder))
+			r.Delete("/", handlers.DeletePatientHandler(logger, storage, recorder))
+		})
 	})
 
 	return router
@@ -7,7 +7,6 @@ type Patient struct {
 	Patronymic string `json:"patronymic"`
 	Polic      string `json:"polic"`
 	Email      string `json:"email"`
-	Password   string `json:"password"`
 	IsDeleted  bool   `json:"is_deleted"`
 }
 
@@ -18,7 +17,6 @@ type PatientDTO struct {
 	Patronymic   string           `json:"patronymic"`
 	Polic        string           `json:"polic"`
 	Email        string           `json:"email"`
-	Password     string           `json:"password"`
 	IsDeleted    bool             `json:"is_deleted"`
 	Appointments []AppointmentDTO `json:"appointments"`
 }
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type PoolStater interface {
+	Stat() *pgxpool.Stat
+}
+
+// RegisterPool добавляет в Registry статистику пула соединений pgx.
+// Значения читаются в момент запроса /metrics.
+func RegisterPool(pool PoolStater) {
+	Registry.MustRegister(&poolCollector{pool: pool})
+}
+
+var (
+	poolAcquiredConns = poolDesc("acquired_conns", "Connections currently in use.")
+	poolIdleConns     = poolDesc("idle_conns", "Idle connections in the pool.")
+	poolTotalConns    = poolDesc("total_conns", "Total connections in the pool.")
+	poolMaxConns      = poolDesc("max_conns", "Maximum size of the pool.")
+	poolAcquires      = poolDesc("acquires_total", "Successful connection acquisitions.")
+	poolEmptyAcquires = poolDesc("empty_acquires_total", "Acquisitions that had to wait for a connection.")
+	poolCanceled      = poolDesc("canceled_acquires_total", "Acquisitions canceled by context.")
+	poolAcquireTime   = poolDesc("acquire_duration_seconds_total", "Total time spent acquiring connections.")
+)
+
+func poolDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pgx_pool", name), help, nil, nil)
+}
+
+type poolCollector struct {
+	pool PoolStater
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		poolAcquiredConns, poolIdleConns, poolTotalConns, poolMaxConns,
+		poolAcquires, poolEmptyAcquires, poolCanceled, poolAcquireTime,
+	} {
+		ch <- desc
+	}
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(poolAcquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolCanceled, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireTime, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
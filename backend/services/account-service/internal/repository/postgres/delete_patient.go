@@ -6,7 +6,7 @@ import (
 	"github.com/pkg/errors"
 )
 
-func (s *Storage) DeletePatientById(patientID int) (bool, error) {
+func (s *Storage) DeletePatientById(ctx context.Context, patientID int) (bool, error) {
 	var isDeleted bool
 
 	query := `
@@ -14,7 +14,7 @@ func (s *Storage) DeletePatientById(patientID int) (bool, error) {
 	SET is_deleted=true
 	WHERE id=$1
 `
-	_, err := s.connection.Exec(context.Background(), query, patientID)
+	_, err := s.connection.Exec(ctx, query, patientID)
 	if err != nil {
 		s.logger.Error("Failed to deleted account", "patientId", patientID, "error", err)
 		return false, err
@@ -26,7 +26,7 @@ func (s *Storage) DeletePatientById(patientID int) (bool, error) {
 	WHERE id=$1
 `
 
-	err = s.connection.QueryRow(context.Background(), query, patientID).Scan(
+	err = s.connection.QueryRow(ctx, query, patientID).Scan(
 		&isDeleted,
 	)
 	if err != nil {
@@ -5,34 +5,72 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/daariikk/MyHelp/services/account-service/internal/domain"
+	"github.com/daariikk/MyHelp/services/account-service/internal/lib/tracing"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pkg/errors"
 	"log/slog"
 )
 
 type Storage struct {
-	connection *pgx.Conn
+	connection *pgxpool.Pool
 	logger     *slog.Logger
 }
 
 func New(ctx context.Context, logger *slog.Logger, url string) (*Storage, error) {
-	conn, err := pgx.Connect(ctx, url)
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		logger.Error("Failed to parse postgres connection string", "error", err)
+		return nil, errors.Wrap(err, "failed to parse postgres connection string")
+	}
+	// Каждый запрос попадает в трассу отдельным спаном
+	config.ConnConfig.Tracer = tracing.QueryTracer{}
+
+	conn, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		logger.Error("Failed to connect to postgres", "error", err)
 		return nil, errors.Wrapf(err, "failed to connect to postgres")
 	}
+	// Пул подключается лениво, без проверки сервис стартовал бы с недоступной БД
+	if err = conn.Ping(ctx); err != nil {
+		conn.Close()
+		logger.Error("Failed to ping postgres", "error", err)
+		return nil, errors.Wrap(err, "failed to ping postgres")
+	}
 
 	return &Storage{conn, logger}, nil
 }
 
 func (s *Storage) Close() error {
 	if s.connection != nil {
-		return s.connection.Close(context.Background())
+		s.connection.Close()
 	}
 	return nil
 }
 
-func (s *Storage) GetPatientById(patientID int) (domain.Patient, error) {
+// Stat возвращает статистику пула соединений для метрик
+func (s *Storage) Stat() *pgxpool.Stat {
+	return s.connection.Stat()
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	return s.connection.Ping(ctx)
+}
+
+// MigrationVersion возвращает версию схемы из таблицы schema_migrations, которую ведет migrate
+func (s *Storage) MigrationVersion(ctx context.Context) (int, bool, error) {
+	var (
+		version int
+		dirty   bool
+	)
+	err := s.connection.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		return 0, false, errors.Wrap(err, "failed to get migration version")
+	}
+	return version, dirty, nil
+}
+
+func (s *Storage) GetPatientById(ctx context.Context, patientID int) (domain.Patient, error) {
 	query := `
 		SELECT id, surname, name, patronymic, email, polic, is_deleted
 		FROM patients 
@@ -40,7 +78,7 @@ func (s *Storage) GetPatientById(patientID int) (domain.Patient, error) {
 `
 	var patient domain.Patient
 	var surname, name, patronymic sql.NullString
-	err := s.connection.QueryRow(context.Background(), query, patientID).Scan(
+	err := s.connection.QueryRow(ctx, query, patientID).Scan(
 		&patient.Id,
 		&surname,
 		&name,
@@ -78,24 +116,9 @@ func (s *Storage) GetPatientById(patientID int) (domain.Patient, error) {
 	return patient, nil
 }
 
-func (s *Storage) GetAppointmentByPatientId(patientID int) ([]domain.Appointment, error) {
+func (s *Storage) GetAppointmentByPatientId(ctx context.Context, patientID int) ([]domain.Appointment, error) {
 	s.logger.Debug("GetAppointmentByPatientId starting...")
 
-	// Сначала обновляем статусы прошедших записей для этого пациента
-	updateQuery := `
-        UPDATE appointments
-        SET status_id = $2
-        WHERE patient_id = $1
-        AND (date < CURRENT_DATE OR (date = CURRENT_DATE AND time < CURRENT_TIME))
-        AND status_id NOT IN (2, 3)  -- Не обновляем уже завершенные или отмененные
-    `
-
-	_, err := s.connection.Exec(context.Background(), updateQuery, patientID, domain.COMPLETED)
-	if err != nil {
-		s.logger.Error("Failed to update appointment statuses", "error", err)
-		return nil, fmt.Errorf("failed to update appointment statuses: %w", err)
-	}
-
 	query := `
         SELECT appointments.id, 
                CONCAT(doctors.surname, ' ', doctors.name, ' ', doctors.patronymic) AS doctor_fio,
@@ -113,7 +136,7 @@ func (s *Storage) GetAppointmentByPatientId(patientID int) ([]domain.Appointment
 
 	s.logger.Debug("Executing query with patientID", "patientID", patientID)
 
-	rows, err := s.connection.Query(context.Background(), query, patientID)
+	rows, err := s.connection.Query(ctx, query, patientID)
 	if err != nil {
 		s.logger.Error("Failed to execute query", "error", err)
 		return nil, fmt.Errorf("failed to execute query: %w", err)
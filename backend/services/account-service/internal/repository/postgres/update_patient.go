@@ -5,7 +5,7 @@ import (
 	"github.com/daariikk/MyHelp/services/account-service/internal/domain"
 )
 
-func (s *Storage) UpdatePatientById(patient domain.Patient) (domain.Patient, error) {
+func (s *Storage) UpdatePatientById(ctx context.Context, patient domain.Patient) (domain.Patient, error) {
 
 	var updatedPatient domain.Patient
 
@@ -18,7 +18,7 @@ func (s *Storage) UpdatePatientById(patient domain.Patient) (domain.Patient, err
 	    polic=$5
 	WHERE id=$6
 `
-	_, err := s.connection.Exec(context.Background(), query,
+	_, err := s.connection.Exec(ctx, query,
 		patient.Surname,
 		patient.Name,
 		patient.Patronymic,
@@ -31,7 +31,7 @@ func (s *Storage) UpdatePatientById(patient domain.Patient) (domain.Patient, err
 		return domain.Patient{}, err
 	}
 
-	updatedPatient, err = s.GetPatientById(patient.Id)
+	updatedPatient, err = s.GetPatientById(ctx, patient.Id)
 
 	return updatedPatient, nil
 }
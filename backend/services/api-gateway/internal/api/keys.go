@@ -0,0 +1,40 @@
+package api
+
+import (
+	"fmt"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/config"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/tokens"
+	"os"
+)
+
+// loadKeyRing читает ключи подписи из PEM файлов или переменных окружения
+func loadKeyRing(cfg config.JWT) (*tokens.KeyRing, error) {
+	keys := make([]tokens.SigningKey, 0, len(cfg.Keys))
+
+	for _, keyCfg := range cfg.Keys {
+		var data []byte
+		switch {
+		case keyCfg.Env != "":
+			data = []byte(os.Getenv(keyCfg.Env))
+			if len(data) == 0 {
+				return nil, fmt.Errorf("key %q: environment variable %s is empty", keyCfg.ID, keyCfg.Env)
+			}
+		case keyCfg.File != "":
+			var err error
+			data, err = os.ReadFile(keyCfg.File)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", keyCfg.ID, err)
+			}
+		default:
+			return nil, fmt.Errorf("key %q: file or env must be set", keyCfg.ID)
+		}
+
+		key, err := tokens.ParseKey(keyCfg.ID, keyCfg.Algorithm, data)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return tokens.NewKeyRing(cfg.ActiveKeyID, keys)
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/api/rest/handlers"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"net/http"
+)
+
+var (
+	anyRole     = []string{domain.RolePatient, domain.RoleAdmin, domain.RoleDoctor}
+	patientOnly = []string{domain.RolePatient}
+	adminOnly   = []string{domain.RoleAdmin}
+)
+
+// Таблица доступа к проксируемым маршрутам и административным маршрутам gateway.
+// Маршрут, которого нет в таблице, запрещен.
+// Проверку "пациент работает только со своими данными" выполняют сами сервисы по X-Patient-ID.
+var routePolicies = []handlers.RoutePolicy{
+	// Личный кабинет пациента
+	{Method: http.MethodGet, Pattern: "/api/v1/account", Roles: patientOnly},
+	{Method: http.MethodPut, Pattern: "/api/v1/account", Roles: patientOnly},
+	{Method: http.MethodDelete, Pattern: "/api/v1/account", Roles: patientOnly},
+
+	// Записи к врачу
+	{Method: http.MethodGet, Pattern: "/api/v1/appointments", Roles: []string{domain.RolePatient, domain.RoleAdmin}},
+	{Method: http.MethodPost, Pattern: "/api/v1/appointments", Roles: []string{domain.RolePatient, domain.RoleAdmin}},
+	{Method: http.MethodPatch, Pattern: "/api/v1/appointments/{appointmentID}", Roles: patientOnly},
+	{Method: http.MethodDelete, Pattern: "/api/v1/appointments/{appointmentID}", Roles: []string{domain.RolePatient, domain.RoleAdmin}},
+	{Method: http.MethodPost, Pattern: "/api/v1/appointments/{appointmentID}/reschedule", Roles: []string{domain.RolePatient, domain.RoleAdmin}},
+	{Method: http.MethodPost, Pattern: "/api/v1/appointments/{appointmentID}/status", Roles: []string{domain.RoleAdmin, domain.RoleDoctor}},
+
+	// Специализации
+	{Method: http.MethodGet, Pattern: "/api/v1/specializations", Roles: anyRole},
+	{Method: http.MethodGet, Pattern: "/api/v1/specializations/{specializationID}", Roles: anyRole},
+	{Method: http.MethodPost, Pattern: "/api/v1/specializations", Roles: adminOnly},
+	{Method: http.MethodDelete, Pattern: "/api/v1/specializations/{specializationID}", Roles: adminOnly},
+
+	// Врачи
+	{Method: http.MethodPost, Pattern: "/api/v1/doctors", Roles: adminOnly},
+	{Method: http.MethodDelete, Pattern: "/api/v1/doctors/{doctorID}", Roles: adminOnly},
+
+	// Расписание врачей
+	{Method: http.MethodGet, Pattern: "/api/v1/schedule/doctors/{doctorID}", Roles: anyRole},
+	{Method: http.MethodPost, Pattern: "/api/v1/schedule/doctors/{doctorID}", Roles: adminOnly},
+
+	// Снятие блокировки входа
+	{Method: http.MethodPost, Pattern: "/api/v1/auth/unlock", Roles: adminOnly},
+
+	// Поиск пациентов
+	{Method: http.MethodGet, Pattern: "/api/v1/users", Roles: adminOnly},
+
+	// Журнал аудита
+	{Method: http.MethodGet, Pattern: "/api/v1/audit", Roles: adminOnly},
+	{Method: http.MethodGet, Pattern: "/api/v1/audit/export", Roles: adminOnly},
+
+	// Управление администраторами (роль super_admin дополнительно проверяет SuperAdminMiddleware)
+	{Method: http.MethodGet, Pattern: "/api/v1/admins", Roles: adminOnly},
+	{Method: http.MethodPost, Pattern: "/api/v1/admins", Roles: adminOnly},
+	{Method: http.MethodPatch, Pattern: "/api/v1/admins/{adminID}", Roles: adminOnly},
+	{Method: http.MethodPut, Pattern: "/api/v1/admins/me/password", Roles: adminOnly},
+	{Method: http.MethodPut, Pattern: "/api/v1/admins/doctors/{doctorID}/credentials", Roles: adminOnly},
+}
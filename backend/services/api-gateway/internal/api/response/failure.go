@@ -9,17 +9,21 @@ const ContentTypeJSON = "application/json"
 
 type FailureResponse struct {
 	Status  string `json:"status"`
+	Code    string `json:"code,omitempty"`
 	Message string `json:"message"`
 }
 
 func SendFailureResponse(w http.ResponseWriter, message string, statusCode int) {
+	SendFailureResponseWithCode(w, "", message, statusCode)
+}
+
+// SendFailureResponseWithCode добавляет к ответу машиночитаемый код ошибки
+func SendFailureResponseWithCode(w http.ResponseWriter, code string, message string, statusCode int) {
 	w.Header().Set("Content-Type", ContentTypeJSON)
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(FailureResponse{
 		Status:  "failure",
+		Code:    code,
 		Message: message,
 	})
 }
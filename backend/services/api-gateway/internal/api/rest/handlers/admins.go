@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/api/response"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/config"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/audit"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/notify"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/password"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/randtoken"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository"
+	"github.com/go-chi/chi/v5"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type AdminGetter interface {
+	GetAdminByID(context.Context, int) (domain.Admin, error)
+}
+
+type AdminWrapper interface {
+	AdminGetter
+	ListAdmins(context.Context) ([]domain.Admin, error)
+	InviteAdmin(context.Context, domain.Admin, string, time.Time) (domain.Admin, error)
+	UpdateAdmin(context.Context, int, string, bool) (domain.Admin, error)
+	ChangeAdminPassword(context.Context, int, string) error
+	AcceptAdminInvite(context.Context, string, string) (int, error)
+}
+
+// SuperAdminMiddleware пропускает только действующего администратора с ролью super_admin.
+// Роль берется из БД, а не из токена, поэтому снятие роли действует сразу.
+// Должен стоять после AuthMiddleware.
+func SuperAdminMiddleware(logger *slog.Logger, wrapper AdminGetter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, ok := IdentityFromContext(r.Context())
+			if !ok || identity.Role != domain.RoleAdmin {
+				response.SendFailureResponse(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			admin, err := wrapper.GetAdminByID(r.Context(), identity.Subject)
+			if err != nil && !errors.Is(err, repository.ErrorNotFound) {
+				logger.ErrorContext(r.Context(), "Failed to get admin", slog.Int("adminID", identity.Subject), slog.String("error", err.Error()))
+				response.SendFailureResponse(w, "Failed to check admin role", http.StatusInternalServerError)
+				return
+			}
+			if err != nil || !admin.IsActive || admin.AdminRole != domain.AdminRoleSuper {
+				logger.InfoContext(r.Context(), "Super admin role required", slog.Int("adminID", identity.Subject))
+				response.SendFailureResponse(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ListAdminsHandler(logger *slog.Logger, wrapper AdminWrapper) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "ListAdminsHandler starting...")
+
+		admins, err := wrapper.ListAdmins(r.Context())
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to list admins", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to get admins", http.StatusInternalServerError)
+			return
+		}
+
+		logger.InfoContext(r.Context(), "ListAdminsHandler works successful")
+		response.SendSuccessResponse(w, admins, http.StatusOK)
+	}
+}
+
+// InviteAdminHandler создает администратора и отправляет ему ссылку для установки пароля.
+// До принятия приглашения войти под новым администратором нельзя: его пароль никому не известен.
+func InviteAdminHandler(logger *slog.Logger, wrapper AdminWrapper, hasher password.PasswordHasher, notifier notify.Notifier, cfg *config.Config, recorder *audit.Recorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "InviteAdminHandler starting...")
+
+		var request struct {
+			Username  string `json:"username"`
+			Email     string `json:"email"`
+			AdminRole string `json:"admin_role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			response.SendFailureResponse(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		request.Username = strings.TrimSpace(request.Username)
+		request.Email = strings.TrimSpace(request.Email)
+		if request.Username == "" || request.Email == "" {
+			response.SendFailureResponse(w, "Username and email are required", http.StatusBadRequest)
+			return
+		}
+		if request.AdminRole == "" {
+			request.AdminRole = domain.AdminRoleClinic
+		}
+		if !validAdminRole(request.AdminRole) {
+			response.SendFailureResponse(w, "Unknown admin role", http.StatusBadRequest)
+			return
+		}
+
+		// Случайный пароль, который никто не знает, до принятия приглашения
+		placeholder, err := randtoken.New()
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to generate password", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to invite admin", http.StatusInternalServerError)
+			return
+		}
+		placeholderHash, err := hasher.Hash(placeholder)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to hash password", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to invite admin", http.StatusInternalServerError)
+			return
+		}
+
+		token, err := randtoken.New()
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to generate invite token", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to invite admin", http.StatusInternalServerError)
+			return
+		}
+		expiresAt := time.Now().Add(cfg.AdminInvite.TokenTTL)
+
+		admin, err := wrapper.InviteAdmin(r.Context(), domain.Admin{
+			Username:  request.Username,
+			Email:     request.Email,
+			AdminRole: request.AdminRole,
+			Password:  placeholderHash,
+		}, randtoken.Hash(token), expiresAt)
+		if errors.Is(err, repository.ErrorAlreadyExists) {
+			response.SendFailureResponse(w, "Admin with this username or email already exists", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to invite admin", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to invite admin", http.StatusInternalServerError)
+			return
+		}
+		recorder.Record(r, audit.Event{
+			Action:     "admin.invite",
+			TargetType: "admin",
+			TargetID:   strconv.Itoa(admin.Id),
+			Changes:    audit.Diff(nil, admin),
+		})
+
+		err = notifier.Send(r.Context(), notify.Message{
+			To:      admin.Email,
+			Subject: "MyHelp: приглашение администратора",
+			Body: fmt.Sprintf("Вас пригласили администратором MyHelp. Для установки пароля перейдите по ссылке: %s\nСсылка действительна до %s.",
+				fmt.Sprintf(cfg.AdminInvite.LinkFormat, token),
+				expiresAt.Format("02.01.2006 15:04"),
+			),
+		})
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to send admin invite", slog.Int("adminID", admin.Id), slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Admin created, but the invite could not be sent", http.StatusBadGateway)
+			return
+		}
+
+		logger.InfoContext(r.Context(), "InviteAdminHandler works successful", slog.Int("adminID", admin.Id))
+		response.SendSuccessResponse(w, admin, http.StatusCreated)
+	}
+}
+
+// UpdateAdminHandler меняет роль администратора и признак is_active.
+// Свою учетную запись деактивировать или понизить нельзя, чтобы не остаться без super_admin.
+func UpdateAdminHandler(logger *slog.Logger, wrapper AdminWrapper, recorder *audit.Recorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "UpdateAdminHandler starting...")
+
+		identity, ok := IdentityFromContext(r.Context())
+		if !ok {
+			unauthorized(w, "Authorization required")
+			return
+		}
+
+		adminID, err := strconv.Atoi(chi.URLParam(r, "adminID"))
+		if err != nil {
+			response.SendFailureResponse(w, "Invalid adminID", http.StatusBadRequest)
+			return
+		}
+
+		var request struct {
+			AdminRole *string `json:"admin_role"`
+			IsActive  *bool   `json:"isActive"`
+		}
+		if err = json.NewDecoder(r.Body).Decode(&request); err != nil {
+			response.SendFailureResponse(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if request.AdminRole != nil && !validAdminRole(*request.AdminRole) {
+			response.SendFailureResponse(w, "Unknown admin role", http.StatusBadRequest)
+			return
+		}
+
+		admin, err := wrapper.GetAdminByID(r.Context(), adminID)
+		if errors.Is(err, repository.ErrorNotFound) {
+			response.SendFailureResponse(w, "Admin not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to get admin", slog.Int("adminID", adminID), slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to update admin", http.StatusInternalServerError)
+			return
+		}
+
+		before := admin
+		if request.AdminRole != nil {
+			admin.AdminRole = *request.AdminRole
+		}
+		if request.IsActive != nil {
+			admin.IsActive = *request.IsActive
+		}
+		if adminID == identity.Subject && (!admin.IsActive || admin.AdminRole != domain.AdminRoleSuper) {
+			response.SendFailureResponse(w, "Cannot deactivate or demote yourself", http.StatusConflict)
+			return
+		}
+
+		admin, err = wrapper.UpdateAdmin(r.Context(), adminID, admin.AdminRole, admin.IsActive)
+		if errors.Is(err, repository.ErrorNotFound) {
+			response.SendFailureResponse(w, "Admin not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to update admin", slog.Int("adminID", adminID), slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to update admin", http.StatusInternalServerError)
+			return
+		}
+
+		recorder.Record(r, audit.Event{
+			Action:     "admin.update",
+			TargetType: "admin",
+			TargetID:   strconv.Itoa(adminID),
+			Changes:    audit.Diff(before, admin),
+		})
+
+		logger.InfoContext(r.Context(), "UpdateAdminHandler works successful",
+			slog.Int("adminID", adminID),
+			slog.String("admin_role", admin.AdminRole),
+			slog.Bool("isActive", admin.IsActive),
+		)
+		response.SendSuccessResponse(w, admin, http.StatusOK)
+	}
+}
+
+// ChangeAdminPasswordHandler меняет пароль вызывающего администратора.
+// Все его refresh токены при этом отзываются.
+func ChangeAdminPasswordHandler(logger *slog.Logger, wrapper AdminWrapper, hasher password.PasswordHasher, recorder *audit.Recorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "ChangeAdminPasswordHandler starting...")
+
+		identity, ok := IdentityFromContext(r.Context())
+		if !ok || identity.Role != domain.RoleAdmin {
+			response.SendFailureResponse(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		var request struct {
+			CurrentPassword string `json:"current_password"`
+			NewPassword     string `json:"new_password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			response.SendFailureResponse(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if request.CurrentPassword == "" || request.NewPassword == "" {
+			response.SendFailureResponse(w, "Current and new passwords are required", http.StatusBadRequest)
+			return
+		}
+
+		admin, err := wrapper.GetAdminByID(r.Context(), identity.Subject)
+		if err != nil && !errors.Is(err, repository.ErrorNotFound) {
+			logger.ErrorContext(r.Context(), "Failed to get admin", slog.Int("adminID", identity.Subject), slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to change password", http.StatusInternalServerError)
+			return
+		}
+		if err != nil || !admin.IsActive {
+			response.SendFailureResponse(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		ok, _ = checkPassword(r.Context(), logger, hasher, admin.Password, request.CurrentPassword, true)
+		if !ok {
+			response.SendFailureResponse(w, "Current password is incorrect", http.StatusUnauthorized)
+			return
+		}
+
+		hash, err := hasher.Hash(request.NewPassword)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to hash password", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to change password", http.StatusInternalServerError)
+			return
+		}
+
+		if err = wrapper.ChangeAdminPassword(r.Context(), admin.Id, hash); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to change password", slog.Int("adminID", admin.Id), slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to change password", http.StatusInternalServerError)
+			return
+		}
+
+		recorder.Record(r, audit.Event{
+			Action:     "admin.password_change",
+			TargetType: "admin",
+			TargetID:   strconv.Itoa(admin.Id),
+		})
+
+		logger.InfoContext(r.Context(), "ChangeAdminPasswordHandler works successful", slog.Int("adminID", admin.Id))
+		response.SendSuccessResponse(w, "Password has been changed", http.StatusOK)
+	}
+}
+
+// AcceptAdminInviteHandler проверяет токен приглашения и устанавливает пароль администратора
+func AcceptAdminInviteHandler(logger *slog.Logger, wrapper AdminWrapper, hasher password.PasswordHasher, recorder *audit.Recorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "AcceptAdminInviteHandler starting...")
+
+		var request struct {
+			Token    string `json:"token"`
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			response.SendFailureResponse(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if request.Token == "" || request.Password == "" {
+			response.SendFailureResponse(w, "Token and password are required", http.StatusBadRequest)
+			return
+		}
+
+		hash, err := hasher.Hash(request.Password)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to hash password", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to accept invite", http.StatusInternalServerError)
+			return
+		}
+
+		adminID, err := wrapper.AcceptAdminInvite(r.Context(), randtoken.Hash(request.Token), hash)
+		if errors.Is(err, repository.ErrorInvalidToken) {
+			response.SendFailureResponse(w, "Invite token is invalid or expired", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to accept invite", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to accept invite", http.StatusInternalServerError)
+			return
+		}
+
+		recorder.Record(r, audit.Event{
+			ActorRole:  domain.RoleAdmin,
+			ActorID:    adminID,
+			Action:     "admin.invite_accept",
+			TargetType: "admin",
+			TargetID:   strconv.Itoa(adminID),
+		})
+
+		logger.InfoContext(r.Context(), "AcceptAdminInviteHandler works successful", slog.Int("adminID", adminID))
+		response.SendSuccessResponse(w, "Password has been set", http.StatusOK)
+	}
+}
+
+func validAdminRole(role string) bool {
+	return role == domain.AdminRoleSuper || role == domain.AdminRoleClinic
+}
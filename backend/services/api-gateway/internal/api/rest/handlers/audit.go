@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/api/response"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/audit"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type AuditWrapper interface {
+	ListAuditEvents(context.Context, audit.Filter) ([]audit.Event, int, error)
+	ExportAuditEvents(context.Context, audit.Filter, func(audit.Event) error) error
+}
+
+// AuditPage - страница журнала аудита
+type AuditPage struct {
+	Items []audit.Event `json:"items"`
+	Page  int           `json:"page"`
+	Limit int           `json:"limit"`
+	Total int           `json:"total"`
+}
+
+// ListAuditEventsHandler возвращает журнал аудита, новые события первыми.
+// Фильтры: actor_role, actor_id, action, target_type, target_id, from, to (RFC 3339); page, limit.
+func ListAuditEventsHandler(logger *slog.Logger, wrapper AuditWrapper) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "ListAuditEventsHandler starting...")
+
+		query := r.URL.Query()
+		filter, err := auditFilter(query)
+		if err != nil {
+			response.SendFailureResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		page, err := positiveQueryInt(query.Get("page"), 1)
+		if err != nil {
+			response.SendFailureResponse(w, "Invalid page", http.StatusBadRequest)
+			return
+		}
+		limit, err := positiveQueryInt(query.Get("limit"), defaultPageLimit)
+		if err != nil {
+			response.SendFailureResponse(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = min(limit, maxPageLimit)
+		filter.Offset = (page - 1) * filter.Limit
+
+		events, total, err := wrapper.ListAuditEvents(r.Context(), filter)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to list audit events", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to get audit events", http.StatusInternalServerError)
+			return
+		}
+
+		logger.InfoContext(r.Context(), "ListAuditEventsHandler works successful", slog.Int("found", total))
+		response.SendSuccessResponse(w, AuditPage{
+			Items: events,
+			Page:  page,
+			Limit: filter.Limit,
+			Total: total,
+		}, http.StatusOK)
+	}
+}
+
+// ExportAuditEventsHandler выгружает журнал аудита в CSV с теми же фильтрами, что и ListAuditEventsHandler
+func ExportAuditEventsHandler(logger *slog.Logger, wrapper AuditWrapper) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "ExportAuditEventsHandler starting...")
+
+		filter, err := auditFilter(r.URL.Query())
+		if err != nil {
+			response.SendFailureResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="audit_events.csv"`)
+
+		out := csv.NewWriter(w)
+		_ = out.Write([]string{"id", "occurred_at", "service", "actor_role", "actor_id", "action", "target_type", "target_id", "changes", "request_id", "ip"})
+
+		exported := 0
+		err = wrapper.ExportAuditEvents(r.Context(), filter, func(event audit.Event) error {
+			exported++
+			var changes []byte
+			if len(event.Changes) > 0 {
+				changes, _ = json.Marshal(event.Changes)
+			}
+			actorID := ""
+			if event.ActorID != 0 {
+				actorID = strconv.Itoa(event.ActorID)
+			}
+			return writeCSVRecord(out, []string{
+				strconv.FormatInt(event.ID, 10),
+				event.OccurredAt.UTC().Format(time.RFC3339),
+				event.Service,
+				event.ActorRole,
+				actorID,
+				event.Action,
+				event.TargetType,
+				event.TargetID,
+				string(changes),
+				event.RequestID,
+				event.IP,
+			})
+		})
+		out.Flush()
+		// Заголовки уже отправлены, поэтому об ошибке можно только записать в лог
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to export audit events", slog.Int("exported", exported), slog.String("error", err.Error()))
+			return
+		}
+
+		logger.InfoContext(r.Context(), "ExportAuditEventsHandler works successful", slog.Int("exported", exported))
+	}
+}
+
+// writeCSVRecord экранирует значения, которые табличный редактор принял бы за формулу:
+// target_id, changes и другие поля приходят из запросов пользователей
+func writeCSVRecord(out *csv.Writer, record []string) error {
+	for i, value := range record {
+		if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+			record[i] = "'" + value
+		}
+	}
+	return out.Write(record)
+}
+
+func auditFilter(query url.Values) (audit.Filter, error) {
+	filter := audit.Filter{
+		ActorRole:  query.Get("actor_role"),
+		Action:     query.Get("action"),
+		TargetType: query.Get("target_type"),
+		TargetID:   query.Get("target_id"),
+	}
+
+	if value := query.Get("actor_id"); value != "" {
+		actorID, err := strconv.Atoi(value)
+		if err != nil {
+			return audit.Filter{}, errors.New("Invalid actor_id")
+		}
+		filter.ActorID = actorID
+	}
+
+	for name, target := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := query.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return audit.Filter{}, errors.New("Invalid " + name + ": expected RFC 3339 timestamp")
+			}
+			*target = t
+		}
+	}
+
+	return filter, nil
+}
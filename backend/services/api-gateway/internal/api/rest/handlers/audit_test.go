@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/audit"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeAuditEvents []audit.Event
+
+func (f fakeAuditEvents) ListAuditEvents(context.Context, audit.Filter) ([]audit.Event, int, error) {
+	return f, len(f), nil
+}
+
+func (f fakeAuditEvents) ExportAuditEvents(_ context.Context, _ audit.Filter, fn func(audit.Event) error) error {
+	for _, event := range f {
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestExportAuditEventsEscapesFormulas(t *testing.T) {
+	tests := []struct {
+		targetID string
+		want     string
+	}{
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+1+2", "'+1+2"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tcmd", "'\tcmd"},
+		{"\rcmd", "'\rcmd"},
+		{"user@example.com", "user@example.com"},
+		{"42", "42"},
+		{"", ""},
+	}
+
+	events := make(fakeAuditEvents, 0, len(tests))
+	for i, tt := range tests {
+		events = append(events, audit.Event{
+			ID:         int64(i + 1),
+			OccurredAt: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+			Service:    "api-gateway",
+			ActorRole:  "admin",
+			ActorID:    1,
+			Action:     "patient.update",
+			TargetType: "patient",
+			TargetID:   tt.targetID,
+			IP:         "10.0.0.1",
+		})
+	}
+
+	w := httptest.NewRecorder()
+	ExportAuditEventsHandler(testLogger, events).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv error = %v", err)
+	}
+	if len(records) != len(tests)+1 {
+		t.Fatalf("got %d records, want %d", len(records), len(tests)+1)
+	}
+	for i, tt := range tests {
+		// Колонка target_id
+		if got := records[i+1][7]; got != tt.want {
+			t.Errorf("target_id %q exported as %q, want %q", tt.targetID, got, tt.want)
+		}
+	}
+}
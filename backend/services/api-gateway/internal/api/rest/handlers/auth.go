@@ -1,211 +1,265 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"github.com/daariikk/MyHelp/services/api-gateway/internal/api/response"
-	"github.com/daariikk/MyHelp/services/api-gateway/internal/config"
 	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/audit"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/metrics"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/password"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/tokens"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 )
 
 type LoginWrapper interface {
-	GetPassword(string) (int, string, error)
-	GetAdminPassword(string) (int, string, error)
-	GetUser(string) (domain.User, error)
-	GetAdmin(string) (domain.Admin, error)
+	GetPassword(context.Context, string) (int, string, error)
+	GetAdminPassword(context.Context, string) (int, string, error)
+	UpdatePatientPasswordHash(context.Context, int, string) error
+	UpdateAdminPasswordHash(context.Context, int, string) error
+	GetPatientStatus(context.Context, int) (string, error)
+	GetAdminTOTP(context.Context, int) (domain.AdminTOTP, error)
+	RefreshTokenSaver
 }
 
-func LoginHandler(logger *slog.Logger, auth LoginWrapper, cfg *config.Config) http.HandlerFunc {
+// credentials - тело запроса на вход. Доменные модели пароль из JSON не читают.
+type credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func LoginHandler(logger *slog.Logger, auth LoginWrapper, issuer *tokens.Issuer, hasher password.PasswordHasher, limiter *LoginLimiter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Debug("LoginHandler starting...")
+		logger.DebugContext(r.Context(), "LoginHandler starting...")
 
-		request := domain.User{}
+		request := credentials{}
 		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
 
-		logger.Debug("Body успешно распарсен")
+		logger.DebugContext(r.Context(), "Body успешно распарсен")
 
-		logger.Debug("Пытаемся получить пароль по указанному email")
-		patientId, encodedPassword, err := auth.GetPassword(request.Email)
-		if err != nil {
-			logger.Error("Произошла ошибка внутри функции GetPassword")
-			//logger.Error(err.Error())
-			response.SendFailureResponse(w, fmt.Sprintf("Failed to auth user: %s", err), http.StatusInternalServerError)
+		account := accountKey(domain.RolePatient, request.Email)
+		if !limiter.allow(logger, w, r, account) {
 			return
 		}
-		logger.Debug("GetPassword отработала успешно")
-		logger.Debug("patientId и encodedPassword", slog.Int("patientId", patientId), slog.String("encodedPassword", encodedPassword))
 
-		logger.Debug("Пытаемся проверить совпадают ли пароли")
-		logger.Debug("Расшифрованный пароль: ", slog.String("decodedPassword", encodedPassword))
-		logger.Debug("Присланный пароль: ", slog.String("inputPassword", request.Password))
-		if encodedPassword != request.Password {
-			logger.Info("Пароли не совпадают")
-			// logger.Error(err.Error())
-			response.SendFailureResponse(w, fmt.Sprintf("Failed to auth user: %v", err), http.StatusUnauthorized)
+		logger.DebugContext(r.Context(), "Пытаемся получить пароль по указанному email")
+		patientId, encodedPassword, err := auth.GetPassword(r.Context(), request.Email)
+		if err != nil && !errors.Is(err, repository.ErrorNotFound) {
+			logger.ErrorContext(r.Context(), "Произошла ошибка внутри функции GetPassword", slog.String("error", err.Error()))
+			limiter.release(logger, r, account)
+			response.SendFailureResponse(w, "Failed to auth user", http.StatusInternalServerError)
 			return
 		}
-		logger.Debug("Пароль введен успешно")
 
-		logger.Debug("Пытаемся снегерировать токен")
-		accessToken, err := generateAccessToken(cfg, patientId)
-		if err != nil {
-			logger.Error("Произошла ошибка внутри функции generateAccessToken")
-			logger.Error("Failed to generate token", slog.String("error", err.Error()))
-			response.SendFailureResponse(w, "Failed to generate token", http.StatusInternalServerError)
+		logger.DebugContext(r.Context(), "Пытаемся проверить совпадают ли пароли")
+		ok, needsRehash := checkPassword(r.Context(), logger, hasher, encodedPassword, request.Password, err == nil)
+		if !ok {
+			logger.InfoContext(r.Context(), "Неверный email или пароль")
+			metrics.LoginFailures.WithLabelValues(domain.RolePatient, "password").Inc()
+			response.SendFailureResponse(w, invalidCredentials, http.StatusUnauthorized)
 			return
 		}
+		limiter.succeed(logger, r, account)
+		logger.DebugContext(r.Context(), "Пароль введен успешно", slog.Int("patientId", patientId))
 
-		refreshToken, err := generateRefreshToken(cfg, patientId)
+		// Статус проверяется только после верного пароля, чтобы не раскрывать его посторонним
+		status, err := auth.GetPatientStatus(r.Context(), patientId)
 		if err != nil {
-			logger.Error("Произошла ошибка внутри функции generateAccessToken")
-			logger.Error("Failed to generate token", slog.String("error", err.Error()))
-			response.SendFailureResponse(w, "Failed to generate token", http.StatusInternalServerError)
+			logger.ErrorContext(r.Context(), "Failed to get patient status", slog.Int("patientId", patientId), slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to auth user", http.StatusInternalServerError)
 			return
 		}
-
-		logger.Debug("Токен успешно сгенерирован")
-
-		accessLifetime := cfg.JWT.ExpireAccess
-		refreshLifetime := cfg.JWT.ExpireRefresh
-
-		logger.Debug("Access lifetime", slog.Time("access_lifetime", time.Now().Add(accessLifetime)))
-		logger.Debug("Refresh lifetime", slog.Time("refresh_lifetime", time.Now().Add(refreshLifetime)))
-
-		logger.Debug("Формируем ответ")
-		res := map[string]interface{}{
-			"patientID":        patientId,
-			"access_token":     accessToken,
-			"access_lifetime":  time.Now().Add(accessLifetime).Format(time.RFC3339),
-			"refresh_token":    refreshToken,
-			"refresh_lifetime": time.Now().Add(refreshLifetime).Format(time.RFC3339),
+		if status != domain.PatientStatusActive {
+			response.SendFailureResponseWithCode(w, codeEmailNotVerified, "Email is not verified", http.StatusForbidden)
+			return
 		}
 
-		logger.Debug("Сформированный ответ", res)
-
-		logger.Info("LoginHandler works successful")
-		response.SendSuccessResponse(w, res, http.StatusOK)
-	}
-}
+		if needsRehash {
+			rehashPassword(r.Context(), logger, hasher, request.Password, func(hash string) error {
+				return auth.UpdatePatientPasswordHash(r.Context(), patientId, hash)
+			})
+		}
 
-func GetUserHandler(logger *slog.Logger, auth LoginWrapper) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Debug("GetUserHandler starting...")
-		logger.Debug("r.URL", "r.URL", r.URL)
-		email := r.URL.Query().Get("email")
-		logger.Debug("email", "email", email)
-		if email == "" {
-			logger.Error("Email is empty")
-			response.SendFailureResponse(w, "Email is empty", http.StatusBadRequest)
-		}
-		patient, err := auth.GetUser(email)
+		identity := domain.Identity{Subject: patientId, Role: domain.RolePatient}
+		logger.DebugContext(r.Context(), "Пытаемся снегерировать токен")
+		res, err := issueLoginTokens(issuer, auth, identity, r)
 		if err != nil {
-			logger.Error("Произошла ошибка внутри функции GetUser")
-			response.SendFailureResponse(w, "Failed get to user", http.StatusInternalServerError)
+			logger.ErrorContext(r.Context(), "Failed to generate token", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to generate token", http.StatusInternalServerError)
+			return
 		}
+		res["patientID"] = patientId
 
-		logger.Debug("GetUserHandler works successful")
-		response.SendSuccessResponse(w, patient, http.StatusOK)
+		logger.InfoContext(r.Context(), "LoginHandler works successful", slog.String("subject", identity.Sub()))
+		response.SendSuccessResponse(w, res, http.StatusOK)
 	}
 }
 
-func LoginAdminHandler(logger *slog.Logger, auth LoginWrapper, cfg *config.Config) http.HandlerFunc {
+// LoginAdminHandler проверяет пароль администратора. Если у администратора подключена 2FA
+// или она обязательна, вместо токенов возвращается промежуточный mfa_token.
+func LoginAdminHandler(logger *slog.Logger, auth LoginWrapper, issuer *tokens.Issuer, hasher password.PasswordHasher, limiter *LoginLimiter, requireTwoFactor bool, recorder *audit.Recorder) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Debug("LoginHandler starting...")
+		logger.DebugContext(r.Context(), "LoginHandler starting...")
 
-		request := domain.Admin{}
+		request := credentials{}
 		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
 
-		logger.Debug("Body успешно распарсен")
+		logger.DebugContext(r.Context(), "Body успешно распарсен")
 
-		logger.Debug("Пытаемся получить пароль по указанному email")
-		adminID, encodedPassword, err := auth.GetAdminPassword(request.Email)
-		if err != nil {
-			logger.Error("Произошла ошибка внутри функции GetPassword")
-			//logger.Error(err.Error())
-			response.SendFailureResponse(w, fmt.Sprintf("Failed to auth user: %s", err), http.StatusInternalServerError)
+		account := accountKey(domain.RoleAdmin, request.Email)
+		if !limiter.allow(logger, w, r, account) {
+			return
+		}
+
+		logger.DebugContext(r.Context(), "Пытаемся получить пароль по указанному email")
+		adminID, encodedPassword, err := auth.GetAdminPassword(r.Context(), request.Email)
+		if err != nil && !errors.Is(err, repository.ErrorNotFound) {
+			logger.ErrorContext(r.Context(), "Произошла ошибка внутри функции GetAdminPassword", slog.String("error", err.Error()))
+			limiter.release(logger, r, account)
+			response.SendFailureResponse(w, "Failed to auth user", http.StatusInternalServerError)
 			return
 		}
-		logger.Debug("GetPassword отработала успешно")
-		logger.Debug("patientId и encodedPassword", slog.Int("adminID", adminID), slog.String("encodedPassword", encodedPassword))
 
-		logger.Debug("Пытаемся проверить совпадают ли пароли")
-		logger.Debug("Расшифрованный пароль: ", slog.String("decodedPassword", encodedPassword))
-		logger.Debug("Присланный пароль: ", slog.String("inputPassword", request.Password))
-		if encodedPassword != request.Password {
-			logger.Info("Пароли не совпадают")
-			// logger.Error(err.Error())
-			response.SendFailureResponse(w, fmt.Sprintf("Failed to auth user: %v", err), http.StatusUnauthorized)
+		logger.DebugContext(r.Context(), "Пытаемся проверить совпадают ли пароли")
+		ok, needsRehash := checkPassword(r.Context(), logger, hasher, encodedPassword, request.Password, err == nil)
+		if !ok {
+			logger.InfoContext(r.Context(), "Неверный email или пароль")
+			metrics.LoginFailures.WithLabelValues(domain.RoleAdmin, "password").Inc()
+			response.SendFailureResponse(w, invalidCredentials, http.StatusUnauthorized)
 			return
 		}
-		logger.Debug("Пароль введен успешно")
+		limiter.succeed(logger, r, account)
+		logger.DebugContext(r.Context(), "Пароль введен успешно", slog.Int("adminID", adminID))
+
+		if needsRehash {
+			rehashPassword(r.Context(), logger, hasher, request.Password, func(hash string) error {
+				return auth.UpdateAdminPasswordHash(r.Context(), adminID, hash)
+			})
+		}
 
-		logger.Debug("Пытаемся снегерировать токен")
-		accessToken, err := generateAccessToken(cfg, adminID)
+		identity := domain.Identity{Subject: adminID, Role: domain.RoleAdmin}
+		totpState, err := auth.GetAdminTOTP(r.Context(), adminID)
+		if errors.Is(err, repository.ErrorNotFound) {
+			// Администратора деактивировали после проверки пароля
+			logger.InfoContext(r.Context(), "Admin is not active", slog.Int("adminID", adminID))
+			response.SendFailureResponse(w, invalidCredentials, http.StatusUnauthorized)
+			return
+		}
 		if err != nil {
-			logger.Error("Произошла ошибка внутри функции generateAccessToken")
-			logger.Error("Failed to generate token", slog.String("error", err.Error()))
-			response.SendFailureResponse(w, "Failed to generate token", http.StatusInternalServerError)
+			logger.ErrorContext(r.Context(), "Failed to get admin 2FA state", slog.Int("adminID", adminID), slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to auth user", http.StatusInternalServerError)
+			return
+		}
+		// Токены выдаются только после второго фактора; без подключенной 2FA при обязательном режиме
+		// промежуточный токен годится лишь для подключения 2FA
+		if totpState.Enabled || requireTwoFactor {
+			sendTwoFactorChallenge(logger, w, r, issuer, identity, totpState.Enabled)
 			return
 		}
 
-		refreshToken, err := generateRefreshToken(cfg, adminID)
+		logger.DebugContext(r.Context(), "Пытаемся снегерировать токен")
+		res, err := issueLoginTokens(issuer, auth, identity, r)
 		if err != nil {
-			logger.Error("Произошла ошибка внутри функции generateAccessToken")
-			logger.Error("Failed to generate token", slog.String("error", err.Error()))
+			logger.ErrorContext(r.Context(), "Failed to generate token", slog.String("error", err.Error()))
 			response.SendFailureResponse(w, "Failed to generate token", http.StatusInternalServerError)
 			return
 		}
+		res["adminID"] = adminID
+		recordAdminLogin(r, recorder, adminID, "password")
 
-		logger.Debug("Токен успешно сгенерирован")
+		logger.InfoContext(r.Context(), "LoginHandler works successful", slog.String("subject", identity.Sub()))
+		response.SendSuccessResponse(w, res, http.StatusOK)
+	}
+}
+
+// recordAdminLogin записывает в журнал аудита вход администратора
+func recordAdminLogin(r *http.Request, recorder *audit.Recorder, adminID int, method string) {
+	recorder.Record(r, audit.Event{
+		ActorRole:  domain.RoleAdmin,
+		ActorID:    adminID,
+		Action:     "admin.login",
+		TargetType: "admin",
+		TargetID:   strconv.Itoa(adminID),
+		Changes:    audit.Diff(nil, map[string]string{"method": method}),
+	})
+}
 
-		accessLifetime := cfg.JWT.ExpireAccess
-		refreshLifetime := cfg.JWT.ExpireRefresh
+// issueLoginTokens выпускает пару access/refresh токенов для новой сессии
+func issueLoginTokens(issuer *tokens.Issuer, saver RefreshTokenSaver, identity domain.Identity, r *http.Request) (map[string]interface{}, error) {
+	accessToken, err := issuer.IssueAccess(identity)
+	if err != nil {
+		return nil, err
+	}
 
-		logger.Debug("Access lifetime", slog.Time("access_lifetime", time.Now().Add(accessLifetime)))
-		logger.Debug("Refresh lifetime", slog.Time("refresh_lifetime", time.Now().Add(refreshLifetime)))
+	refreshToken, refreshRecord, err := issueRefreshToken(issuer, identity, "", r)
+	if err != nil {
+		return nil, err
+	}
 
-		logger.Debug("Формируем ответ")
-		res := map[string]interface{}{
-			"adminID":          adminID,
-			"access_token":     accessToken,
-			"access_lifetime":  time.Now().Add(accessLifetime).Format(time.RFC3339),
-			"refresh_token":    refreshToken,
-			"refresh_lifetime": time.Now().Add(refreshLifetime).Format(time.RFC3339),
-		}
+	if err = saver.SaveRefreshToken(r.Context(), refreshRecord); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"role":             identity.Role,
+		"access_token":     accessToken.Value,
+		"access_lifetime":  accessToken.ExpiresAt.Format(time.RFC3339),
+		"refresh_token":    refreshToken,
+		"refresh_lifetime": refreshRecord.ExpiresAt.Format(time.RFC3339),
+	}, nil
+}
 
-		logger.Debug("Сформированный ответ", res)
+// Ответ одинаков для неизвестного email и неверного пароля
+const invalidCredentials = "Failed to auth user: invalid credentials"
 
-		logger.Info("LoginHandler works successful")
-		response.SendSuccessResponse(w, res, http.StatusOK)
+var dummyHash struct {
+	once  sync.Once
+	value string
+}
+
+// checkPassword сверяет пароль с хешем. Для несуществующего пользователя пароль сверяется
+// с заранее посчитанным хешем, чтобы время ответа не выдавало наличие аккаунта.
+func checkPassword(ctx context.Context, logger *slog.Logger, hasher password.PasswordHasher, encoded, plain string, found bool) (bool, bool) {
+	if !found {
+		dummyHash.once.Do(func() {
+			dummyHash.value, _ = hasher.Hash("dummy password for timing equalization")
+		})
+		hasher.Verify(dummyHash.value, plain)
+		return false, false
 	}
+
+	ok, needsRehash, err := hasher.Verify(encoded, plain)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to verify password", slog.String("error", err.Error()))
+	}
+	return ok, needsRehash
 }
 
-func GetAdminHandler(logger *slog.Logger, auth LoginWrapper) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Debug("GetAdminHandler starting...")
-		logger.Debug("r.URL", "r.URL", r.URL)
-		email := r.URL.Query().Get("email")
-		logger.Debug("email", "email", email)
-		if email == "" {
-			logger.Error("Email is empty")
-			response.SendFailureResponse(w, "Email is empty", http.StatusBadRequest)
-		}
-		admin, err := auth.GetAdmin(email)
-		if err != nil {
-			logger.Error("Произошла ошибка внутри функции GetAdmin")
-			response.SendFailureResponse(w, "Failed get to admin", http.StatusInternalServerError)
-		}
+// rehashPassword переводит пароль, хранящийся в устаревшем виде, на текущий алгоритм.
+// Ошибка не мешает входу: пароль будет перехеширован при следующем входе.
+func rehashPassword(ctx context.Context, logger *slog.Logger, hasher password.PasswordHasher, plain string, save func(string) error) {
+	hash, err := hasher.Hash(plain)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to rehash password", slog.String("error", err.Error()))
+		return
+	}
 
-		logger.Debug("GetAdminHandler works successful")
-		response.SendSuccessResponse(w, admin, http.StatusOK)
+	if err := save(hash); err != nil {
+		logger.ErrorContext(ctx, "Failed to save rehashed password", slog.String("error", err.Error()))
+		return
 	}
+
+	logger.InfoContext(ctx, "Password hash upgraded")
 }
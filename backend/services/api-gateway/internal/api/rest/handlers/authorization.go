@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/api/response"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// RoutePolicy описывает, каким ролям разрешен метод на маршруте.
+// В Pattern сегмент вида {id} совпадает с любым одним сегментом пути.
+type RoutePolicy struct {
+	Method  string
+	Pattern string
+	Roles   []string
+}
+
+// AuthorizeMiddleware пропускает запрос, только если для него есть политика,
+// разрешающая роль вызывающего. Маршруты без политики запрещены.
+// Должен стоять после AuthMiddleware.
+func AuthorizeMiddleware(logger *slog.Logger, policies []RoutePolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, ok := IdentityFromContext(r.Context())
+			if !ok {
+				unauthorized(w, "Unauthorized")
+				return
+			}
+
+			policy, found := findPolicy(policies, r.Method, r.URL.Path)
+			if !found {
+				logger.DebugContext(r.Context(), "No policy for route", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+				response.SendFailureResponse(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			if !slices.Contains(policy.Roles, identity.Role) {
+				logger.InfoContext(r.Context(), "Access denied",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("role", identity.Role),
+					slog.Int("subject", identity.Subject),
+				)
+				response.SendFailureResponse(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func findPolicy(policies []RoutePolicy, method, path string) (RoutePolicy, bool) {
+	for _, policy := range policies {
+		if policy.Method == method && matchPattern(policy.Pattern, path) {
+			return policy, true
+		}
+	}
+	return RoutePolicy{}, false
+}
+
+func matchPattern(pattern, path string) bool {
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+
+	if len(patternParts) != len(pathParts) {
+		return false
+	}
+
+	for i, part := range patternParts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if pathParts[i] == "" {
+				return false
+			}
+			continue
+		}
+		if part != pathParts[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/api/response"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/audit"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/metrics"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/password"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/tokens"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository"
+	"github.com/go-chi/chi/v5"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type DoctorLoginWrapper interface {
+	GetDoctorPassword(context.Context, string) (int, string, error)
+	UpdateDoctorPasswordHash(context.Context, int, string) error
+	RefreshTokenSaver
+}
+
+type DoctorCredentialsWrapper interface {
+	SetDoctorCredentials(context.Context, int, string, string) error
+}
+
+// LoginDoctorHandler проверяет пароль врача и выдает токены с ролью doctor.
+// Subject токена - id врача, сервисы получают его в X-Doctor-ID.
+func LoginDoctorHandler(logger *slog.Logger, auth DoctorLoginWrapper, issuer *tokens.Issuer, hasher password.PasswordHasher, limiter *LoginLimiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "LoginDoctorHandler starting...")
+
+		request := credentials{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		account := accountKey(domain.RoleDoctor, request.Email)
+		if !limiter.allow(logger, w, r, account) {
+			return
+		}
+
+		doctorID, encodedPassword, err := auth.GetDoctorPassword(r.Context(), request.Email)
+		if err != nil && !errors.Is(err, repository.ErrorNotFound) {
+			logger.ErrorContext(r.Context(), "Failed to get doctor password", slog.String("error", err.Error()))
+			limiter.release(logger, r, account)
+			response.SendFailureResponse(w, "Failed to auth user", http.StatusInternalServerError)
+			return
+		}
+
+		ok, needsRehash := checkPassword(r.Context(), logger, hasher, encodedPassword, request.Password, err == nil)
+		if !ok {
+			logger.InfoContext(r.Context(), "Неверный email или пароль")
+			metrics.LoginFailures.WithLabelValues(domain.RoleDoctor, "password").Inc()
+			response.SendFailureResponse(w, invalidCredentials, http.StatusUnauthorized)
+			return
+		}
+		limiter.succeed(logger, r, account)
+
+		if needsRehash {
+			rehashPassword(r.Context(), logger, hasher, request.Password, func(hash string) error {
+				return auth.UpdateDoctorPasswordHash(r.Context(), doctorID, hash)
+			})
+		}
+
+		identity := domain.Identity{Subject: doctorID, Role: domain.RoleDoctor}
+		res, err := issueLoginTokens(issuer, auth, identity, r)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to generate token", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to generate token", http.StatusInternalServerError)
+			return
+		}
+		res["doctorID"] = doctorID
+
+		logger.InfoContext(r.Context(), "LoginDoctorHandler works successful", slog.String("subject", identity.Sub()))
+		response.SendSuccessResponse(w, res, http.StatusOK)
+	}
+}
+
+// SetDoctorCredentialsHandler задает врачу email и пароль для входа.
+// Прежние сессии врача при этом отзываются.
+func SetDoctorCredentialsHandler(logger *slog.Logger, wrapper DoctorCredentialsWrapper, hasher password.PasswordHasher, recorder *audit.Recorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "SetDoctorCredentialsHandler starting...")
+
+		doctorID, err := strconv.Atoi(chi.URLParam(r, "doctorID"))
+		if err != nil || doctorID <= 0 {
+			response.SendFailureResponse(w, "Invalid doctorID", http.StatusBadRequest)
+			return
+		}
+
+		request := credentials{}
+		if err = json.NewDecoder(r.Body).Decode(&request); err != nil {
+			response.SendFailureResponse(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		request.Email = strings.TrimSpace(request.Email)
+		if request.Email == "" || request.Password == "" {
+			response.SendFailureResponse(w, "Email and password are required", http.StatusBadRequest)
+			return
+		}
+
+		hash, err := hasher.Hash(request.Password)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to hash password", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to set doctor credentials", http.StatusInternalServerError)
+			return
+		}
+
+		err = wrapper.SetDoctorCredentials(r.Context(), doctorID, request.Email, hash)
+		if errors.Is(err, repository.ErrorNotFound) {
+			response.SendFailureResponse(w, "Doctor not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, repository.ErrorAlreadyExists) {
+			response.SendFailureResponse(w, "Email is already in use", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to set doctor credentials", slog.Int("doctorID", doctorID), slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to set doctor credentials", http.StatusInternalServerError)
+			return
+		}
+
+		recorder.Record(r, audit.Event{
+			Action:     "doctor.credentials_set",
+			TargetType: "doctor",
+			TargetID:   strconv.Itoa(doctorID),
+			Changes:    audit.Diff(nil, map[string]string{"email": request.Email}),
+		})
+
+		logger.InfoContext(r.Context(), "SetDoctorCredentialsHandler works successful", slog.Int("doctorID", doctorID))
+		response.SendSuccessResponse(w, "Doctor credentials have been set", http.StatusOK)
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testDoctorID = 12
+
+type fakeDoctorLogin struct {
+	email    string
+	password string
+}
+
+func (f *fakeDoctorLogin) GetDoctorPassword(_ context.Context, email string) (int, string, error) {
+	if email != f.email {
+		return 0, "", repository.ErrorNotFound
+	}
+	return testDoctorID, f.password, nil
+}
+
+func (f *fakeDoctorLogin) UpdateDoctorPasswordHash(context.Context, int, string) error { return nil }
+
+func (f *fakeDoctorLogin) SaveRefreshToken(context.Context, domain.RefreshToken) error { return nil }
+
+func TestLoginDoctorHandler(t *testing.T) {
+	issuer := newTestIssuer(t)
+	wrapper := &fakeDoctorLogin{email: "doctor@example.com", password: "secret"}
+	handler := LoginDoctorHandler(testLogger, wrapper, issuer, plainHasher{}, newTestLimiter())
+
+	w := postJSON(handler, credentials{Email: "doctor@example.com", Password: "secret"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+
+	var res struct {
+		Data struct {
+			Role        string `json:"role"`
+			AccessToken string `json:"access_token"`
+			DoctorID    int    `json:"doctorID"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatalf("decode response error = %v", err)
+	}
+	if res.Data.Role != domain.RoleDoctor || res.Data.DoctorID != testDoctorID {
+		t.Fatalf("response = %+v, want role doctor and doctorID %d", res.Data, testDoctorID)
+	}
+
+	claims, err := issuer.VerifyAccess(res.Data.AccessToken)
+	if err != nil {
+		t.Fatalf("VerifyAccess() error = %v", err)
+	}
+	want := domain.Identity{Subject: testDoctorID, Role: domain.RoleDoctor}
+	if claims.Identity != want {
+		t.Fatalf("identity = %+v, want %+v", claims.Identity, want)
+	}
+}
+
+func TestLoginDoctorHandlerInvalidCredentials(t *testing.T) {
+	wrapper := &fakeDoctorLogin{email: "doctor@example.com", password: "secret"}
+	handler := LoginDoctorHandler(testLogger, wrapper, newTestIssuer(t), plainHasher{}, newTestLimiter())
+
+	for _, body := range []credentials{
+		{Email: "doctor@example.com", Password: "wrong"},
+		{Email: "unknown@example.com", Password: "secret"},
+	} {
+		w := postJSON(handler, body)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("%s: status = %d, want 401", body.Email, w.Code)
+		}
+		if message := failureMessage(t, w); message != invalidCredentials {
+			t.Fatalf("%s: message = %q, want %q", body.Email, message, invalidCredentials)
+		}
+	}
+}
+
+func TestAuthMiddlewareForwardsDoctorID(t *testing.T) {
+	issuer := newTestIssuer(t)
+	access, err := issuer.IssueAccess(domain.Identity{Subject: testDoctorID, Role: domain.RoleDoctor})
+	if err != nil {
+		t.Fatalf("IssueAccess() error = %v", err)
+	}
+
+	var got http.Header
+	handler := AuthMiddleware(testLogger, issuer)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+access.Value)
+	// Подставленные клиентом заголовки личности должны быть затерты
+	r.Header.Set(domain.HeaderPatientID, "1")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if got == nil {
+		t.Fatal("next handler was not called")
+	}
+	if got.Get(domain.HeaderDoctorID) != "12" || got.Get(domain.HeaderRole) != domain.RoleDoctor {
+		t.Fatalf("headers = %v, want X-Doctor-ID 12 and X-Role doctor", got)
+	}
+	if got.Get(domain.HeaderPatientID) != "" {
+		t.Fatalf("X-Patient-ID = %q, want empty", got.Get(domain.HeaderPatientID))
+	}
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/tokens"
+	"log/slog"
+	"net/http"
+)
+
+// JWKSHandler публикует открытые ключи подписи, по которым сервисы проверяют токены сами.
+// Ответ отдается в формате RFC 7517 без общей обертки status/data.
+func JWKSHandler(logger *slog.Logger, keys *tokens.KeyRing) http.HandlerFunc {
+	body, err := json.Marshal(keys.JWKS())
+	if err != nil {
+		// JWKS состоит только из строк, ошибка здесь невозможна
+		panic(err)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "JWKSHandler starting...")
+
+		w.Header().Set("Content-Type", "application/jwk-set+json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}
+}
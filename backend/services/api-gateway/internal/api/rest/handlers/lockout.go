@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/api/response"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/audit"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/lockout"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoginLimiter ограничивает подбор пароля: отдельно по аккаунту и по IP.
+// Счетчик аккаунта ведется и для несуществующих email, чтобы блокировка не выдавала наличие аккаунта.
+type LoginLimiter struct {
+	Accounts *lockout.Guard
+	IPs      *lockout.Guard
+}
+
+func accountKey(role, email string) string {
+	return role + ":" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(r *http.Request) string {
+	return "ip:" + clientIP(r)
+}
+
+// guardedKey - ключ, по которому учитываются попытки, и его ограничитель
+type guardedKey struct {
+	guard *lockout.Guard
+	key   string
+}
+
+func (l *LoginLimiter) keys(r *http.Request, account string) []guardedKey {
+	return []guardedKey{{l.Accounts, account}, {l.IPs, ipKey(r)}}
+}
+
+// allow учитывает попытку по аккаунту и по IP и отвечает 429, если один из них заблокирован.
+// До вызова succeed или release попытка считается неудачной.
+func (l *LoginLimiter) allow(logger *slog.Logger, w http.ResponseWriter, r *http.Request, account string) bool {
+	var (
+		retryAfter time.Duration
+		acquired   []guardedKey
+	)
+	for _, k := range l.keys(r, account) {
+		remaining, err := k.guard.Acquire(r.Context(), k.key)
+		if err != nil {
+			// Недоступность хранилища счетчиков не должна блокировать вход
+			logger.ErrorContext(r.Context(), "Failed to check login lockout", slog.String("error", err.Error()))
+			continue
+		}
+		if remaining > 0 {
+			retryAfter = max(retryAfter, remaining)
+			continue
+		}
+		acquired = append(acquired, k)
+	}
+
+	if retryAfter == 0 {
+		return true
+	}
+
+	// Отклоненный запрос не должен расходовать попытки по второму ключу
+	for _, k := range acquired {
+		if err := k.guard.Release(r.Context(), k.key); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to release login attempt", slog.String("error", err.Error()))
+		}
+	}
+
+	logger.InfoContext(r.Context(), "Login locked", slog.String("ip", clientIP(r)), slog.Duration("retry_after", retryAfter))
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	response.SendFailureResponse(w, "Too many failed login attempts, try again later", http.StatusTooManyRequests)
+	return false
+}
+
+// succeed сбрасывает счетчик аккаунта и возвращает попытку IP: успешный вход с IP
+// не прощает ему перебор других аккаунтов
+func (l *LoginLimiter) succeed(logger *slog.Logger, r *http.Request, account string) {
+	if err := l.Accounts.Reset(r.Context(), account); err != nil {
+		logger.ErrorContext(r.Context(), "Failed to reset login attempts", slog.String("error", err.Error()))
+	}
+	if err := l.IPs.Release(r.Context(), ipKey(r)); err != nil {
+		logger.ErrorContext(r.Context(), "Failed to release login attempt", slog.String("error", err.Error()))
+	}
+}
+
+// release возвращает попытку, проверку которой не удалось завершить из-за ошибки сервера
+func (l *LoginLimiter) release(logger *slog.Logger, r *http.Request, account string) {
+	for _, k := range l.keys(r, account) {
+		if err := k.guard.Release(r.Context(), k.key); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to release login attempt", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// UnlockHandler снимает блокировку входа с аккаунта и, если передан, с IP
+func UnlockHandler(logger *slog.Logger, limiter *LoginLimiter, recorder *audit.Recorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "UnlockHandler starting...")
+
+		var request struct {
+			Email string `json:"email"`
+			Role  string `json:"role"`
+			IP    string `json:"ip"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Email == "" {
+			response.SendFailureResponse(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if request.Role == "" {
+			request.Role = domain.RolePatient
+		}
+		if request.Role != domain.RolePatient && request.Role != domain.RoleAdmin && request.Role != domain.RoleDoctor {
+			response.SendFailureResponse(w, "Role must be patient, admin or doctor", http.StatusBadRequest)
+			return
+		}
+
+		if err := limiter.Accounts.Reset(r.Context(), accountKey(request.Role, request.Email)); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to unlock account", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to unlock account", http.StatusInternalServerError)
+			return
+		}
+		if request.IP != "" {
+			if err := limiter.IPs.Reset(r.Context(), "ip:"+request.IP); err != nil {
+				logger.ErrorContext(r.Context(), "Failed to unlock ip", slog.String("error", err.Error()))
+				response.SendFailureResponse(w, "Failed to unlock account", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		recorder.Record(r, audit.Event{
+			Action:     "auth.unlock",
+			TargetType: request.Role,
+			TargetID:   request.Email,
+			Changes:    audit.Diff(nil, map[string]string{"ip": request.IP}),
+		})
+
+		identity, _ := IdentityFromContext(r.Context())
+		logger.InfoContext(r.Context(), "UnlockHandler works successful", slog.String("unlocked_by", identity.Sub()), slog.String("role", request.Role))
+		response.SendSuccessResponse(w, "Account unlocked", http.StatusOK)
+	}
+}
@@ -1,18 +1,81 @@
 package handlers
 
-import "net/http"
+import (
+	"context"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/api/response"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/tokens"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
 
-func CorsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+type identityKey struct{}
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+var identityHeaders = []string{
+	domain.HeaderPatientID,
+	domain.HeaderAdminID,
+	domain.HeaderDoctorID,
+	domain.HeaderRole,
+}
+
+var subjectHeaders = map[string]string{
+	domain.RolePatient: domain.HeaderPatientID,
+	domain.RoleAdmin:   domain.HeaderAdminID,
+	domain.RoleDoctor:  domain.HeaderDoctorID,
+}
+
+// AuthMiddleware проверяет Bearer access токен и передает личность вызывающего
+// в сервисы через заголовки X-Role и X-Patient-ID/X-Admin-ID/X-Doctor-ID.
+func AuthMiddleware(logger *slog.Logger, issuer *tokens.Issuer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Клиент не должен иметь возможности подставить личность сам
+			for _, header := range identityHeaders {
+				r.Header.Del(header)
+			}
+
+			tokenString, ok := bearerToken(r)
+			if !ok {
+				unauthorized(w, "Missing bearer token")
+				return
+			}
+
+			claims, err := issuer.VerifyAccess(tokenString)
+			if err != nil {
+				logger.DebugContext(r.Context(), "Invalid access token", slog.String("error", err.Error()))
+				unauthorized(w, "Invalid or expired access token")
+				return
+			}
+			identity := claims.Identity
+
+			r.Header.Set(subjectHeaders[identity.Role], strconv.Itoa(identity.Subject))
+			r.Header.Set(domain.HeaderRole, identity.Role)
+
+			ctx := context.WithValue(r.Context(), identityKey{}, identity)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// IdentityFromContext возвращает личность, проверенную AuthMiddleware
+func IdentityFromContext(ctx context.Context) (domain.Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(domain.Identity)
+	return identity, ok
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
 
-		next.ServeHTTP(w, r)
-	})
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="MyHelp"`)
+	response.SendFailureResponse(w, message, http.StatusUnauthorized)
 }
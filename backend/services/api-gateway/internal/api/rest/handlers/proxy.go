@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/api/response"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/config"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/requestid"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/tracing"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+)
+
+// ProxyHandler проксирует запросы с префиксом from на upstream, заменяя префикс на to.
+// Например, /api/v1/account/... -> http://account-service/MyHelp/account/...
+func ProxyHandler(logger *slog.Logger, upstream config.Upstream, from, to string) (http.Handler, error) {
+	target, err := url.Parse(upstream.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream url %q: %w", upstream.URL, err)
+	}
+	if target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("invalid upstream url %q: scheme and host are required", upstream.URL)
+	}
+
+	// Таймаут ограничивает только ожидание заголовков ответа: тело, которое сервис уже начал
+	// отдавать, не обрывается
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = upstream.Timeout
+
+	proxy := &httputil.ReverseProxy{
+		Transport: tracing.Transport(transport),
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.Out.URL.Scheme = target.Scheme
+			pr.Out.URL.Host = target.Host
+			pr.Out.URL.Path = strings.TrimSuffix(target.Path, "/") + rewritePath(pr.In.URL.Path, from, to)
+			pr.Out.URL.RawPath = ""
+			pr.Out.Host = target.Host
+			pr.SetXForwarded()
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			// CORS-заголовки выставляет только gateway, дубли от сервисов ломают браузер
+			for key := range resp.Header {
+				if strings.HasPrefix(key, "Access-Control-") {
+					resp.Header.Del(key)
+				}
+			}
+			// id запроса уже выставлен в ответе gateway, сервис возвращает тот же
+			resp.Header.Del(requestid.Header)
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				logger.ErrorContext(r.Context(), "Upstream timeout", slog.String("upstream", target.Host), slog.String("path", r.URL.Path), slog.String("error", err.Error()))
+				response.SendFailureResponse(w, "Upstream service timeout", http.StatusGatewayTimeout)
+				return
+			}
+			if errors.Is(err, context.Canceled) {
+				logger.DebugContext(r.Context(), "Client canceled request", slog.String("upstream", target.Host), slog.String("path", r.URL.Path))
+				return
+			}
+			logger.ErrorContext(r.Context(), "Upstream unavailable", slog.String("upstream", target.Host), slog.String("path", r.URL.Path), slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Upstream service unavailable", http.StatusBadGateway)
+		},
+	}
+
+	return proxy, nil
+}
+
+func rewritePath(path, from, to string) string {
+	rest := strings.TrimPrefix(path, from)
+	if rest == "" {
+		rest = "/"
+	}
+	return to + rest
+}
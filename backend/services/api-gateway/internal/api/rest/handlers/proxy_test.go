@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProxyHandlerSlowUpstream(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer upstream.Close()
+	defer close(release)
+
+	handler, err := ProxyHandler(testLogger, config.Upstream{URL: upstream.URL, Timeout: 50 * time.Millisecond}, "/api/v1/account", "/MyHelp/account")
+	if err != nil {
+		t.Fatalf("ProxyHandler() error = %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/account", nil))
+		if w.Code != http.StatusGatewayTimeout {
+			t.Fatalf("attempt %d: status = %d, want 504", i, w.Code)
+		}
+	}
+}
+
+func TestProxyHandlerSlowBody(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first,"))
+		w.(http.Flusher).Flush()
+		// Тело дольше таймаута: заголовки уже получены, ответ не обрывается
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("second"))
+	}))
+	defer upstream.Close()
+
+	handler, err := ProxyHandler(testLogger, config.Upstream{URL: upstream.URL, Timeout: 50 * time.Millisecond}, "/api/v1/account", "/MyHelp/account")
+	if err != nil {
+		t.Fatalf("ProxyHandler() error = %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/account", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "first,second" {
+		t.Fatalf("response = %d %q, want 200 %q", w.Code, w.Body.String(), "first,second")
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/api/response"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/ratelimit"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// RateLimitByIP ограничивает частоту запросов группы маршрутов с одного IP.
+// Ставится до AuthMiddleware, чтобы ограничивать и запросы без токена.
+func RateLimitByIP(logger *slog.Logger, store ratelimit.Store, group string, rule ratelimit.Rule) func(http.Handler) http.Handler {
+	return rateLimit(logger, store, group, rule, func(r *http.Request) (string, bool) {
+		return "ip:" + clientIP(r), true
+	})
+}
+
+// RateLimitBySubject ограничивает частоту запросов группы маршрутов от одного пользователя.
+// Должен стоять после AuthMiddleware.
+func RateLimitBySubject(logger *slog.Logger, store ratelimit.Store, group string, rule ratelimit.Rule) func(http.Handler) http.Handler {
+	return rateLimit(logger, store, group, rule, func(r *http.Request) (string, bool) {
+		identity, ok := IdentityFromContext(r.Context())
+		if !ok {
+			return "", false
+		}
+		return "sub:" + identity.Sub(), true
+	})
+}
+
+// rateLimit отвечает 429 с Retry-After, когда в ведре ключа кончились токены
+func rateLimit(logger *slog.Logger, store ratelimit.Store, group string, rule ratelimit.Rule, key func(*http.Request) (string, bool)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !rule.Enabled() {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k, ok := key(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			retryAfter, err := store.TakeRateLimitToken(r.Context(), "rl:"+group+":"+k, rule)
+			if err != nil {
+				// Недоступность хранилища не должна останавливать обслуживание запросов
+				logger.ErrorContext(r.Context(), "Failed to check rate limit", slog.String("error", err.Error()))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if retryAfter > 0 {
+				logger.InfoContext(r.Context(), "Rate limit exceeded", slog.String("group", group), slog.String("key", k), slog.Duration("retry_after", retryAfter))
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				response.SendFailureResponse(w, "Too many requests, try again later", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
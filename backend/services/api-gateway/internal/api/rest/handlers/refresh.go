@@ -1,18 +1,67 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"github.com/daariikk/MyHelp/services/api-gateway/internal/api/response"
-	"github.com/daariikk/MyHelp/services/api-gateway/internal/config"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/randtoken"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/tokens"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository"
 	"log/slog"
+	"net"
 	"net/http"
 	"time"
 )
 
-func RefreshHandler(logger *slog.Logger, cfg *config.Config) func(http.ResponseWriter, *http.Request) {
+type RefreshTokenSaver interface {
+	SaveRefreshToken(context.Context, domain.RefreshToken) error
+}
+
+type RefreshWrapper interface {
+	RotateRefreshToken(context.Context, string, domain.RefreshToken) error
+}
+
+// issueRefreshToken выпускает refresh токен и готовит запись для хранилища.
+// Пустой familyID означает новый вход - для него заводится новая цепочка.
+func issueRefreshToken(issuer *tokens.Issuer, identity domain.Identity, familyID string, r *http.Request) (string, domain.RefreshToken, error) {
+	if familyID == "" {
+		var err error
+		if familyID, err = randtoken.New(); err != nil {
+			return "", domain.RefreshToken{}, err
+		}
+	}
+
+	token, err := issuer.IssueRefresh(identity, familyID)
+	if err != nil {
+		return "", domain.RefreshToken{}, err
+	}
+
+	record := domain.RefreshToken{
+		JTI:       token.JTI,
+		FamilyID:  familyID,
+		Identity:  identity,
+		TokenHash: randtoken.Hash(token.Value),
+		UserAgent: r.UserAgent(),
+		IP:        clientIP(r),
+		ExpiresAt: token.ExpiresAt,
+	}
+
+	return token.Value, record, nil
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func RefreshHandler(logger *slog.Logger, issuer *tokens.Issuer, wrapper RefreshWrapper) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Debug("RefreshHandler starting...")
+		logger.DebugContext(r.Context(), "RefreshHandler starting...")
 
 		var request struct {
 			RefreshToken string `json:"refresh_token"`
@@ -21,23 +70,56 @@ func RefreshHandler(logger *slog.Logger, cfg *config.Config) func(http.ResponseW
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
-		logger.Debug("request: ", request)
 
-		newAccessToken, err := refreshAccessToken(cfg, request.RefreshToken)
+		claims, err := issuer.VerifyRefresh(request.RefreshToken)
+		if err != nil {
+			logger.DebugContext(r.Context(), "Invalid refresh token", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to refresh access token: invalid refresh token", http.StatusUnauthorized)
+			return
+		}
+		identity := claims.Identity
+
+		newRefreshToken, record, err := issueRefreshToken(issuer, identity, claims.FamilyID, r)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to generate token", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to generate token", http.StatusInternalServerError)
+			return
+		}
+
+		err = wrapper.RotateRefreshToken(r.Context(), randtoken.Hash(request.RefreshToken), record)
+		if errors.Is(err, repository.ErrorTokenReused) {
+			logger.WarnContext(r.Context(), "Refresh token reuse", slog.String("subject", identity.Sub()))
+			response.SendFailureResponse(w, "Failed to refresh access token: invalid refresh token", http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, repository.ErrorInvalidToken) {
+			response.SendFailureResponse(w, "Failed to refresh access token: invalid refresh token", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to rotate refresh token", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to refresh access token", http.StatusInternalServerError)
+			return
+		}
+
+		accessToken, err := issuer.IssueAccess(identity)
 		if err != nil {
-			response.SendFailureResponse(w, fmt.Sprintf("Failed to refresh access token: %v", err), http.StatusUnauthorized)
+			logger.ErrorContext(r.Context(), "Failed to generate token", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to generate token", http.StatusInternalServerError)
 			return
 		}
-		// Формируем ответ
-		accessLifetime := time.Duration(cfg.JWT.ExpireAccess) * time.Minute
 
+		// Формируем ответ в том же виде, что и при входе
 		res := map[string]interface{}{
-			"access_token":    newAccessToken,
-			"access_lifetime": time.Now().Add(accessLifetime).Format(time.RFC3339),
+			"role":             identity.Role,
+			"access_token":     accessToken.Value,
+			"access_lifetime":  accessToken.ExpiresAt.Format(time.RFC3339),
+			"refresh_token":    newRefreshToken,
+			"refresh_lifetime": record.ExpiresAt.Format(time.RFC3339),
 		}
 
-		logger.Info("RefreshHandler works successful")
+		logger.InfoContext(r.Context(), "RefreshHandler works successful", slog.String("subject", identity.Sub()))
 
-		response.SendSuccessResponse(w, res, http.StatusCreated)
+		response.SendSuccessResponse(w, res, http.StatusOK)
 	}
 }
@@ -1,35 +1,70 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/daariikk/MyHelp/services/api-gateway/internal/api/response"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/config"
 	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/notify"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/password"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository"
 	"log/slog"
 	"net/http"
 )
 
 type RegisterWrapper interface {
-	RegisterUser(user domain.User) (domain.User, error)
+	RegisterUser(ctx context.Context, user domain.User) (domain.User, error)
+	VerificationTokenCreator
 }
 
-func RegisterHandler(logger *slog.Logger, register RegisterWrapper) func(http.ResponseWriter, *http.Request) {
+// RegisterHandler создает пациента в статусе pending и отправляет письмо для подтверждения email
+func RegisterHandler(logger *slog.Logger, register RegisterWrapper, hasher password.PasswordHasher, notifier notify.Notifier, cfg *config.Config) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Debug("RegisterHandler starting...")
+		logger.DebugContext(r.Context(), "RegisterHandler starting...")
 
-		request := domain.User{}
+		var request struct {
+			domain.User
+			Password string `json:"password"`
+		}
 		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
-		logger.Debug("request: ", request)
+		logger.DebugContext(r.Context(), "request", slog.String("email", request.Email))
+
+		if request.Password == "" {
+			response.SendFailureResponse(w, "Password is required", http.StatusBadRequest)
+			return
+		}
 
-		newUser, err := register.RegisterUser(request)
+		hash, err := hasher.Hash(request.Password)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to hash password", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to create user", http.StatusInternalServerError)
+			return
+		}
+		request.User.Password = hash
+
+		newUser, err := register.RegisterUser(r.Context(), request.User)
+		if errors.Is(err, repository.ErrorAlreadyExists) {
+			response.SendFailureResponse(w, "Failed to create user: user already exists", http.StatusConflict)
+			return
+		}
 		if err != nil {
 			response.SendFailureResponse(w, fmt.Sprintf("Failed to create user: %v", err), http.StatusInternalServerError)
 			return
 		}
-		logger.Info("RegisterHandler works successful")
+		newUser.Password = ""
+
+		// Пациент уже создан: если письмо не ушло, его можно запросить повторно
+		err = sendVerificationEmail(r.Context(), register, notifier, cfg, newUser.Id, newUser.Email)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to send verification email", slog.Int("patientID", newUser.Id), slog.String("error", err.Error()))
+		}
+		logger.InfoContext(r.Context(), "RegisterHandler works successful")
 
 		response.SendSuccessResponse(w, newUser, http.StatusCreated)
 	}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/api/response"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/config"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/notify"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/password"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/randtoken"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type ResetWrapper interface {
+	GetPatientIDByEmail(context.Context, string) (int, error)
+	CreatePasswordResetToken(context.Context, int, string, time.Time) error
+	ResetPassword(context.Context, string, string) (int, error)
+}
+
+// resetSendTimeout ограничивает выпуск токена и отправку письма, которые идут уже после ответа клиенту
+const resetSendTimeout = 30 * time.Second
+
+// ResetHandler выпускает одноразовый токен сброса пароля и отправляет ссылку на почту.
+// Ответ не зависит от того, существует ли пациент с таким email: поиск пациента, выпуск токена
+// и отправка письма идут после ответа, а их ошибки только пишутся в лог.
+func ResetHandler(logger *slog.Logger, wrapper ResetWrapper, notifier notify.Notifier, cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "ResetHandler starting...")
+
+		var request struct {
+			Email string `json:"email"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Email == "" {
+			response.SendFailureResponse(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		// Запрос завершится раньше отправки письма, его отмена не должна ее прерывать
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), resetSendTimeout)
+		go func() {
+			defer cancel()
+			sendPasswordReset(ctx, logger, wrapper, notifier, cfg, request.Email)
+		}()
+
+		response.SendSuccessResponse(w, "If the account exists, a reset link has been sent", http.StatusAccepted)
+	}
+}
+
+// sendPasswordReset выпускает токен сброса для пациента с email и отправляет ему ссылку
+func sendPasswordReset(ctx context.Context, logger *slog.Logger, wrapper ResetWrapper, notifier notify.Notifier, cfg *config.Config, email string) {
+	patientID, err := wrapper.GetPatientIDByEmail(ctx, email)
+	if errors.Is(err, repository.ErrorNotFound) {
+		logger.DebugContext(ctx, "Password reset requested for unknown email")
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to get patient", slog.String("error", err.Error()))
+		return
+	}
+
+	token, err := randtoken.New()
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to generate reset token", slog.String("error", err.Error()))
+		return
+	}
+
+	expiresAt := time.Now().Add(cfg.PasswordReset.TokenTTL)
+	if err = wrapper.CreatePasswordResetToken(ctx, patientID, randtoken.Hash(token), expiresAt); err != nil {
+		logger.ErrorContext(ctx, "Failed to save reset token", slog.Int("patientID", patientID), slog.String("error", err.Error()))
+		return
+	}
+
+	err = notifier.Send(ctx, notify.Message{
+		To:      email,
+		Subject: "MyHelp: сброс пароля",
+		Body: fmt.Sprintf("Для установки нового пароля перейдите по ссылке: %s\nСсылка действительна до %s.",
+			fmt.Sprintf(cfg.PasswordReset.LinkFormat, token),
+			expiresAt.Format("02.01.2006 15:04"),
+		),
+	})
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to send reset token", slog.Int("patientID", patientID), slog.String("error", err.Error()))
+		return
+	}
+
+	logger.InfoContext(ctx, "Password reset link sent", slog.Int("patientID", patientID))
+}
+
+// ResetConfirmHandler проверяет токен сброса и устанавливает новый пароль.
+// Все refresh токены пациента при этом отзываются.
+func ResetConfirmHandler(logger *slog.Logger, wrapper ResetWrapper, hasher password.PasswordHasher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "ResetConfirmHandler starting...")
+
+		var request struct {
+			Token    string `json:"token"`
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			response.SendFailureResponse(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if request.Token == "" || request.Password == "" {
+			response.SendFailureResponse(w, "Token and password are required", http.StatusBadRequest)
+			return
+		}
+
+		hash, err := hasher.Hash(request.Password)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to hash password", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to reset password", http.StatusInternalServerError)
+			return
+		}
+
+		patientID, err := wrapper.ResetPassword(r.Context(), randtoken.Hash(request.Token), hash)
+		if errors.Is(err, repository.ErrorInvalidToken) {
+			response.SendFailureResponse(w, "Reset token is invalid or expired", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to reset password", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to reset password", http.StatusInternalServerError)
+			return
+		}
+
+		logger.InfoContext(r.Context(), "ResetConfirmHandler works successful", slog.Int("patientID", patientID))
+		response.SendSuccessResponse(w, "Password has been reset", http.StatusOK)
+	}
+}
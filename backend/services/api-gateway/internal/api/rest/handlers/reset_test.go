@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/config"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/notify"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository"
+	"net/http"
+	"testing"
+	"time"
+)
+
+const testPatientEmail = "patient@example.com"
+
+type fakeReset struct {
+	lookupErr error
+	saveErr   error
+	done      chan struct{}
+}
+
+func (f *fakeReset) GetPatientIDByEmail(_ context.Context, email string) (int, error) {
+	if f.lookupErr != nil {
+		close(f.done)
+		return 0, f.lookupErr
+	}
+	if email != testPatientEmail {
+		close(f.done)
+		return 0, repository.ErrorNotFound
+	}
+	return 5, nil
+}
+
+func (f *fakeReset) CreatePasswordResetToken(context.Context, int, string, time.Time) error {
+	if f.saveErr != nil {
+		close(f.done)
+	}
+	return f.saveErr
+}
+
+func (f *fakeReset) ResetPassword(context.Context, string, string) (int, error) {
+	return 0, errors.New("not implemented")
+}
+
+type fakeNotifier struct {
+	err  error
+	sent chan notify.Message
+}
+
+func (f *fakeNotifier) Send(_ context.Context, msg notify.Message) error {
+	f.sent <- msg
+	return f.err
+}
+
+func TestResetHandlerSameResponse(t *testing.T) {
+	cfg := &config.Config{PasswordReset: config.PasswordReset{TokenTTL: time.Hour, LinkFormat: "https://example.com/reset?token=%s"}}
+
+	tests := []struct {
+		name      string
+		email     string
+		lookupErr error
+		saveErr   error
+		sendErr   error
+		wantSent  bool
+	}{
+		{"known email", testPatientEmail, nil, nil, nil, true},
+		{"unknown email", "unknown@example.com", nil, nil, nil, false},
+		{"lookup error", testPatientEmail, errors.New("connection refused"), nil, nil, false},
+		{"save error", testPatientEmail, nil, errors.New("connection refused"), nil, false},
+		{"send error", testPatientEmail, nil, nil, errors.New("smtp unavailable"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrapper := &fakeReset{lookupErr: tt.lookupErr, saveErr: tt.saveErr, done: make(chan struct{})}
+			// Отправка блокируется до чтения из канала: ответ не должен ее ждать
+			notifier := &fakeNotifier{err: tt.sendErr, sent: make(chan notify.Message)}
+			handler := ResetHandler(testLogger, wrapper, notifier, cfg)
+
+			w := postJSON(handler, map[string]string{"email": tt.email})
+			if w.Code != http.StatusAccepted {
+				t.Fatalf("status = %d, want 202", w.Code)
+			}
+
+			select {
+			case msg := <-notifier.sent:
+				if !tt.wantSent {
+					t.Fatalf("unexpected message to %s", msg.To)
+				}
+				if msg.To != tt.email {
+					t.Fatalf("message to %s, want %s", msg.To, tt.email)
+				}
+			case <-wrapper.done:
+				if tt.wantSent {
+					t.Fatal("reset link was not sent")
+				}
+			case <-time.After(time.Second):
+				t.Fatal("reset request was not processed")
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/api/response"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/randtoken"
+	"github.com/go-chi/chi/v5"
+	"log/slog"
+	"net/http"
+)
+
+type SessionWrapper interface {
+	RevokeRefreshTokenByHash(context.Context, string) error
+	RevokeAllRefreshTokens(context.Context, domain.Identity) error
+	ListSessions(context.Context, domain.Identity) ([]domain.Session, error)
+	RevokeSession(context.Context, domain.Identity, string) (bool, error)
+}
+
+// LogoutHandler завершает сессию, к которой относится переданный refresh токен.
+// Неизвестный или уже отозванный токен не считается ошибкой.
+func LogoutHandler(logger *slog.Logger, wrapper SessionWrapper) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "LogoutHandler starting...")
+
+		var request struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.RefreshToken == "" {
+			response.SendFailureResponse(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := wrapper.RevokeRefreshTokenByHash(r.Context(), randtoken.Hash(request.RefreshToken)); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to revoke refresh token", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to logout", http.StatusInternalServerError)
+			return
+		}
+
+		logger.InfoContext(r.Context(), "LogoutHandler works successful")
+		response.SendSuccessResponse(w, "Logged out", http.StatusOK)
+	}
+}
+
+// LogoutAllHandler завершает все сессии вызывающего
+func LogoutAllHandler(logger *slog.Logger, wrapper SessionWrapper) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "LogoutAllHandler starting...")
+
+		identity, ok := IdentityFromContext(r.Context())
+		if !ok {
+			unauthorized(w, "Authorization required")
+			return
+		}
+
+		if err := wrapper.RevokeAllRefreshTokens(r.Context(), identity); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to revoke refresh tokens", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to logout", http.StatusInternalServerError)
+			return
+		}
+
+		logger.InfoContext(r.Context(), "LogoutAllHandler works successful", slog.String("subject", identity.Sub()))
+		response.SendSuccessResponse(w, "Logged out from all sessions", http.StatusOK)
+	}
+}
+
+func GetSessionsHandler(logger *slog.Logger, wrapper SessionWrapper) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "GetSessionsHandler starting...")
+
+		identity, ok := IdentityFromContext(r.Context())
+		if !ok {
+			unauthorized(w, "Authorization required")
+			return
+		}
+
+		sessions, err := wrapper.ListSessions(r.Context(), identity)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to get sessions", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to get sessions", http.StatusInternalServerError)
+			return
+		}
+
+		logger.InfoContext(r.Context(), "GetSessionsHandler works successful")
+		response.SendSuccessResponse(w, sessions, http.StatusOK)
+	}
+}
+
+func RevokeSessionHandler(logger *slog.Logger, wrapper SessionWrapper) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "RevokeSessionHandler starting...")
+
+		identity, ok := IdentityFromContext(r.Context())
+		if !ok {
+			unauthorized(w, "Authorization required")
+			return
+		}
+
+		sessionID := chi.URLParam(r, "sessionID")
+		found, err := wrapper.RevokeSession(r.Context(), identity, sessionID)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to revoke session", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to revoke session", http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			response.SendFailureResponse(w, "Session not found", http.StatusNotFound)
+			return
+		}
+
+		logger.InfoContext(r.Context(), "RevokeSessionHandler works successful", slog.String("sessionID", sessionID))
+		response.SendSuccessResponse(w, "Session revoked", http.StatusOK)
+	}
+}
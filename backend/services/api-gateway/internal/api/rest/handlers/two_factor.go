@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/api/response"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/audit"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/metrics"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/randtoken"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/tokens"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/totp"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const recoveryCodesCount = 10
+
+type TwoFactorWrapper interface {
+	GetAdminTOTP(context.Context, int) (domain.AdminTOTP, error)
+	SetAdminTOTPSecret(context.Context, int, string) error
+	EnableAdminTOTP(context.Context, int, int64, []string) error
+	UseAdminTOTPCounter(context.Context, int, int64) (bool, error)
+	UseAdminRecoveryCode(context.Context, int, string) (bool, error)
+	RefreshTokenSaver
+}
+
+// sendTwoFactorChallenge отвечает промежуточным токеном вместо access/refresh токенов
+func sendTwoFactorChallenge(logger *slog.Logger, w http.ResponseWriter, r *http.Request, issuer *tokens.Issuer, identity domain.Identity, enrolled bool) {
+	challenge, err := issuer.IssueChallenge(identity)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Failed to generate token", slog.String("error", err.Error()))
+		response.SendFailureResponse(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	res := map[string]interface{}{
+		"adminID":            identity.Subject,
+		"mfa_token":          challenge.Value,
+		"mfa_token_lifetime": challenge.ExpiresAt.Format(time.RFC3339),
+	}
+	if enrolled {
+		res["mfa_required"] = true
+	} else {
+		res["mfa_enrollment_required"] = true
+	}
+
+	logger.InfoContext(r.Context(), "Two-factor step required", slog.String("subject", identity.Sub()), slog.Bool("enrolled", enrolled))
+	response.SendSuccessResponse(w, res, http.StatusOK)
+}
+
+// twoFactorAdmin определяет администратора по access токену или по промежуточному mfa_token.
+// Второй вариант нужен, когда 2FA обязательна и без нее access токен не выдается.
+func twoFactorAdmin(r *http.Request, issuer *tokens.Issuer) (domain.Identity, bool) {
+	tokenString, ok := bearerToken(r)
+	if !ok {
+		return domain.Identity{}, false
+	}
+
+	claims, err := issuer.VerifyAccess(tokenString)
+	if err != nil {
+		claims, err = issuer.VerifyChallenge(tokenString)
+	}
+	if err != nil || claims.Identity.Role != domain.RoleAdmin {
+		return domain.Identity{}, false
+	}
+
+	return claims.Identity, true
+}
+
+// TwoFactorEnrollHandler создает новый секрет TOTP и возвращает otpauth ссылку для приложения.
+// 2FA включается только после подтверждения кодом.
+func TwoFactorEnrollHandler(logger *slog.Logger, wrapper TwoFactorWrapper, issuer *tokens.Issuer, totpIssuer string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "TwoFactorEnrollHandler starting...")
+
+		identity, ok := twoFactorAdmin(r, issuer)
+		if !ok {
+			unauthorized(w, "Admin access token or mfa token is required")
+			return
+		}
+
+		state, err := wrapper.GetAdminTOTP(r.Context(), identity.Subject)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to get admin 2FA state", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to enroll two-factor authentication", http.StatusInternalServerError)
+			return
+		}
+		if state.Enabled {
+			response.SendFailureResponse(w, "Two-factor authentication is already enabled", http.StatusConflict)
+			return
+		}
+
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to generate totp secret", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to enroll two-factor authentication", http.StatusInternalServerError)
+			return
+		}
+
+		err = wrapper.SetAdminTOTPSecret(r.Context(), identity.Subject, secret)
+		if errors.Is(err, repository.ErrorAlreadyExists) {
+			response.SendFailureResponse(w, "Two-factor authentication is already enabled", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to save totp secret", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to enroll two-factor authentication", http.StatusInternalServerError)
+			return
+		}
+
+		logger.InfoContext(r.Context(), "TwoFactorEnrollHandler works successful", slog.Int("adminID", identity.Subject))
+		response.SendSuccessResponse(w, map[string]interface{}{
+			"secret":      secret,
+			"otpauth_uri": totp.URI(totpIssuer, state.Email, secret),
+		}, http.StatusOK)
+	}
+}
+
+// TwoFactorEnrollConfirmHandler включает 2FA после проверки первого кода и выдает резервные коды.
+// Резервные коды показываются один раз, хранятся только их хеши.
+func TwoFactorEnrollConfirmHandler(logger *slog.Logger, wrapper TwoFactorWrapper, issuer *tokens.Issuer, recorder *audit.Recorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "TwoFactorEnrollConfirmHandler starting...")
+
+		identity, ok := twoFactorAdmin(r, issuer)
+		if !ok {
+			unauthorized(w, "Admin access token or mfa token is required")
+			return
+		}
+
+		var request struct {
+			Code string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Code == "" {
+			response.SendFailureResponse(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		state, err := wrapper.GetAdminTOTP(r.Context(), identity.Subject)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to get admin 2FA state", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to enable two-factor authentication", http.StatusInternalServerError)
+			return
+		}
+		if state.Enabled {
+			response.SendFailureResponse(w, "Two-factor authentication is already enabled", http.StatusConflict)
+			return
+		}
+		if state.Secret == "" {
+			response.SendFailureResponse(w, "Two-factor enrollment is not started", http.StatusBadRequest)
+			return
+		}
+
+		counter, ok := totp.Validate(state.Secret, strings.TrimSpace(request.Code), time.Now())
+		if !ok {
+			response.SendFailureResponse(w, "Invalid two-factor code", http.StatusBadRequest)
+			return
+		}
+
+		codes, hashes, err := generateRecoveryCodes()
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to generate recovery codes", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to enable two-factor authentication", http.StatusInternalServerError)
+			return
+		}
+
+		err = wrapper.EnableAdminTOTP(r.Context(), identity.Subject, counter, hashes)
+		if errors.Is(err, repository.ErrorAlreadyExists) {
+			response.SendFailureResponse(w, "Two-factor authentication is already enabled", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to enable totp", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to enable two-factor authentication", http.StatusInternalServerError)
+			return
+		}
+
+		recorder.Record(r, audit.Event{
+			ActorRole:  domain.RoleAdmin,
+			ActorID:    identity.Subject,
+			Action:     "admin.2fa_enable",
+			TargetType: "admin",
+			TargetID:   strconv.Itoa(identity.Subject),
+		})
+
+		logger.InfoContext(r.Context(), "TwoFactorEnrollConfirmHandler works successful", slog.Int("adminID", identity.Subject))
+		response.SendSuccessResponse(w, map[string]interface{}{
+			"recovery_codes": codes,
+		}, http.StatusOK)
+	}
+}
+
+// TwoFactorVerifyHandler завершает вход администратора: принимает mfa_token и код из приложения
+// или резервный код, и только тогда выдает access и refresh токены.
+func TwoFactorVerifyHandler(logger *slog.Logger, wrapper TwoFactorWrapper, issuer *tokens.Issuer, limiter *LoginLimiter, recorder *audit.Recorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "TwoFactorVerifyHandler starting...")
+
+		var request struct {
+			MFAToken     string `json:"mfa_token"`
+			Code         string `json:"code"`
+			RecoveryCode string `json:"recovery_code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.MFAToken == "" {
+			response.SendFailureResponse(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if (request.Code == "") == (request.RecoveryCode == "") {
+			response.SendFailureResponse(w, "Either code or recovery_code is required", http.StatusBadRequest)
+			return
+		}
+
+		claims, err := issuer.VerifyChallenge(request.MFAToken)
+		if err != nil || claims.Identity.Role != domain.RoleAdmin {
+			response.SendFailureResponse(w, "Invalid or expired mfa token", http.StatusUnauthorized)
+			return
+		}
+		identity := claims.Identity
+
+		account := "2fa:" + identity.Sub()
+		if !limiter.allow(logger, w, r, account) {
+			return
+		}
+
+		state, err := wrapper.GetAdminTOTP(r.Context(), identity.Subject)
+		if errors.Is(err, repository.ErrorNotFound) {
+			// Администратора деактивировали после выдачи mfa_token: ответ не отличается от неверных данных
+			logger.InfoContext(r.Context(), "Admin is not active", slog.Int("adminID", identity.Subject))
+			metrics.LoginFailures.WithLabelValues(domain.RoleAdmin, "two_factor").Inc()
+			response.SendFailureResponse(w, invalidCredentials, http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to get admin 2FA state", slog.String("error", err.Error()))
+			limiter.release(logger, r, account)
+			response.SendFailureResponse(w, "Failed to auth user", http.StatusInternalServerError)
+			return
+		}
+		if !state.Enabled {
+			limiter.release(logger, r, account)
+			response.SendFailureResponse(w, "Two-factor authentication is not enabled", http.StatusBadRequest)
+			return
+		}
+
+		var valid bool
+		if request.Code != "" {
+			if counter, ok := totp.Validate(state.Secret, strings.TrimSpace(request.Code), time.Now()); ok {
+				// Код принимается один раз, даже если он еще не истек
+				valid, err = wrapper.UseAdminTOTPCounter(r.Context(), identity.Subject, counter)
+			}
+		} else {
+			valid, err = wrapper.UseAdminRecoveryCode(r.Context(), identity.Subject, randtoken.Hash(normalizeRecoveryCode(request.RecoveryCode)))
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to check two-factor code", slog.String("error", err.Error()))
+			limiter.release(logger, r, account)
+			response.SendFailureResponse(w, "Failed to auth user", http.StatusInternalServerError)
+			return
+		}
+		if !valid {
+			metrics.LoginFailures.WithLabelValues(domain.RoleAdmin, "two_factor").Inc()
+			response.SendFailureResponse(w, "Invalid two-factor code", http.StatusUnauthorized)
+			return
+		}
+		limiter.succeed(logger, r, account)
+
+		res, err := issueLoginTokens(issuer, wrapper, identity, r)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to generate token", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to generate token", http.StatusInternalServerError)
+			return
+		}
+		res["adminID"] = identity.Subject
+		if request.RecoveryCode != "" {
+			recordAdminLogin(r, recorder, identity.Subject, "recovery_code")
+		} else {
+			recordAdminLogin(r, recorder, identity.Subject, "totp")
+		}
+
+		logger.InfoContext(r.Context(), "TwoFactorVerifyHandler works successful", slog.Int("adminID", identity.Subject), slog.Bool("recovery_code", request.RecoveryCode != ""))
+		response.SendSuccessResponse(w, res, http.StatusOK)
+	}
+}
+
+// generateRecoveryCodes возвращает коды в виде xxxxx-xxxxx и их хеши для хранения
+func generateRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+
+	for i := 0; i < recoveryCodesCount; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(encoding.EncodeToString(b))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, randtoken.Hash(raw))
+	}
+
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
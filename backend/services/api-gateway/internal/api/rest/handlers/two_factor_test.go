@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/api/response"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/audit"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/lockout"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/randtoken"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/tokens"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/totp"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAdminID = 7
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// fakeTwoFactor повторяет условия из запросов хранилища: шаг принимается, только если он больше
+// последнего использованного, резервный код гасится один раз
+type fakeTwoFactor struct {
+	state         domain.AdminTOTP
+	stateErr      error
+	lastCounter   *int64
+	recoveryCodes map[string]bool
+}
+
+func (f *fakeTwoFactor) GetAdminTOTP(context.Context, int) (domain.AdminTOTP, error) {
+	return f.state, f.stateErr
+}
+
+func (f *fakeTwoFactor) SetAdminTOTPSecret(context.Context, int, string) error { return nil }
+
+func (f *fakeTwoFactor) EnableAdminTOTP(context.Context, int, int64, []string) error { return nil }
+
+func (f *fakeTwoFactor) UseAdminTOTPCounter(_ context.Context, _ int, counter int64) (bool, error) {
+	if f.lastCounter != nil && *f.lastCounter >= counter {
+		return false, nil
+	}
+	f.lastCounter = &counter
+	return true, nil
+}
+
+func (f *fakeTwoFactor) UseAdminRecoveryCode(_ context.Context, _ int, codeHash string) (bool, error) {
+	used, ok := f.recoveryCodes[codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	f.recoveryCodes[codeHash] = true
+	return true, nil
+}
+
+func (f *fakeTwoFactor) SaveRefreshToken(context.Context, domain.RefreshToken) error { return nil }
+
+type discardAudit struct{}
+
+func (discardAudit) SaveAuditEvent(context.Context, audit.Event) error { return nil }
+
+func newTestIssuer(t *testing.T) *tokens.Issuer {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey() error = %v", err)
+	}
+	key, err := tokens.ParseKey("test", tokens.AlgEdDSA, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("ParseKey() error = %v", err)
+	}
+	ring, err := tokens.NewKeyRing("test", []tokens.SigningKey{key})
+	if err != nil {
+		t.Fatalf("NewKeyRing() error = %v", err)
+	}
+	issuer, err := tokens.NewIssuer(tokens.Options{
+		Keys:         ring,
+		Issuer:       "test",
+		Audience:     "test",
+		AccessTTL:    time.Minute,
+		RefreshTTL:   time.Hour,
+		ChallengeTTL: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewIssuer() error = %v", err)
+	}
+	return issuer
+}
+
+func newTestLimiter() *LoginLimiter {
+	policy := lockout.Policy{FreeAttempts: 100, BaseDelay: time.Second, MaxDelay: time.Minute, Window: time.Hour}
+	store := lockout.NewMemoryStore(time.Hour)
+	return &LoginLimiter{Accounts: lockout.New(store, policy), IPs: lockout.New(store, policy)}
+}
+
+// totpCode считает код по RFC 6238 независимо от пакета totp
+func totpCode(t *testing.T, secret string, counter int64) string {
+	t.Helper()
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("decode secret error = %v", err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+func postJSON(handler http.Handler, body any) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(payload))
+	r.RemoteAddr = "10.0.0.1:1234"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func failureMessage(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var res response.FailureResponse
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatalf("decode failure response error = %v", err)
+	}
+	return res.Message
+}
+
+func TestTwoFactorVerifyHandler(t *testing.T) {
+	issuer := newTestIssuer(t)
+	challenge, err := issuer.IssueChallenge(domain.Identity{Subject: testAdminID, Role: domain.RoleAdmin})
+	if err != nil {
+		t.Fatalf("IssueChallenge() error = %v", err)
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error = %v", err)
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("generateRecoveryCodes() error = %v", err)
+	}
+
+	wrapper := &fakeTwoFactor{
+		state:         domain.AdminTOTP{Secret: secret, Enabled: true},
+		recoveryCodes: make(map[string]bool),
+	}
+	for _, hash := range hashes {
+		wrapper.recoveryCodes[hash] = false
+	}
+	handler := TwoFactorVerifyHandler(testLogger, wrapper, issuer, newTestLimiter(), audit.New("api-gateway", discardAudit{}, testLogger))
+
+	counter := time.Now().Unix() / 30
+	current := totpCode(t, secret, counter)
+	previous := totpCode(t, secret, counter-1)
+
+	tests := []struct {
+		name   string
+		body   map[string]string
+		status int
+	}{
+		{"current code", map[string]string{"code": current}, http.StatusOK},
+		{"replayed code", map[string]string{"code": current}, http.StatusUnauthorized},
+		// Код предыдущего шага еще в пределах допуска, но шаг старше уже использованного
+		{"older code after newer", map[string]string{"code": previous}, http.StatusUnauthorized},
+		{"wrong code", map[string]string{"code": "000000"}, http.StatusUnauthorized},
+		{"recovery code", map[string]string{"recovery_code": codes[0]}, http.StatusOK},
+		{"reused recovery code", map[string]string{"recovery_code": codes[0]}, http.StatusUnauthorized},
+		{"recovery code reformatted", map[string]string{"recovery_code": " " + strings.ToUpper(strings.ReplaceAll(codes[1], "-", " ")) + " "}, http.StatusOK},
+		{"unknown recovery code", map[string]string{"recovery_code": "aaaaa-bbbbb"}, http.StatusUnauthorized},
+		{"both code and recovery code", map[string]string{"code": current, "recovery_code": codes[2]}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := map[string]string{"mfa_token": challenge.Value}
+			for k, v := range tt.body {
+				body[k] = v
+			}
+			if w := postJSON(handler, body); w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+
+	if wrapper.lastCounter == nil || *wrapper.lastCounter != counter {
+		t.Fatalf("last used counter = %v, want %d", wrapper.lastCounter, counter)
+	}
+}
+
+func TestTwoFactorVerifyHandlerRejectsTokens(t *testing.T) {
+	issuer := newTestIssuer(t)
+	wrapper := &fakeTwoFactor{state: domain.AdminTOTP{Enabled: true}}
+	handler := TwoFactorVerifyHandler(testLogger, wrapper, issuer, newTestLimiter(), audit.New("api-gateway", discardAudit{}, testLogger))
+
+	access, err := issuer.IssueAccess(domain.Identity{Subject: testAdminID, Role: domain.RoleAdmin})
+	if err != nil {
+		t.Fatalf("IssueAccess() error = %v", err)
+	}
+	patient, err := issuer.IssueChallenge(domain.Identity{Subject: 5, Role: domain.RolePatient})
+	if err != nil {
+		t.Fatalf("IssueChallenge() error = %v", err)
+	}
+
+	for name, token := range map[string]string{"access token": access.Value, "patient challenge": patient.Value, "garbage": "x.y.z"} {
+		t.Run(name, func(t *testing.T) {
+			if w := postJSON(handler, map[string]string{"mfa_token": token, "code": "123456"}); w.Code != http.StatusUnauthorized {
+				t.Fatalf("status = %d, want 401", w.Code)
+			}
+		})
+	}
+}
+
+func TestTwoFactorVerifyHandlerDeactivatedAdmin(t *testing.T) {
+	issuer := newTestIssuer(t)
+	challenge, err := issuer.IssueChallenge(domain.Identity{Subject: testAdminID, Role: domain.RoleAdmin})
+	if err != nil {
+		t.Fatalf("IssueChallenge() error = %v", err)
+	}
+	wrapper := &fakeTwoFactor{stateErr: repository.ErrorNotFound}
+	handler := TwoFactorVerifyHandler(testLogger, wrapper, issuer, newTestLimiter(), audit.New("api-gateway", discardAudit{}, testLogger))
+
+	w := postJSON(handler, map[string]string{"mfa_token": challenge.Value, "code": "123456"})
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", w.Code)
+	}
+	if message := failureMessage(t, w); message != invalidCredentials {
+		t.Fatalf("message = %q, want %q", message, invalidCredentials)
+	}
+}
+
+type fakeLogin struct {
+	fakeTwoFactor
+	password string
+}
+
+func (f *fakeLogin) GetPassword(context.Context, string) (int, string, error) {
+	return 0, "", repository.ErrorNotFound
+}
+
+func (f *fakeLogin) GetAdminPassword(context.Context, string) (int, string, error) {
+	return testAdminID, f.password, nil
+}
+
+func (f *fakeLogin) UpdatePatientPasswordHash(context.Context, int, string) error { return nil }
+
+func (f *fakeLogin) UpdateAdminPasswordHash(context.Context, int, string) error { return nil }
+
+func (f *fakeLogin) GetPatientStatus(context.Context, int) (string, error) { return "", nil }
+
+type plainHasher struct{}
+
+func (plainHasher) Hash(password string) (string, error) { return password, nil }
+
+func (plainHasher) Verify(encoded, password string) (bool, bool, error) {
+	return encoded == password, false, nil
+}
+
+func TestLoginAdminHandlerDeactivatedBeforeTwoFactor(t *testing.T) {
+	wrapper := &fakeLogin{fakeTwoFactor: fakeTwoFactor{stateErr: repository.ErrorNotFound}, password: "secret"}
+	handler := LoginAdminHandler(testLogger, wrapper, newTestIssuer(t), plainHasher{}, newTestLimiter(), true, audit.New("api-gateway", discardAudit{}, testLogger))
+
+	w := postJSON(handler, credentials{Email: "admin@example.com", Password: "secret"})
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", w.Code)
+	}
+	if message := failureMessage(t, w); message != invalidCredentials {
+		t.Fatalf("message = %q, want %q", message, invalidCredentials)
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("generateRecoveryCodes() error = %v", err)
+	}
+	if len(codes) != recoveryCodesCount || len(hashes) != recoveryCodesCount {
+		t.Fatalf("got %d codes and %d hashes, want %d", len(codes), len(hashes), recoveryCodesCount)
+	}
+
+	format := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("code %q does not match xxxxx-xxxxx", code)
+		}
+		// Хранится только хеш нормализованного кода
+		if hashes[i] != randtoken.Hash(normalizeRecoveryCode(code)) {
+			t.Errorf("hash of code %d does not match normalized code", i)
+		}
+		if strings.Contains(hashes[i], normalizeRecoveryCode(code)) {
+			t.Errorf("hash of code %d contains the code", i)
+		}
+		if seen[code] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[code] = true
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/api/response"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+type ProfileWrapper interface {
+	GetPatientByID(context.Context, int) (domain.User, error)
+	AdminGetter
+	GetDoctorByID(context.Context, int) (domain.Doctor, error)
+}
+
+type UserSearchWrapper interface {
+	SearchPatients(context.Context, domain.UserFilter) ([]domain.User, int, error)
+}
+
+// MeHandler возвращает профиль владельца access токена: пациента, администратора или врача
+func MeHandler(logger *slog.Logger, wrapper ProfileWrapper) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "MeHandler starting...")
+
+		identity, ok := IdentityFromContext(r.Context())
+		if !ok {
+			unauthorized(w, "Authorization required")
+			return
+		}
+
+		var (
+			profile interface{}
+			err     error
+		)
+		switch identity.Role {
+		case domain.RolePatient:
+			profile, err = wrapper.GetPatientByID(r.Context(), identity.Subject)
+		case domain.RoleAdmin:
+			var admin domain.Admin
+			admin, err = wrapper.GetAdminByID(r.Context(), identity.Subject)
+			if err == nil && !admin.IsActive {
+				err = repository.ErrorNotFound
+			}
+			profile = admin
+		case domain.RoleDoctor:
+			profile, err = wrapper.GetDoctorByID(r.Context(), identity.Subject)
+		default:
+			err = repository.ErrorNotFound
+		}
+		if errors.Is(err, repository.ErrorNotFound) {
+			response.SendFailureResponse(w, "Profile not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to get profile", slog.String("subject", identity.Sub()), slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to get profile", http.StatusInternalServerError)
+			return
+		}
+
+		logger.InfoContext(r.Context(), "MeHandler works successful", slog.String("subject", identity.Sub()))
+		response.SendSuccessResponse(w, profile, http.StatusOK)
+	}
+}
+
+// SearchUsersHandler ищет пациентов по email, полису и ФИО.
+// Параметры: email, polic, name, page (с 1), limit (по умолчанию 20, не больше 100).
+func SearchUsersHandler(logger *slog.Logger, wrapper UserSearchWrapper) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "SearchUsersHandler starting...")
+
+		query := r.URL.Query()
+
+		page, err := positiveQueryInt(query.Get("page"), 1)
+		if err != nil {
+			response.SendFailureResponse(w, "Invalid page", http.StatusBadRequest)
+			return
+		}
+		limit, err := positiveQueryInt(query.Get("limit"), defaultPageLimit)
+		if err != nil {
+			response.SendFailureResponse(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(limit, maxPageLimit)
+
+		filter := domain.UserFilter{
+			Email:  strings.TrimSpace(query.Get("email")),
+			Polic:  strings.TrimSpace(query.Get("polic")),
+			Name:   strings.TrimSpace(query.Get("name")),
+			Limit:  limit,
+			Offset: (page - 1) * limit,
+		}
+
+		users, total, err := wrapper.SearchPatients(r.Context(), filter)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to search users", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to search users", http.StatusInternalServerError)
+			return
+		}
+
+		logger.InfoContext(r.Context(), "SearchUsersHandler works successful", slog.Int("found", total))
+		response.SendSuccessResponse(w, domain.UserPage{
+			Items: users,
+			Page:  page,
+			Limit: limit,
+			Total: total,
+		}, http.StatusOK)
+	}
+}
+
+func positiveQueryInt(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, errors.New("must be a positive integer")
+	}
+	return n, nil
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/api/response"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/config"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/notify"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/randtoken"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Код ошибки входа для пациента, не подтвердившего email
+const codeEmailNotVerified = "email_not_verified"
+
+type VerificationTokenCreator interface {
+	CreateEmailVerificationToken(context.Context, int, string, time.Time) error
+}
+
+type EmailVerificationWrapper interface {
+	VerificationTokenCreator
+	GetPendingPatient(context.Context, string) (int, time.Time, error)
+	VerifyEmail(context.Context, string) (int, error)
+}
+
+// sendVerificationEmail выпускает новый токен подтверждения и отправляет ссылку пациенту
+func sendVerificationEmail(ctx context.Context, wrapper VerificationTokenCreator, notifier notify.Notifier, cfg *config.Config, patientID int, email string) error {
+	token, err := randtoken.New()
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(cfg.EmailVerification.TokenTTL)
+	if err = wrapper.CreateEmailVerificationToken(ctx, patientID, randtoken.Hash(token), expiresAt); err != nil {
+		return err
+	}
+
+	return notifier.Send(ctx, notify.Message{
+		To:      email,
+		Subject: "MyHelp: подтверждение email",
+		Body: fmt.Sprintf("Для завершения регистрации перейдите по ссылке: %s\nСсылка действительна до %s.",
+			fmt.Sprintf(cfg.EmailVerification.LinkFormat, token),
+			expiresAt.Format("02.01.2006 15:04"),
+		),
+	})
+}
+
+// VerifyEmailHandler подтверждает email по токену из письма
+func VerifyEmailHandler(logger *slog.Logger, wrapper EmailVerificationWrapper) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "VerifyEmailHandler starting...")
+
+		var request struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Token == "" {
+			response.SendFailureResponse(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		patientID, err := wrapper.VerifyEmail(r.Context(), randtoken.Hash(request.Token))
+		if errors.Is(err, repository.ErrorInvalidToken) {
+			response.SendFailureResponse(w, "Verification token is invalid or expired", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to verify email", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to verify email", http.StatusInternalServerError)
+			return
+		}
+
+		logger.InfoContext(r.Context(), "VerifyEmailHandler works successful", slog.Int("patientID", patientID))
+		response.SendSuccessResponse(w, "Email has been verified", http.StatusOK)
+	}
+}
+
+// ResendVerificationHandler повторно отправляет письмо с подтверждением.
+// Ответ не зависит от того, есть ли такой неподтвержденный пациент и сработало ли ограничение частоты.
+func ResendVerificationHandler(logger *slog.Logger, wrapper EmailVerificationWrapper, notifier notify.Notifier, cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "ResendVerificationHandler starting...")
+
+		var request struct {
+			Email string `json:"email"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Email == "" {
+			response.SendFailureResponse(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		accepted := "If the account exists and is not verified, a new link has been sent"
+
+		patientID, lastSentAt, err := wrapper.GetPendingPatient(r.Context(), request.Email)
+		if errors.Is(err, repository.ErrorNotFound) {
+			logger.DebugContext(r.Context(), "Verification resend requested for unknown or verified email")
+			response.SendSuccessResponse(w, accepted, http.StatusAccepted)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to get patient", slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to resend verification", http.StatusInternalServerError)
+			return
+		}
+
+		if time.Since(lastSentAt) < cfg.EmailVerification.ResendInterval {
+			logger.InfoContext(r.Context(), "Verification resend throttled", slog.Int("patientID", patientID))
+			response.SendSuccessResponse(w, accepted, http.StatusAccepted)
+			return
+		}
+
+		if err = sendVerificationEmail(r.Context(), wrapper, notifier, cfg, patientID, request.Email); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to send verification email", slog.Int("patientID", patientID), slog.String("error", err.Error()))
+			response.SendFailureResponse(w, "Failed to resend verification", http.StatusInternalServerError)
+			return
+		}
+
+		logger.InfoContext(r.Context(), "ResendVerificationHandler works successful", slog.Int("patientID", patientID))
+		response.SendSuccessResponse(w, accepted, http.StatusAccepted)
+	}
+}
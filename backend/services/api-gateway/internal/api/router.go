@@ -1,26 +1,263 @@
 package api
 
 import (
+	"fmt"
 	"github.com/daariikk/MyHelp/services/api-gateway/internal/api/rest/handlers"
 	"github.com/daariikk/MyHelp/services/api-gateway/internal/config"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/audit"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/cors"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/health"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/lockout"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/metrics"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/notify"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/password"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/ratelimit"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/requestid"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/tokens"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/tracing"
 	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository/postgres"
 	"github.com/go-chi/chi/v5"
 	"log/slog"
+	"net/http"
 )
 
-func NewRouter(cfg *config.Config, logger *slog.Logger, storage *postgres.Storage) *chi.Mux {
+// Имя сервиса в журнале аудита
+const auditService = "api-gateway"
+
+type proxyRoute struct {
+	from     string
+	to       string
+	upstream config.Upstream
+}
+
+func NewRouter(cfg *config.Config, logger *slog.Logger, storage *postgres.Storage, checker *health.Checker) (*chi.Mux, error) {
+	corsPolicy, err := cors.New(cors.Options{
+		AllowedOrigins:   cfg.CORS.AllowedOrigins,
+		AllowedHeaders:   cfg.CORS.AllowedHeaders,
+		ExposedHeaders:   cfg.CORS.ExposedHeaders,
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           cfg.CORS.MaxAge,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	router := chi.NewRouter()
-	router.Use(handlers.CorsMiddleware)
+	router.Use(requestid.Middleware)
+	router.Use(tracing.Middleware)
+	router.Use(metrics.Middleware)
+	// Preflight-запросы отвечаются до аутентификации и ограничения частоты
+	router.Use(corsPolicy.Middleware)
+
+	router.Get("/healthz", checker.LivenessHandler())
+	router.Get("/readyz", checker.ReadinessHandler())
+
+	hasher := password.NewArgon2id(password.Params{
+		Memory:      cfg.Password.Memory,
+		Iterations:  cfg.Password.Iterations,
+		Parallelism: cfg.Password.Parallelism,
+		SaltLength:  16,
+		KeyLength:   32,
+	})
+
+	keys, err := loadKeyRing(cfg.JWT)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer, err := tokens.NewIssuer(tokens.Options{
+		Keys:         keys,
+		Issuer:       cfg.JWT.Issuer,
+		Audience:     cfg.JWT.Audience,
+		AccessTTL:    cfg.JWT.ExpireAccess,
+		RefreshTTL:   cfg.JWT.ExpireRefresh,
+		ChallengeTTL: cfg.TwoFactor.ChallengeTTL,
+		ClockSkew:    cfg.JWT.ClockSkew,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	notifier, err := notify.New(logger, notify.Options{
+		Type:     cfg.Notifier.Type,
+		FilePath: cfg.Notifier.FilePath,
+		SMTP: notify.SMTPOptions{
+			Host:     cfg.Notifier.SMTP.Host,
+			Port:     cfg.Notifier.SMTP.Port,
+			Username: cfg.Notifier.SMTP.Username,
+			Password: cfg.Notifier.SMTP.Password,
+			From:     cfg.Notifier.SMTP.From,
+			Timeout:  cfg.Notifier.SMTP.Timeout,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	recorder := audit.New(auditService, storage, logger)
+
+	// Открытые ключи для проверки токенов в сервисах
+	router.Get("/.well-known/jwks.json", handlers.JWKSHandler(logger, keys))
+
+	limiter, err := newLoginLimiter(cfg.Lockout, storage)
+	if err != nil {
+		return nil, err
+	}
+
+	rateStore, err := newRateLimitStore(cfg.RateLimit, storage)
+	if err != nil {
+		return nil, err
+	}
+	// Ограничения группы маршрутов: по IP - до проверки токена, по пользователю - после
+	limitByIP := func(group string) func(http.Handler) http.Handler {
+		return handlers.RateLimitByIP(logger, rateStore, group, rateLimitRule(cfg.RateLimit.Groups[group].PerIP))
+	}
+	limitBySubject := func(group string) func(http.Handler) http.Handler {
+		return handlers.RateLimitBySubject(logger, rateStore, group, rateLimitRule(cfg.RateLimit.Groups[group].PerSubject))
+	}
 
 	router.Route("/api/v1/auth", func(r chi.Router) {
-		r.Post("/signin", handlers.RegisterHandler(logger, storage))
-		r.Post("/signup", handlers.LoginHandler(logger, storage, cfg))
-		r.Post("/signup/admin", handlers.LoginAdminHandler(logger, storage, cfg))
-		r.Post("/refresh", handlers.RefreshHandler(logger, cfg))
-		r.Get("/get-user", handlers.GetUserHandler(logger, storage))
-		r.Get("/get-admin", handlers.GetAdminHandler(logger, storage))
-		// r.Post("/reset-password", handlers.ResetHandler(logger, cfg))
+		r.Use(limitByIP("auth"))
+
+		r.Post("/signin", handlers.RegisterHandler(logger, storage, hasher, notifier, cfg))
+		r.Post("/signup", handlers.LoginHandler(logger, storage, issuer, hasher, limiter))
+		r.Post("/signup/admin", handlers.LoginAdminHandler(logger, storage, issuer, hasher, limiter, cfg.TwoFactor.Required, recorder))
+		r.Post("/signup/doctor", handlers.LoginDoctorHandler(logger, storage, issuer, hasher, limiter))
+		r.Post("/signup/admin/verify", handlers.TwoFactorVerifyHandler(logger, storage, issuer, limiter, recorder))
+		// Подключение 2FA: по access токену администратора или по mfa_token, если 2FA обязательна
+		r.Post("/2fa/enroll", handlers.TwoFactorEnrollHandler(logger, storage, issuer, cfg.TwoFactor.Issuer))
+		r.Post("/2fa/enroll/confirm", handlers.TwoFactorEnrollConfirmHandler(logger, storage, issuer, recorder))
+		r.Post("/refresh", handlers.RefreshHandler(logger, issuer, storage))
+		r.Post("/reset-password", handlers.ResetHandler(logger, storage, notifier, cfg))
+		r.Post("/reset-password/confirm", handlers.ResetConfirmHandler(logger, storage, hasher))
+		r.Post("/verify-email", handlers.VerifyEmailHandler(logger, storage))
+		r.Post("/verify-email/resend", handlers.ResendVerificationHandler(logger, storage, notifier, cfg))
+		r.Post("/logout", handlers.LogoutHandler(logger, storage))
+		r.Post("/admin/accept-invite", handlers.AcceptAdminInviteHandler(logger, storage, hasher, recorder))
+
+		// Профиль и управление сессиями требуют access токен
+		r.Group(func(r chi.Router) {
+			r.Use(handlers.AuthMiddleware(logger, issuer))
+			r.Use(limitBySubject("auth"))
+			r.Get("/me", handlers.MeHandler(logger, storage))
+			r.Post("/logout-all", handlers.LogoutAllHandler(logger, storage))
+			r.Get("/sessions", handlers.GetSessionsHandler(logger, storage))
+			r.Delete("/sessions/{sessionID}", handlers.RevokeSessionHandler(logger, storage))
+
+			r.With(handlers.AuthorizeMiddleware(logger, routePolicies)).
+				Post("/unlock", handlers.UnlockHandler(logger, limiter, recorder))
+		})
 	})
 
-	return router
+	// Управление администраторами: свой пароль и учетные данные врачей меняет любой администратор, остальное - только super_admin
+	router.Route("/api/v1/admins", func(r chi.Router) {
+		r.Use(limitByIP("admin"))
+		r.Use(handlers.AuthMiddleware(logger, issuer))
+		r.Use(limitBySubject("admin"))
+		r.Use(handlers.AuthorizeMiddleware(logger, routePolicies))
+
+		r.Put("/me/password", handlers.ChangeAdminPasswordHandler(logger, storage, hasher, recorder))
+		r.Put("/doctors/{doctorID}/credentials", handlers.SetDoctorCredentialsHandler(logger, storage, hasher, recorder))
+
+		r.Group(func(r chi.Router) {
+			r.Use(handlers.SuperAdminMiddleware(logger, storage))
+			r.Get("/", handlers.ListAdminsHandler(logger, storage))
+			r.Post("/", handlers.InviteAdminHandler(logger, storage, hasher, notifier, cfg, recorder))
+			r.Patch("/{adminID}", handlers.UpdateAdminHandler(logger, storage, recorder))
+		})
+	})
+
+	// Поиск пациентов администратором
+	router.Route("/api/v1/users", func(r chi.Router) {
+		r.Use(limitByIP("admin"))
+		r.Use(handlers.AuthMiddleware(logger, issuer))
+		r.Use(limitBySubject("admin"))
+		r.Use(handlers.AuthorizeMiddleware(logger, routePolicies))
+		r.Get("/", handlers.SearchUsersHandler(logger, storage))
+	})
+
+	// Журнал аудита
+	router.Route("/api/v1/audit", func(r chi.Router) {
+		r.Use(limitByIP("admin"))
+		r.Use(handlers.AuthMiddleware(logger, issuer))
+		r.Use(limitBySubject("admin"))
+		r.Use(handlers.AuthorizeMiddleware(logger, routePolicies))
+		r.Get("/", handlers.ListAuditEventsHandler(logger, storage))
+		r.Get("/export", handlers.ExportAuditEventsHandler(logger, storage))
+	})
+
+	proxyRoutes := []proxyRoute{
+		// Личный кабинет пациента
+		{from: "/api/v1/account", to: "/MyHelp/account", upstream: cfg.Services.AccountService},
+		// Записи к врачу
+		{from: "/api/v1/appointments", to: "/MyHelp/schedule/appointments", upstream: cfg.Services.AppointmentService},
+		// Специализации, врачи и их расписание
+		{from: "/api/v1/specializations", to: "/MyHelp/specializations", upstream: cfg.Services.PolyclinicService},
+		{from: "/api/v1/doctors", to: "/MyHelp/doctors", upstream: cfg.Services.PolyclinicService},
+		{from: "/api/v1/schedule", to: "/MyHelp/schedule", upstream: cfg.Services.PolyclinicService},
+	}
+
+	proxies := make(map[string]http.Handler, len(proxyRoutes))
+	for _, route := range proxyRoutes {
+		proxy, err := handlers.ProxyHandler(logger, route.upstream, route.from, route.to)
+		if err != nil {
+			return nil, err
+		}
+		proxies[route.from] = proxy
+	}
+
+	// Все маршруты, кроме /api/v1/auth, доступны только с access токеном и по таблице доступа
+	router.Group(func(r chi.Router) {
+		r.Use(limitByIP("api"))
+		r.Use(handlers.AuthMiddleware(logger, issuer))
+		r.Use(limitBySubject("api"))
+		r.Use(handlers.AuthorizeMiddleware(logger, routePolicies))
+
+		for prefix, proxy := range proxies {
+			r.Mount(prefix, proxy)
+		}
+	})
+
+	return router, nil
+}
+
+func newLoginLimiter(cfg config.Lockout, storage *postgres.Storage) (*handlers.LoginLimiter, error) {
+	var store lockout.Store
+	switch cfg.Store {
+	case "memory":
+		store = lockout.NewMemoryStore(cfg.Window)
+	case "postgres":
+		store = storage
+	default:
+		return nil, fmt.Errorf("unknown lockout store %q", cfg.Store)
+	}
+
+	policy := lockout.Policy{
+		BaseDelay: cfg.BaseDelay,
+		MaxDelay:  cfg.MaxDelay,
+		Window:    cfg.Window,
+	}
+	accounts, ips := policy, policy
+	accounts.FreeAttempts = cfg.AccountFreeAttempts
+	ips.FreeAttempts = cfg.IPFreeAttempts
+
+	return &handlers.LoginLimiter{
+		Accounts: lockout.New(store, accounts),
+		IPs:      lockout.New(store, ips),
+	}, nil
+}
+
+func newRateLimitStore(cfg config.RateLimit, storage *postgres.Storage) (ratelimit.Store, error) {
+	switch cfg.Store {
+	case "memory":
+		return ratelimit.NewMemoryStore(), nil
+	case "postgres":
+		return storage, nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", cfg.Store)
+	}
+}
+
+func rateLimitRule(cfg config.RateLimitRule) ratelimit.Rule {
+	return ratelimit.Rule{Rate: cfg.Rate, Burst: cfg.Burst}
 }
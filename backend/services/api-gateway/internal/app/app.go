@@ -5,11 +5,19 @@ import (
 	"fmt"
 	"github.com/daariikk/MyHelp/services/api-gateway/internal/api"
 	"github.com/daariikk/MyHelp/services/api-gateway/internal/config"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/audit"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/health"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/logger/handlers/slogctx"
 	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/logger/handlers/slogpretty"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/metrics"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/ratelimit"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/tracing"
 	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository/postgres"
+	"github.com/daariikk/MyHelp/services/migrations"
 	"log"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 )
@@ -24,7 +32,12 @@ type App struct {
 	logger *slog.Logger
 	config *config.Config
 	server *http.Server
-	db     *postgres.Storage
+	// nil, если метрики отключены
+	metricsServer *http.Server
+	checker       *health.Checker
+	db            *postgres.Storage
+	// Досылает накопленные спаны при остановке
+	shutdownTracing func(context.Context) error
 }
 
 type DBParams struct {
@@ -36,6 +49,16 @@ type DBParams struct {
 func New(config *config.Config) *App {
 	logger := setupLogger(config.Env)
 
+	shutdownTracing, err := tracing.Setup(context.Background(), serviceName, tracing.Options{
+		Exporter:    config.Tracing.Exporter,
+		Endpoint:    config.Tracing.Endpoint,
+		FilePath:    config.Tracing.FilePath,
+		SampleRatio: config.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Fatalf("tracing setup error: %s", err)
+	}
+
 	dbUrlConnection := CreateDBConnectionUrl(*config)
 	logger.Debug("DB url connection", slog.String("url", dbUrlConnection))
 	ctx := context.Background()
@@ -45,7 +68,39 @@ func New(config *config.Config) *App {
 		log.Fatalf("db create error: %s", err)
 	}
 
-	router := api.NewRouter(config, logger, storage)
+	metrics.RegisterPool(storage)
+
+	checker := health.New(readinessTimeout)
+	checker.Add("database", health.Database(storage))
+	checker.Add("migrations", health.Migrations(storage, migrations.Latest()))
+	for _, upstream := range []struct{ name, url string }{
+		{"account_service", config.Services.AccountService.URL},
+		{"appointment_service", config.Services.AppointmentService.URL},
+		{"polyclinic_service", config.Services.PolyclinicService.URL},
+	} {
+		healthURL, err := url.JoinPath(upstream.url, "healthz")
+		if err != nil {
+			log.Fatalf("invalid upstream url %q: %s", upstream.url, err)
+		}
+		checker.Add(upstream.name, health.Upstream(http.DefaultClient, healthURL))
+	}
+
+	router, err := api.NewRouter(config, logger, storage, checker)
+	if err != nil {
+		log.Fatalf("router create error: %s", err)
+	}
+
+	var metricsServer *http.Server
+	if config.MetricsAddress != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		metricsServer = &http.Server{
+			Addr:         config.MetricsAddress,
+			Handler:      mux,
+			ReadTimeout:  config.Timeout,
+			WriteTimeout: config.Timeout,
+		}
+	}
 
 	return &App{
 		logger: logger,
@@ -57,20 +112,41 @@ func New(config *config.Config) *App {
 			WriteTimeout: config.Timeout,
 			IdleTimeout:  config.IdleTimeout,
 		},
-		db: storage,
+		metricsServer:   metricsServer,
+		checker:         checker,
+		db:              storage,
+		shutdownTracing: shutdownTracing,
 	}
 }
 
 func (a *App) Run() {
 	defer a.db.Close()
 
-	errChan := make(chan error, 1)
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+	if a.config.Audit.Retention > 0 {
+		go audit.RunPurge(purgeCtx, a.logger, a.db, a.config.Audit.Retention, a.config.Audit.PurgeInterval)
+	}
+	// Ведро, простоявшее час, заведомо восстановилось при любых разумных настройках
+	if a.config.RateLimit.Store == "postgres" {
+		go ratelimit.RunPurge(purgeCtx, a.logger, a.db, time.Hour, 10*time.Minute)
+	}
+
+	errChan := make(chan error, 2)
 	go func() {
 		a.logger.Info("Starting server", slog.String("addr", a.config.Address))
 		if err := a.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			errChan <- err
 		}
 	}()
+	if a.metricsServer != nil {
+		go func() {
+			a.logger.Info("Starting metrics server", slog.String("addr", a.metricsServer.Addr))
+			if err := a.metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				errChan <- err
+			}
+		}()
+	}
 
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
@@ -84,12 +160,23 @@ func (a *App) Run() {
 		a.logger.Info("Shutting down server...")
 	}
 
+	a.checker.Shutdown()
+	time.Sleep(a.config.ShutdownDelay)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := a.server.Shutdown(ctx); err != nil {
 		a.logger.Error("Server forced to shutdown", slog.String("error", err.Error()))
 	}
+	if a.metricsServer != nil {
+		if err := a.metricsServer.Shutdown(ctx); err != nil {
+			a.logger.Error("Metrics server forced to shutdown", slog.String("error", err.Error()))
+		}
+	}
+	if err := a.shutdownTracing(ctx); err != nil {
+		a.logger.Error("Failed to flush traces", slog.String("error", err.Error()))
+	}
 
 	a.logger.Info("Server exiting")
 }
@@ -114,7 +201,8 @@ func setupLogger(env string) *slog.Logger {
 		)
 	}
 
-	return log
+	// id запроса из контекста добавляется к записям в любом окружении
+	return slog.New(slogctx.NewHandler(log.Handler()))
 }
 
 func setupPrettySlog() *slog.Logger {
@@ -1,7 +1,15 @@
 package app
 
+import "time"
+
 const (
 	envLocal = "local"
 	envProd  = "prod"
 	envDev   = "dev"
 )
+
+// Имя сервиса в трассах
+const serviceName = "api-gateway"
+
+// Ограничение на все проверки /readyz
+const readinessTimeout = 2 * time.Second
@@ -9,30 +9,176 @@ import (
 )
 
 type Config struct {
-	Env             string `yaml:"env" env-default:"local"`
-	DatabaseBaseUrl string `yaml:"database_connection_url" env-required:"true"`
-	HTTPServer      `yaml:"http_server"`
-	JWT             `yaml:"jwt"`
-	Services        `yaml:"services"`
+	Env               string `yaml:"env" env-default:"local"`
+	DatabaseBaseUrl   string `yaml:"database_connection_url" env-required:"true"`
+	HTTPServer        `yaml:"http_server"`
+	JWT               `yaml:"jwt"`
+	Services          `yaml:"services"`
+	Password          `yaml:"password"`
+	Notifier          `yaml:"notifier"`
+	PasswordReset     `yaml:"password_reset"`
+	Lockout           `yaml:"lockout"`
+	EmailVerification `yaml:"email_verification"`
+	AdminInvite       `yaml:"admin_invite"`
+	TwoFactor         `yaml:"two_factor"`
+	Audit             `yaml:"audit"`
+	RateLimit         `yaml:"rate_limit"`
+	CORS              `yaml:"cors"`
+	Tracing           `yaml:"tracing"`
 }
 
 type HTTPServer struct {
 	Address     string        `yaml:"address" env-default:"localhost:8080"`
 	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
+	// Отдельный адрес для /metrics, чтобы метрики не были доступны снаружи вместе с API.
+	// Пустое значение отключает метрики.
+	MetricsAddress string `yaml:"metrics_address" env:"METRICS_ADDRESS" env-default:"localhost:9082"`
+	// Сколько /readyz отвечает отказом до остановки сервера, чтобы балансировщик успел
+	// снять экземпляр с ротации
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" env-default:"0s"`
 }
 
 type JWT struct {
-	AccessSecretKey  string        `yaml:"access_secret_key" env-required:"true"`
-	RefreshSecretKey string        `yaml:"refresh_secret_key" env-required:"true"`
-	ExpireAccess     time.Duration `yaml:"expire_access" env-default:"30m"`
-	ExpireRefresh    time.Duration `yaml:"expire_refresh" env-default:"24h"`
+	ExpireAccess  time.Duration `yaml:"expire_access" env-default:"30m"`
+	ExpireRefresh time.Duration `yaml:"expire_refresh" env-default:"24h"`
+	Issuer        string        `yaml:"issuer" env-default:"myhelp-api-gateway"`
+	Audience      string        `yaml:"audience" env-default:"myhelp"`
+	// Допустимое расхождение часов между узлами при проверке токенов
+	ClockSkew time.Duration `yaml:"clock_skew" env-default:"30s"`
+	// kid ключа, которым подписываются новые токены
+	ActiveKeyID string       `yaml:"active_key_id" env:"JWT_ACTIVE_KEY_ID" env-required:"true"`
+	Keys        []SigningKey `yaml:"keys"`
+}
+
+// Ключ подписи токенов. PEM берется из файла или из переменной окружения.
+// Для предыдущих ключей достаточно открытой части.
+type SigningKey struct {
+	ID        string `yaml:"kid"`
+	Algorithm string `yaml:"alg"` // RS256 или EdDSA
+	File      string `yaml:"file"`
+	Env       string `yaml:"env"`
 }
 
 type Services struct {
-	AccountService     string `yaml:"account_service" env-required:"true"`
-	AppointmentService string `yaml:"appointment_service" env-required:"true"`
-	PolyclinicService  string `yaml:"polyclinic_service" env-required:"true"`
+	AccountService     Upstream `yaml:"account_service"`
+	AppointmentService Upstream `yaml:"appointment_service"`
+	PolyclinicService  Upstream `yaml:"polyclinic_service"`
+}
+
+type Upstream struct {
+	URL     string        `yaml:"url" env-required:"true"`
+	Timeout time.Duration `yaml:"timeout" env-default:"3s"`
+}
+
+// Параметры argon2id для хеширования паролей
+type Password struct {
+	Memory      uint32 `yaml:"memory" env-default:"65536"`
+	Iterations  uint32 `yaml:"iterations" env-default:"3"`
+	Parallelism uint8  `yaml:"parallelism" env-default:"2"`
+}
+
+// Доставка писем пользователям: smtp, log или file (для локальной разработки)
+type Notifier struct {
+	Type     string `yaml:"type" env-default:"log"`
+	FilePath string `yaml:"file_path" env-default:"outbox.txt"`
+	SMTP     SMTP   `yaml:"smtp"`
+}
+
+type SMTP struct {
+	Host     string        `yaml:"host" env:"SMTP_HOST"`
+	Port     int           `yaml:"port" env:"SMTP_PORT" env-default:"587"`
+	Username string        `yaml:"username" env:"SMTP_USERNAME"`
+	Password string        `yaml:"password" env:"SMTP_PASSWORD"`
+	From     string        `yaml:"from" env:"SMTP_FROM"`
+	Timeout  time.Duration `yaml:"timeout" env-default:"10s"`
+}
+
+type PasswordReset struct {
+	TokenTTL time.Duration `yaml:"token_ttl" env-default:"30m"`
+	// Ссылка на страницу сброса пароля, %s заменяется токеном
+	LinkFormat string `yaml:"link_format" env-required:"true"`
+}
+
+type EmailVerification struct {
+	TokenTTL time.Duration `yaml:"token_ttl" env-default:"24h"`
+	// Повторное письмо отправляется не чаще этого интервала
+	ResendInterval time.Duration `yaml:"resend_interval" env-default:"1m"`
+	// Ссылка на страницу подтверждения, %s заменяется токеном
+	LinkFormat string `yaml:"link_format" env-required:"true"`
+}
+
+type AdminInvite struct {
+	TokenTTL time.Duration `yaml:"token_ttl" env-default:"72h"`
+	// Ссылка на страницу принятия приглашения, %s заменяется токеном
+	LinkFormat string `yaml:"link_format" env-required:"true"`
+}
+
+// Ограничение частоты запросов (token bucket). Store: memory (один экземпляр gateway) или postgres.
+// Для каждой группы маршрутов задаются отдельные ведра на IP и на аутентифицированного пользователя.
+type RateLimit struct {
+	Store  string                    `yaml:"store" env-default:"memory"`
+	Groups map[string]RateLimitGroup `yaml:"groups"`
+}
+
+type RateLimitGroup struct {
+	PerIP      RateLimitRule `yaml:"per_ip"`
+	PerSubject RateLimitRule `yaml:"per_subject"`
+}
+
+// Нулевой rate отключает ограничение
+type RateLimitRule struct {
+	// Запросов в секунду в среднем
+	Rate float64 `yaml:"rate"`
+	// Сколько запросов можно сделать подряд
+	Burst int `yaml:"burst"`
+}
+
+// CORS для браузерных клиентов. Заголовки выставляет только gateway, сервисы за ним их не задают.
+type CORS struct {
+	// Точные origin, шаблоны с одной звездочкой ("https://*.myhelp.ru") или "*"
+	AllowedOrigins   []string      `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS" env-separator:","`
+	AllowedHeaders   []string      `yaml:"allowed_headers" env-default:"Content-Type,Authorization,X-Request-ID"`
+	ExposedHeaders   []string      `yaml:"exposed_headers" env-default:"Retry-After,X-Request-ID"`
+	AllowCredentials bool          `yaml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS" env-default:"false"`
+	MaxAge           time.Duration `yaml:"max_age" env-default:"10m"`
+}
+
+// Журнал аудита: события старше retention удаляются раз в purge_interval.
+// Нулевой retention отключает очистку.
+type Audit struct {
+	Retention     time.Duration `yaml:"retention" env:"AUDIT_RETENTION" env-default:"8760h"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env-default:"24h"`
+}
+
+// Двухфакторная аутентификация администраторов (TOTP).
+// Пока required выключен, 2FA подключается администратором по желанию.
+type TwoFactor struct {
+	Required bool   `yaml:"required" env:"ADMIN_2FA_REQUIRED" env-default:"false"`
+	Issuer   string `yaml:"issuer" env-default:"MyHelp"`
+	// Сколько живет токен между вводом пароля и вводом кода
+	ChallengeTTL time.Duration `yaml:"challenge_ttl" env-default:"5m"`
+}
+
+// Защита от подбора пароля. Store: memory (один экземпляр gateway) или postgres.
+// После free_attempts неудач вход блокируется на base_delay, каждая следующая неудача
+// удваивает блокировку до max_delay. Счетчик сбрасывается после window без неудач.
+type Lockout struct {
+	Store               string        `yaml:"store" env-default:"memory"`
+	AccountFreeAttempts int           `yaml:"account_free_attempts" env-default:"5"`
+	IPFreeAttempts      int           `yaml:"ip_free_attempts" env-default:"20"`
+	BaseDelay           time.Duration `yaml:"base_delay" env-default:"30s"`
+	MaxDelay            time.Duration `yaml:"max_delay" env-default:"15m"`
+	Window              time.Duration `yaml:"window" env-default:"1h"`
+}
+
+// Трассировка OpenTelemetry. Exporter: otlp (коллектор по OTLP/HTTP), stdout или file для
+// локального запуска, none - спаны не записываются, но traceparent пробрасывается дальше.
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER" env-default:"none"`
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" env-default:"http://localhost:4318"`
+	FilePath    string  `yaml:"file_path" env-default:"traces.json"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" env-default:"1"`
 }
 
 func MustLoad() *Config {
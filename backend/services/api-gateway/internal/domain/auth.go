@@ -1 +1,82 @@
 package domain
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Заголовки, которыми gateway передает сервисам проверенную личность вызывающего
+const (
+	HeaderPatientID = "X-Patient-ID"
+	HeaderAdminID   = "X-Admin-ID"
+	HeaderDoctorID  = "X-Doctor-ID"
+	HeaderRole      = "X-Role"
+)
+
+const (
+	RolePatient = "patient"
+	RoleAdmin   = "admin"
+	RoleDoctor  = "doctor"
+)
+
+type Identity struct {
+	Subject int
+	Role    string
+}
+
+// Sub возвращает типизированный subject токена, например "patient:5"
+func (i Identity) Sub() string {
+	return i.Role + ":" + strconv.Itoa(i.Subject)
+}
+
+// ParseSub разбирает типизированный subject токена
+func ParseSub(sub string) (Identity, error) {
+	role, id, found := strings.Cut(sub, ":")
+	if !found {
+		return Identity{}, errors.New("subject has no type")
+	}
+
+	switch role {
+	case RolePatient, RoleAdmin, RoleDoctor:
+	default:
+		return Identity{}, fmt.Errorf("unknown subject type %q", role)
+	}
+
+	subject, err := strconv.Atoi(id)
+	if err != nil || subject <= 0 {
+		return Identity{}, fmt.Errorf("invalid subject id %q", id)
+	}
+
+	return Identity{Subject: subject, Role: role}, nil
+}
+
+// RefreshToken - запись о выданном refresh токене. Сам токен не хранится, только его хеш.
+type RefreshToken struct {
+	JTI       string
+	FamilyID  string
+	Identity  Identity
+	TokenHash string
+	UserAgent string
+	IP        string
+	ExpiresAt time.Time
+}
+
+// Session - активная цепочка refresh токенов одного входа
+type Session struct {
+	Id         string    `json:"sessionID"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	StartedAt  time.Time `json:"started_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// LoginAttempts - счетчик неудачных попыток входа по ключу (аккаунт или IP)
+type LoginAttempts struct {
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
@@ -1,5 +1,17 @@
 package domain
 
+// Роли администраторов: super_admin управляет администраторами, clinic_admin - данными поликлиники
+const (
+	AdminRoleSuper  = "super_admin"
+	AdminRoleClinic = "clinic_admin"
+)
+
+// Статусы пациента: pending - email еще не подтвержден
+const (
+	PatientStatusPending = "pending"
+	PatientStatusActive  = "active"
+)
+
 type User struct {
 	Id         int    `json:"patientID"`
 	Surname    string `json:"surname"`
@@ -7,14 +19,53 @@ type User struct {
 	Patronymic string `json:"patronymic"`
 	Polic      string `json:"polic"`
 	Email      string `json:"email"`
-	Password   string `json:"password"`
-	IsDeleted  bool   `json:"is_deleted"`
+	// Хеш пароля никогда не сериализуется в ответы
+	Password  string `json:"-"`
+	IsDeleted bool   `json:"is_deleted"`
+	Status    string `json:"status,omitempty"`
 }
 
 type Admin struct {
-	Id       int    `json:"adminID"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Id        int    `json:"adminID"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	AdminRole string `json:"admin_role"`
+	// Хеш пароля никогда не сериализуется в ответы
+	Password string `json:"-"`
 	IsActive bool   `json:"isActive"`
 }
+
+// Doctor - учетная запись врача. Профиль врача ведет polyclinic-service, gateway хранит только учетные данные.
+type Doctor struct {
+	Id               int    `json:"doctorID"`
+	Surname          string `json:"surname"`
+	Name             string `json:"name"`
+	Patronymic       string `json:"patronymic"`
+	SpecializationID int    `json:"specializationID"`
+	Email            string `json:"email"`
+}
+
+// AdminTOTP - состояние двухфакторной аутентификации администратора
+type AdminTOTP struct {
+	Email   string
+	Secret  string
+	Enabled bool
+}
+
+// UserFilter - условия поиска пациентов администратором. Пустое поле не фильтрует.
+type UserFilter struct {
+	Email string
+	Polic string
+	// Ищется в фамилии, имени и отчестве
+	Name   string
+	Limit  int
+	Offset int
+}
+
+// UserPage - страница результатов поиска пациентов
+type UserPage struct {
+	Items []User `json:"items"`
+	Page  int    `json:"page"`
+	Limit int    `json:"limit"`
+	Total int    `json:"total"`
+}
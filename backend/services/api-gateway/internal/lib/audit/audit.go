@@ -0,0 +1,144 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Заголовки запроса, из которых берутся личность вызывающего и id запроса
+const (
+	HeaderRequestID = "X-Request-ID"
+	headerRole      = "X-Role"
+)
+
+var subjectHeaders = map[string]string{
+	"patient": "X-Patient-ID",
+	"admin":   "X-Admin-ID",
+	"doctor":  "X-Doctor-ID",
+}
+
+// Роль для действий без аутентифицированного вызывающего
+const RoleAnonymous = "anonymous"
+
+// Event - запись журнала аудита
+type Event struct {
+	ID         int64             `json:"id"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Service    string            `json:"service"`
+	ActorRole  string            `json:"actor_role"`
+	ActorID    int               `json:"actor_id,omitempty"`
+	Action     string            `json:"action"`
+	TargetType string            `json:"target_type"`
+	TargetID   string            `json:"target_id"`
+	Changes    map[string]Change `json:"changes,omitempty"`
+	RequestID  string            `json:"request_id,omitempty"`
+	IP         string            `json:"ip,omitempty"`
+}
+
+// Change - значение поля до и после действия
+type Change struct {
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+type Store interface {
+	SaveAuditEvent(context.Context, Event) error
+}
+
+type Recorder struct {
+	service string
+	store   Store
+	logger  *slog.Logger
+}
+
+func New(service string, store Store, logger *slog.Logger) *Recorder {
+	return &Recorder{service: service, store: store, logger: logger}
+}
+
+// Record дополняет событие данными запроса и сохраняет его. Если вызывающий не указан явно,
+// он берется из заголовков, выставленных после проверки access токена.
+// Ошибка сохранения только логируется: действие уже выполнено.
+func (rec *Recorder) Record(r *http.Request, event Event) {
+	event.Service = rec.service
+	event.OccurredAt = time.Now()
+	event.RequestID = r.Header.Get(HeaderRequestID)
+	event.IP = clientIP(r)
+	if event.ActorRole == "" {
+		event.ActorRole, event.ActorID = actor(r)
+	}
+
+	if err := rec.store.SaveAuditEvent(r.Context(), event); err != nil {
+		rec.logger.Error("Failed to save audit event",
+			slog.String("action", event.Action),
+			slog.String("target_type", event.TargetType),
+			slog.String("target_id", event.TargetID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// Diff возвращает поля, которые различаются в JSON-представлении before и after.
+// nil означает отсутствие объекта: до создания или после удаления.
+func Diff(before, after interface{}) map[string]Change {
+	beforeFields := fields(before)
+	afterFields := fields(after)
+
+	changes := make(map[string]Change)
+	for name, value := range beforeFields {
+		if other, ok := afterFields[name]; !ok || !bytes.Equal(value, other) {
+			changes[name] = Change{Before: value, After: nullIfEmpty(other)}
+		}
+	}
+	for name, value := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			changes[name] = Change{Before: json.RawMessage("null"), After: value}
+		}
+	}
+	return changes
+}
+
+func fields(value interface{}) map[string]json.RawMessage {
+	result := make(map[string]json.RawMessage)
+	if value == nil {
+		return result
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return result
+	}
+	_ = json.Unmarshal(data, &result)
+	return result
+}
+
+func nullIfEmpty(value json.RawMessage) json.RawMessage {
+	if value == nil {
+		return json.RawMessage("null")
+	}
+	return value
+}
+
+func actor(r *http.Request) (string, int) {
+	role := r.Header.Get(headerRole)
+	header, ok := subjectHeaders[role]
+	if !ok {
+		return RoleAnonymous, 0
+	}
+	id, _ := strconv.Atoi(r.Header.Get(header))
+	return role, id
+}
+
+// clientIP - адрес, с которого пришел запрос. Gateway стоит на границе, поэтому
+// X-Forwarded-For от клиента не учитывается.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
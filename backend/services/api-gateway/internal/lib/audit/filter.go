@@ -0,0 +1,16 @@
+package audit
+
+import "time"
+
+// Filter - условия выборки журнала аудита. Пустое поле не фильтрует.
+type Filter struct {
+	ActorRole  string
+	ActorID    int
+	Action     string
+	TargetType string
+	TargetID   string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
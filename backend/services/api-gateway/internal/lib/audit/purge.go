@@ -0,0 +1,32 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type Purger interface {
+	PurgeAuditEvents(ctx context.Context, before time.Time) (int64, error)
+}
+
+// RunPurge раз в interval удаляет события старше retention. Блокирует до отмены ctx.
+func RunPurge(ctx context.Context, logger *slog.Logger, purger Purger, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := purger.PurgeAuditEvents(ctx, time.Now().Add(-retention))
+		if err != nil {
+			logger.Error("Failed to purge audit events", slog.String("error", err.Error()))
+		} else if deleted > 0 {
+			logger.Info("Audit events purged", slog.Int64("deleted", deleted))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
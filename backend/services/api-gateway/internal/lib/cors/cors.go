@@ -0,0 +1,165 @@
+package cors
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Методы, которые используют сервисы за gateway
+var allowedMethods = []string{
+	http.MethodGet,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+type Options struct {
+	// Точные origin ("https://myhelp.ru"), шаблоны с одной звездочкой
+	// ("https://*.myhelp.ru", "http://localhost:*") или "*" для любого origin
+	AllowedOrigins   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+type pattern struct {
+	prefix string
+	suffix string
+}
+
+type Policy struct {
+	anyOrigin      bool
+	exact          map[string]struct{}
+	patterns       []pattern
+	credentials    bool
+	methods        string
+	allowedHeaders string
+	exposedHeaders string
+	maxAge         string
+}
+
+func New(opts Options) (*Policy, error) {
+	p := &Policy{
+		exact:          make(map[string]struct{}),
+		credentials:    opts.AllowCredentials,
+		methods:        strings.Join(allowedMethods, ", "),
+		allowedHeaders: strings.Join(opts.AllowedHeaders, ", "),
+		exposedHeaders: strings.Join(opts.ExposedHeaders, ", "),
+	}
+	if opts.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(opts.MaxAge.Seconds()))
+	}
+
+	for _, origin := range opts.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(origin), "/"))
+		switch {
+		case origin == "":
+			continue
+		case origin == "*":
+			// Браузер не принимает "*" вместе с учетными данными, а отражать любой origin небезопасно
+			if opts.AllowCredentials {
+				return nil, fmt.Errorf("cors: origin \"*\" can not be used with credentials")
+			}
+			p.anyOrigin = true
+		case strings.Count(origin, "*") > 1:
+			return nil, fmt.Errorf("cors: origin pattern %q has more than one wildcard", origin)
+		case strings.Contains(origin, "*"):
+			prefix, suffix, _ := strings.Cut(origin, "*")
+			if !strings.Contains(prefix, "://") {
+				return nil, fmt.Errorf("cors: origin pattern %q must include scheme", origin)
+			}
+			p.patterns = append(p.patterns, pattern{prefix: prefix, suffix: suffix})
+		default:
+			p.exact[origin] = struct{}{}
+		}
+	}
+
+	return p, nil
+}
+
+// Allowed сообщает, разрешен ли запрос с указанного origin
+func (p *Policy) Allowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if _, ok := p.exact[origin]; ok {
+		return true
+	}
+	return slices.ContainsFunc(p.patterns, func(pt pattern) bool {
+		if len(origin) <= len(pt.prefix)+len(pt.suffix) ||
+			!strings.HasPrefix(origin, pt.prefix) || !strings.HasSuffix(origin, pt.suffix) {
+			return false
+		}
+		// Звездочка заменяет часть хоста или порт, но не путь
+		return !strings.Contains(origin[len(pt.prefix):len(origin)-len(pt.suffix)], "/")
+	})
+}
+
+// Middleware выставляет CORS-заголовки для разрешенных origin и отвечает на preflight-запросы.
+// Запросы без Origin проходят без изменений.
+func (p *Policy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := w.Header()
+		if !p.anyOrigin {
+			header.Add("Vary", "Origin")
+		}
+
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		allowed := p.Allowed(origin)
+
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			if !allowed {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			p.setOrigin(header, origin)
+			header.Set("Access-Control-Allow-Methods", p.methods)
+			if p.allowedHeaders != "" {
+				header.Set("Access-Control-Allow-Headers", p.allowedHeaders)
+			}
+			if p.maxAge != "" {
+				header.Set("Access-Control-Max-Age", p.maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if allowed {
+			p.setOrigin(header, origin)
+			if p.exposedHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", p.exposedHeaders)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (p *Policy) setOrigin(header http.Header, origin string) {
+	if p.anyOrigin {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.credentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
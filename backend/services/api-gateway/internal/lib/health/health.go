@@ -0,0 +1,116 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check возвращает ошибку, если зависимость недоступна
+type Check func(ctx context.Context) error
+
+type result struct {
+	Status   string `json:"status"`
+	Duration string `json:"duration,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type report struct {
+	Status string            `json:"status"`
+	Checks map[string]result `json:"checks,omitempty"`
+}
+
+const (
+	statusOK   = "ok"
+	statusFail = "fail"
+)
+
+// Checker отвечает на пробы liveness и readiness. Readiness выполняет все проверки
+// параллельно, каждая ограничена timeout.
+type Checker struct {
+	timeout      time.Duration
+	checks       map[string]Check
+	shuttingDown atomic.Bool
+}
+
+func New(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout, checks: make(map[string]Check)}
+}
+
+// Add регистрирует проверку. Вызывается до запуска сервера.
+func (c *Checker) Add(name string, check Check) {
+	c.checks[name] = check
+}
+
+// Shutdown переводит readiness в состояние отказа, чтобы балансировщик
+// перестал слать запросы, пока сервер завершает текущие
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+}
+
+// LivenessHandler сообщает только, что процесс жив и обрабатывает запросы
+func (c *Checker) LivenessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, report{Status: statusOK})
+	}
+}
+
+// ReadinessHandler отвечает 200, если все проверки прошли, иначе 503 с деталями по каждой
+func (c *Checker) ReadinessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if c.shuttingDown.Load() {
+			writeReport(w, report{
+				Status: statusFail,
+				Checks: map[string]result{"shutdown": {Status: statusFail, Error: "service is shutting down"}},
+			})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), c.timeout)
+		defer cancel()
+
+		rep := report{Status: statusOK, Checks: make(map[string]result, len(c.checks))}
+		var (
+			mu sync.Mutex
+			wg sync.WaitGroup
+		)
+		for name, check := range c.checks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				start := time.Now()
+				err := check(ctx)
+				res := result{Status: statusOK, Duration: time.Since(start).String()}
+				if err != nil {
+					res.Status = statusFail
+					res.Error = err.Error()
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				rep.Checks[name] = res
+				if err != nil {
+					rep.Status = statusFail
+				}
+			}()
+		}
+		wg.Wait()
+
+		writeReport(w, rep)
+	}
+}
+
+func writeReport(w http.ResponseWriter, rep report) {
+	status := http.StatusOK
+	if rep.Status != statusOK {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(rep)
+}
@@ -0,0 +1,98 @@
+package lockout
+
+import (
+	"context"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"time"
+)
+
+// Store хранит счетчики неудачных попыток входа
+type Store interface {
+	// UpdateLoginAttempts атомарно читает и изменяет счетчик ключа: пока выполняется update,
+	// другие вызовы для того же ключа ждут. Если попыток не было, update получает нулевое значение.
+	UpdateLoginAttempts(ctx context.Context, key string, update func(*domain.LoginAttempts)) error
+	ResetLoginAttempts(ctx context.Context, key string) error
+}
+
+// Policy задает, сколько ошибок прощается и как растет блокировка после них
+type Policy struct {
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	Window       time.Duration
+}
+
+// Guard блокирует ключ после FreeAttempts неудач подряд.
+// Каждая следующая неудача удваивает блокировку, но не больше MaxDelay.
+type Guard struct {
+	store  Store
+	policy Policy
+	now    func() time.Time
+}
+
+func New(store Store, policy Policy) *Guard {
+	return &Guard{store: store, policy: policy, now: time.Now}
+}
+
+// Acquire учитывает попытку входа до проверки пароля и возвращает оставшееся время блокировки.
+// Попытка считается неудачной, пока ее не вернут через Release или Reset: так параллельные
+// запросы не успевают пройти проверку раньше, чем будет учтена хотя бы одна неудача.
+// Заблокированная попытка не учитывается.
+func (g *Guard) Acquire(ctx context.Context, key string) (time.Duration, error) {
+	var remaining time.Duration
+	err := g.store.UpdateLoginAttempts(ctx, key, func(attempts *domain.LoginAttempts) {
+		now := g.now()
+		if remaining = attempts.LockedUntil.Sub(now); remaining > 0 {
+			return
+		}
+		remaining = 0
+
+		// Если с последней неудачи прошло больше Window, счет начинается заново
+		if now.Sub(attempts.LastFailureAt) > g.policy.Window {
+			attempts.Failures = 0
+		}
+		attempts.Failures++
+		attempts.LastFailureAt = now
+		if delay := g.delay(attempts.Failures); delay > 0 {
+			attempts.LockedUntil = now.Add(delay)
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	return remaining, nil
+}
+
+// Release возвращает попытку, которая не оказалась неудачной: вход удался или упал по вине сервера
+func (g *Guard) Release(ctx context.Context, key string) error {
+	return g.store.UpdateLoginAttempts(ctx, key, func(attempts *domain.LoginAttempts) {
+		if attempts.Failures > 0 {
+			attempts.Failures--
+		}
+		// Блокировку могла поставить сама возвращаемая попытка
+		if g.delay(attempts.Failures) == 0 {
+			attempts.LockedUntil = time.Time{}
+		}
+	})
+}
+
+// Reset сбрасывает счетчик после успешного входа или ручной разблокировки
+func (g *Guard) Reset(ctx context.Context, key string) error {
+	return g.store.ResetLoginAttempts(ctx, key)
+}
+
+func (g *Guard) delay(failures int) time.Duration {
+	over := failures - g.policy.FreeAttempts
+	if over <= 0 {
+		return 0
+	}
+
+	delay := g.policy.BaseDelay
+	for i := 1; i < over; i++ {
+		delay *= 2
+		if delay >= g.policy.MaxDelay {
+			return g.policy.MaxDelay
+		}
+	}
+	return min(delay, g.policy.MaxDelay)
+}
@@ -0,0 +1,192 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	FreeAttempts: 3,
+	BaseDelay:    time.Second,
+	MaxDelay:     10 * time.Second,
+	Window:       time.Minute,
+}
+
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestGuard() (*Guard, *testClock) {
+	clock := &testClock{now: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore(time.Hour)
+	store.now = clock.Now
+	guard := New(store, testPolicy)
+	guard.now = clock.Now
+	return guard, clock
+}
+
+func acquire(t *testing.T, guard *Guard, key string) time.Duration {
+	t.Helper()
+	remaining, err := guard.Acquire(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	return remaining
+}
+
+func TestDelaySchedule(t *testing.T) {
+	guard, _ := newTestGuard()
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{6, 4 * time.Second},
+		{7, 8 * time.Second},
+		{8, 10 * time.Second},
+		{100, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := guard.delay(tt.failures); got != tt.want {
+			t.Errorf("delay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestAcquireBackoff(t *testing.T) {
+	guard, clock := newTestGuard()
+	const key = "patient:a@example.com"
+
+	// Бесплатные попытки и первая платная проходят; платная ставит блокировку для следующих
+	for i := 1; i <= testPolicy.FreeAttempts+1; i++ {
+		if remaining := acquire(t, guard, key); remaining != 0 {
+			t.Fatalf("attempt %d locked for %v", i, remaining)
+		}
+	}
+	if remaining := acquire(t, guard, key); remaining != time.Second {
+		t.Fatalf("attempt while locked remaining = %v, want 1s", remaining)
+	}
+
+	// Каждая следующая неудача удваивает блокировку
+	for _, want := range []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		clock.Advance(time.Minute / 2)
+		if remaining := acquire(t, guard, key); remaining != 0 {
+			t.Fatalf("attempt after lock expired locked for %v", remaining)
+		}
+		if remaining := acquire(t, guard, key); remaining != want {
+			t.Fatalf("remaining = %v, want %v", remaining, want)
+		}
+	}
+}
+
+func TestAcquireWindowReset(t *testing.T) {
+	guard, clock := newTestGuard()
+	const key = "ip:10.0.0.1"
+
+	for i := 0; i < testPolicy.FreeAttempts; i++ {
+		acquire(t, guard, key)
+		clock.Advance(testPolicy.Window)
+	}
+	// Паузы ровно в Window не сбрасывают счет: следующая попытка платная
+	acquire(t, guard, key)
+	if remaining := acquire(t, guard, key); remaining == 0 {
+		t.Fatal("attempt after FreeAttempts+1 failures within window is not locked")
+	}
+
+	// После паузы дольше Window счет начинается заново, и блокировка снова растет с BaseDelay
+	clock.Advance(testPolicy.Window + time.Second)
+	for i := 1; i <= testPolicy.FreeAttempts+1; i++ {
+		if remaining := acquire(t, guard, key); remaining != 0 {
+			t.Fatalf("attempt %d after window reset locked for %v", i, remaining)
+		}
+	}
+	if remaining := acquire(t, guard, key); remaining != time.Second {
+		t.Fatalf("remaining = %v, want 1s", remaining)
+	}
+}
+
+func TestReleaseAndReset(t *testing.T) {
+	guard, _ := newTestGuard()
+	ctx := context.Background()
+	const key = "ip:10.0.0.2"
+
+	for i := 0; i < testPolicy.FreeAttempts; i++ {
+		acquire(t, guard, key)
+	}
+	// Удачная попытка, поставившая блокировку, снимает ее при возврате
+	acquire(t, guard, key)
+	if err := guard.Release(ctx, key); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if remaining := acquire(t, guard, key); remaining != 0 {
+		t.Fatalf("attempt after release locked for %v", remaining)
+	}
+	if remaining := acquire(t, guard, key); remaining != time.Second {
+		t.Fatalf("remaining = %v, want 1s", remaining)
+	}
+
+	if err := guard.Reset(ctx, key); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	for i := 1; i <= testPolicy.FreeAttempts+1; i++ {
+		if remaining := acquire(t, guard, key); remaining != 0 {
+			t.Fatalf("attempt %d after reset locked for %v", i, remaining)
+		}
+	}
+}
+
+func TestAcquireConcurrent(t *testing.T) {
+	guard, _ := newTestGuard()
+	const (
+		key      = "patient:b@example.com"
+		requests = 50
+	)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			remaining, err := guard.Acquire(context.Background(), key)
+			if err != nil {
+				t.Errorf("Acquire() error = %v", err)
+				return
+			}
+			if remaining == 0 {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if want := testPolicy.FreeAttempts + 1; allowed != want {
+		t.Fatalf("allowed = %d of %d parallel attempts, want %d", allowed, requests, want)
+	}
+}
@@ -0,0 +1,62 @@
+package lockout
+
+import (
+	"context"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"sync"
+	"time"
+)
+
+// MemoryStore хранит счетчики в памяти процесса.
+// Подходит для одного экземпляра gateway; при нескольких нужен общий Store.
+type MemoryStore struct {
+	mu       sync.Mutex
+	attempts map[string]domain.LoginAttempts
+	maxAge   time.Duration
+	cleaned  time.Time
+	now      func() time.Time
+}
+
+// NewMemoryStore создает хранилище; записи старше maxAge без блокировки удаляются
+func NewMemoryStore(maxAge time.Duration) *MemoryStore {
+	return &MemoryStore{
+		attempts: make(map[string]domain.LoginAttempts),
+		maxAge:   maxAge,
+		now:      time.Now,
+	}
+}
+
+func (s *MemoryStore) UpdateLoginAttempts(_ context.Context, key string, update func(*domain.LoginAttempts)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cleanup(s.now())
+
+	attempts := s.attempts[key]
+	update(&attempts)
+	s.attempts[key] = attempts
+
+	return nil
+}
+
+func (s *MemoryStore) ResetLoginAttempts(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+// cleanup удаляет устаревшие записи, не чаще раза в минуту
+func (s *MemoryStore) cleanup(now time.Time) {
+	if now.Sub(s.cleaned) < time.Minute {
+		return
+	}
+	s.cleaned = now
+
+	for key, attempts := range s.attempts {
+		if now.Sub(attempts.LastFailureAt) > s.maxAge && now.After(attempts.LockedUntil) {
+			delete(s.attempts, key)
+		}
+	}
+}
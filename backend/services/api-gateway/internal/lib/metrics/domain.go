@@ -0,0 +1,10 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// LoginFailures - неудачные попытки входа. stage: password или two_factor.
+var LoginFailures = factory.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "login_failures_total",
+	Help:      "Failed login attempts by role and stage.",
+}, []string{"role", "stage"})
@@ -0,0 +1,98 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier доставляет сообщения пользователю (письмо, файл для локальной разработки и т.д.)
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogNotifier пишет сообщения в лог. Только для локальной разработки.
+type LogNotifier struct {
+	logger *slog.Logger
+}
+
+func NewLogNotifier(logger *slog.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+func (n *LogNotifier) Send(_ context.Context, msg Message) error {
+	n.logger.Info("Notification",
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+		slog.String("body", msg.Body),
+	)
+	return nil
+}
+
+// FileNotifier дописывает сообщения в файл. Только для локальной разработки.
+type FileNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+func (n *FileNotifier) Send(_ context.Context, msg Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	file, err := os.OpenFile(n.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open notification file: %w", err)
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC3339),
+		msg.To,
+		msg.Subject,
+		msg.Body,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write notification: %w", err)
+	}
+
+	return nil
+}
+
+const (
+	TypeLog  = "log"
+	TypeFile = "file"
+	TypeSMTP = "smtp"
+)
+
+type Options struct {
+	Type     string
+	FilePath string
+	SMTP     SMTPOptions
+}
+
+// New создает Notifier по типу из конфига
+func New(logger *slog.Logger, opts Options) (Notifier, error) {
+	switch opts.Type {
+	case TypeLog:
+		return NewLogNotifier(logger), nil
+	case TypeFile:
+		return NewFileNotifier(opts.FilePath), nil
+	case TypeSMTP:
+		return NewSMTPNotifier(opts.SMTP)
+	default:
+		return nil, fmt.Errorf("unknown notifier type %q", opts.Type)
+	}
+}
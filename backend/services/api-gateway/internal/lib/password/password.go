@@ -0,0 +1,152 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordHasher хеширует и проверяет пароли.
+// needsRehash=true означает, что пароль верный, но хранится в устаревшем виде
+// (открытым текстом, bcrypt или с другими параметрами) и его стоит перехешировать.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(encoded, password string) (ok bool, needsRehash bool, err error)
+}
+
+type Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+const argon2idPrefix = "$argon2id$"
+
+var ErrInvalidHash = errors.New("invalid password hash format")
+
+type Argon2id struct {
+	params Params
+}
+
+func NewArgon2id(params Params) *Argon2id {
+	return &Argon2id{params: params}
+}
+
+// Hash возвращает хеш в формате PHC: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+func (a *Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, a.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.params.Iterations, a.params.Memory, a.params.Parallelism, a.params.KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		a.params.Memory,
+		a.params.Iterations,
+		a.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (a *Argon2id) Verify(encoded, password string) (bool, bool, error) {
+	// Схема определяется по полному разбору хеша, а не по префиксу: старый пароль
+	// открытым текстом может начинаться с "$2a$" или "$argon2id$"
+	if strings.HasPrefix(encoded, argon2idPrefix) {
+		params, salt, key, err := decodeArgon2id(encoded)
+		if err == nil {
+			return a.verifyArgon2id(params, salt, key, password)
+		}
+		if !errors.Is(err, ErrInvalidHash) {
+			return false, false, err
+		}
+	}
+
+	if isBcrypt(encoded) {
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, err
+		}
+		return true, true, nil
+	}
+
+	// Старые записи хранят пароль открытым текстом
+	ok := subtle.ConstantTimeCompare([]byte(encoded), []byte(password)) == 1
+	return ok, ok, nil
+}
+
+func (a *Argon2id) verifyArgon2id(params Params, salt, key []byte, password string) (bool, bool, error) {
+	otherKey := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	if subtle.ConstantTimeCompare(key, otherKey) != 1 {
+		return false, false, nil
+	}
+
+	needsRehash := params.Memory != a.params.Memory ||
+		params.Iterations != a.params.Iterations ||
+		params.Parallelism != a.params.Parallelism ||
+		params.KeyLength != a.params.KeyLength
+
+	return true, needsRehash, nil
+}
+
+func decodeArgon2id(encoded string) (Params, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+	if version != argon2.Version {
+		return Params{}, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	var params Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+	if len(salt) == 0 {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+	params.SaltLength = uint32(len(salt))
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+	if len(key) == 0 {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
+
+// Хеш bcrypt: $2a$, $2b$ или $2y$, двузначная стоимость и 53 символа соли и ключа
+var bcryptHash = regexp.MustCompile(`^\$2[aby]\$\d{2}\$[./A-Za-z0-9]{53}$`)
+
+func isBcrypt(encoded string) bool {
+	return bcryptHash.MatchString(encoded)
+}
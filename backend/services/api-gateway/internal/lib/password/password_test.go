@@ -0,0 +1,143 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testParams = Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2idRoundTrip(t *testing.T) {
+	hasher := NewArgon2id(testParams)
+
+	encoded, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("Hash() = %q, want PHC string with configured params", encoded)
+	}
+
+	other, _ := hasher.Hash("correct horse")
+	if other == encoded {
+		t.Fatal("Hash() returned the same string twice, salt is not random")
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		wantOK   bool
+	}{
+		{"correct password", "correct horse", true},
+		{"wrong password", "battery staple", false},
+		{"empty password", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash, err := hasher.Verify(encoded, tt.password)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if ok != tt.wantOK || needsRehash {
+				t.Fatalf("Verify() = %v, %v, want %v, false", ok, needsRehash, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestArgon2idNeedsRehash(t *testing.T) {
+	encoded, err := NewArgon2id(testParams).Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		params Params
+		want   bool
+	}{
+		{"same params", testParams, false},
+		{"more memory", Params{Memory: 128, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}, true},
+		{"more iterations", Params{Memory: 64, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}, true},
+		{"more parallelism", Params{Memory: 64, Iterations: 1, Parallelism: 2, SaltLength: 16, KeyLength: 32}, true},
+		{"longer key", Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 64}, true},
+		// Длина соли не влияет на стойкость настолько, чтобы перехешировать
+		{"longer salt", Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 32, KeyLength: 32}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash, err := NewArgon2id(tt.params).Verify(encoded, "secret")
+			if err != nil || !ok {
+				t.Fatalf("Verify() = %v, %v, want true, nil", ok, err)
+			}
+			if needsRehash != tt.want {
+				t.Fatalf("needsRehash = %v, want %v", needsRehash, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyBcrypt(t *testing.T) {
+	encoded, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword() error = %v", err)
+	}
+	hasher := NewArgon2id(testParams)
+
+	ok, needsRehash, err := hasher.Verify(string(encoded), "secret")
+	if err != nil || !ok || !needsRehash {
+		t.Fatalf("Verify(bcrypt, correct) = %v, %v, %v, want true, true, nil", ok, needsRehash, err)
+	}
+
+	ok, needsRehash, err = hasher.Verify(string(encoded), "wrong")
+	if err != nil || ok || needsRehash {
+		t.Fatalf("Verify(bcrypt, wrong) = %v, %v, %v, want false, false, nil", ok, needsRehash, err)
+	}
+}
+
+func TestVerifyPlaintext(t *testing.T) {
+	hasher := NewArgon2id(testParams)
+
+	tests := []struct {
+		name     string
+		stored   string
+		password string
+		wantOK   bool
+	}{
+		{"plain match", "secret", "secret", true},
+		{"plain mismatch", "secret", "Secret", false},
+		{"empty stored", "", "secret", false},
+		// Пароли открытым текстом, похожие на хеши, не должны блокировать вход
+		{"bcrypt prefix", "$2a$mypassword", "$2a$mypassword", true},
+		{"bcrypt prefix with cost", "$2b$10$short", "$2b$10$short", true},
+		{"argon2id prefix", "$argon2id$hunter2", "$argon2id$hunter2", true},
+		{"argon2id prefix mismatch", "$argon2id$hunter2", "hunter2", false},
+		{"argon2id empty key", "$argon2id$v=19$m=64,t=1,p=1$c2FsdA$", "$argon2id$v=19$m=64,t=1,p=1$c2FsdA$", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash, err := hasher.Verify(tt.stored, tt.password)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			// Верный пароль открытым текстом всегда перехешируется
+			if ok != tt.wantOK || needsRehash != tt.wantOK {
+				t.Fatalf("Verify() = %v, %v, want %v, %v", ok, needsRehash, tt.wantOK, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestVerifyUnsupportedArgon2Version(t *testing.T) {
+	encoded, err := NewArgon2id(testParams).Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	encoded = strings.Replace(encoded, "v=19", "v=16", 1)
+
+	if _, _, err := NewArgon2id(testParams).Verify(encoded, "secret"); err == nil {
+		t.Fatal("Verify() error = nil, want unsupported version error")
+	}
+}
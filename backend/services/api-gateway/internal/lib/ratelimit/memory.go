@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	rule    Rule
+}
+
+// MemoryStore хранит ведра в памяти процесса.
+// Подходит для одного экземпляра gateway; при нескольких нужен общий Store.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]bucket
+	cleaned time.Time
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]bucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) TakeRateLimitToken(_ context.Context, key string, rule Rule) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.cleanup(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = bucket{tokens: float64(rule.Burst), updated: now}
+	}
+	b.tokens = refill(b.tokens, now.Sub(b.updated), rule)
+	b.updated = now
+	b.rule = rule
+
+	if b.tokens < 1 {
+		s.buckets[key] = b
+		return rule.RetryAfter(b.tokens), nil
+	}
+
+	b.tokens--
+	s.buckets[key] = b
+	return 0, nil
+}
+
+// cleanup удаляет полностью восстановившиеся ведра, не чаще раза в минуту
+func (s *MemoryStore) cleanup(now time.Time) {
+	if now.Sub(s.cleaned) < time.Minute {
+		return
+	}
+	s.cleaned = now
+
+	for key, b := range s.buckets {
+		if refill(b.tokens, now.Sub(b.updated), b.rule) >= float64(b.rule.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func refill(tokens float64, elapsed time.Duration, rule Rule) float64 {
+	return math.Min(float64(rule.Burst), tokens+elapsed.Seconds()*rule.Rate)
+}
@@ -0,0 +1,29 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type Purger interface {
+	PurgeRateLimitBuckets(ctx context.Context, idle time.Duration) error
+}
+
+// RunPurge раз в interval удаляет из общего хранилища ведра, не использовавшиеся дольше idle.
+// Блокирует до отмены ctx.
+func RunPurge(ctx context.Context, logger *slog.Logger, purger Purger, idle, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := purger.PurgeRateLimitBuckets(ctx, idle); err != nil {
+				logger.Error("Failed to purge rate limit buckets", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
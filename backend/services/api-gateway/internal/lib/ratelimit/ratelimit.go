@@ -0,0 +1,28 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Rule - параметры token bucket: ведро вмещает Burst запросов и пополняется
+// на Rate запросов в секунду. Нулевой Rate отключает ограничение.
+type Rule struct {
+	Rate  float64
+	Burst int
+}
+
+func (r Rule) Enabled() bool {
+	return r.Rate > 0 && r.Burst > 0
+}
+
+// RetryAfter - через сколько в ведре появится целый токен, если сейчас в нем tokens
+func (r Rule) RetryAfter(tokens float64) time.Duration {
+	return time.Duration((1 - tokens) / r.Rate * float64(time.Second))
+}
+
+// Store списывает токен из ведра key. Возвращает 0, если запрос разрешен,
+// иначе - через сколько можно повторить запрос.
+type Store interface {
+	TakeRateLimitToken(ctx context.Context, key string, rule Rule) (time.Duration, error)
+}
@@ -0,0 +1,191 @@
+package tokens
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+)
+
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// SigningKey - ключ из связки. У выведенных из оборота ключей может не быть
+// закрытой части: ими уже ничего не подписывается, но выданные токены еще проверяются.
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	private   crypto.PrivateKey
+	public    crypto.PublicKey
+}
+
+// ParseKey разбирает PEM с закрытым (PKCS#8, PKCS#1) или открытым (PKIX) ключом
+func ParseKey(id, algorithm string, data []byte) (SigningKey, error) {
+	if id == "" {
+		return SigningKey{}, errors.New("key id is required")
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return SigningKey{}, fmt.Errorf("key %q: no PEM block found", id)
+	}
+
+	key := SigningKey{ID: id, Algorithm: algorithm}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return SigningKey{}, fmt.Errorf("key %q: %w", id, err)
+		}
+		// PKCS#8 допускает ключи, которыми нельзя подписывать (например, X25519)
+		signer, ok := private.(crypto.Signer)
+		if !ok {
+			return SigningKey{}, fmt.Errorf("key %q: %T cannot be used for signing", id, private)
+		}
+		key.private = private
+		key.public = signer.Public()
+	case "RSA PRIVATE KEY":
+		private, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return SigningKey{}, fmt.Errorf("key %q: %w", id, err)
+		}
+		key.private = private
+		key.public = private.Public()
+	case "PUBLIC KEY":
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return SigningKey{}, fmt.Errorf("key %q: %w", id, err)
+		}
+		key.public = public
+	default:
+		return SigningKey{}, fmt.Errorf("key %q: unsupported PEM block %q", id, block.Type)
+	}
+
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		if algorithm != AlgRS256 {
+			return SigningKey{}, fmt.Errorf("key %q: RSA key requires alg %s", id, AlgRS256)
+		}
+		if public.N.BitLen() < 2048 {
+			return SigningKey{}, fmt.Errorf("key %q: RSA key must be at least 2048 bits", id)
+		}
+	case ed25519.PublicKey:
+		if algorithm != AlgEdDSA {
+			return SigningKey{}, fmt.Errorf("key %q: Ed25519 key requires alg %s", id, AlgEdDSA)
+		}
+	default:
+		return SigningKey{}, fmt.Errorf("key %q: unsupported key type %T", id, key.public)
+	}
+
+	return key, nil
+}
+
+func (k SigningKey) method() jwt.SigningMethod {
+	if k.Algorithm == AlgEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+// KeyRing хранит активный ключ подписи и предыдущие ключи, по которым еще проверяются токены
+type KeyRing struct {
+	active SigningKey
+	keys   map[string]SigningKey
+	order  []string
+}
+
+func NewKeyRing(activeID string, keys []SigningKey) (*KeyRing, error) {
+	ring := &KeyRing{keys: make(map[string]SigningKey, len(keys))}
+
+	for _, key := range keys {
+		if _, exists := ring.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		ring.keys[key.ID] = key
+		ring.order = append(ring.order, key.ID)
+	}
+
+	active, ok := ring.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q is not configured", activeID)
+	}
+	if active.private == nil {
+		return nil, fmt.Errorf("active key %q has no private key", activeID)
+	}
+	ring.active = active
+
+	return ring, nil
+}
+
+func (r *KeyRing) sign(claims jwt.Claims, typ string) (string, error) {
+	token := jwt.NewWithClaims(r.active.method(), claims)
+	token.Header["kid"] = r.active.ID
+	token.Header["typ"] = typ
+
+	return token.SignedString(r.active.private)
+}
+
+// keyFunc выбирает ключ проверки по kid; алгоритм обязан совпадать с алгоритмом ключа
+func (r *KeyRing) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := r.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("alg %s does not match key %q", token.Method.Alg(), kid)
+	}
+	return key.public, nil
+}
+
+// JWK - открытый ключ в формате RFC 7517
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS возвращает открытые ключи связки для публикации
+func (r *KeyRing) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(r.order))}
+
+	for _, id := range r.order {
+		key := r.keys[id]
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Algorithm}
+
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
@@ -0,0 +1,80 @@
+package tokens
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+func encodePEM(t *testing.T, blockType string, der []byte, err error) []byte {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("marshal %s error = %v", blockType, err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+}
+
+func TestParseKey(t *testing.T) {
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey() error = %v", err)
+	}
+	rsaPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	weakRSA, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	x25519, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdh.GenerateKey() error = %v", err)
+	}
+
+	pkcs8 := func(key any) []byte {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		return encodePEM(t, "PRIVATE KEY", der, err)
+	}
+	pkix := func(key any) []byte {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		return encodePEM(t, "PUBLIC KEY", der, err)
+	}
+
+	tests := []struct {
+		name        string
+		id          string
+		algorithm   string
+		data        []byte
+		wantErr     bool
+		wantPrivate bool
+	}{
+		{"ed25519 pkcs8", "k", AlgEdDSA, pkcs8(edPrivate), false, true},
+		{"ed25519 public", "k", AlgEdDSA, pkix(edPublic), false, false},
+		{"rsa pkcs8", "k", AlgRS256, pkcs8(rsaPrivate), false, true},
+		{"rsa pkcs1", "k", AlgRS256, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaPrivate)}), false, true},
+		{"x25519 cannot sign", "k", AlgEdDSA, pkcs8(x25519), true, false},
+		{"weak rsa", "k", AlgRS256, pkcs8(weakRSA), true, false},
+		{"algorithm mismatch", "k", AlgRS256, pkcs8(edPrivate), true, false},
+		{"unknown algorithm", "k", "HS256", pkcs8(edPrivate), true, false},
+		{"missing id", "", AlgEdDSA, pkcs8(edPrivate), true, false},
+		{"not pem", "k", AlgEdDSA, []byte("not a key"), true, false},
+		{"unsupported block", "k", AlgEdDSA, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1}}), true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParseKey(tt.id, tt.algorithm, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (key.private != nil) != tt.wantPrivate {
+				t.Fatalf("private = %v, want %v", key.private != nil, tt.wantPrivate)
+			}
+		})
+	}
+}
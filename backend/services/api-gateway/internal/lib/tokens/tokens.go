@@ -0,0 +1,184 @@
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/randtoken"
+	"github.com/golang-jwt/jwt/v5"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Тип токена передается в заголовке typ, чтобы refresh токен нельзя было предъявить как access
+const (
+	TypeAccess  = "at+jwt"
+	TypeRefresh = "rt+jwt"
+	// Промежуточный токен входа: пароль проверен, ожидается второй фактор
+	TypeChallenge = "mfa+jwt"
+)
+
+type Options struct {
+	Keys         *KeyRing
+	Issuer       string
+	Audience     string
+	AccessTTL    time.Duration
+	RefreshTTL   time.Duration
+	ChallengeTTL time.Duration
+	// Допустимое расхождение часов при проверке exp, nbf и iat
+	ClockSkew time.Duration
+}
+
+// Issuer выпускает и проверяет access и refresh токены.
+// Срок жизни токена, указанный в ответе клиенту, берется из выпущенного Token.
+type Issuer struct {
+	opts Options
+	now  func() time.Time
+}
+
+// Token - подписанный токен и его заявленные сроки
+type Token struct {
+	Value     string
+	JTI       string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// Claims - проверенное содержимое токена
+type Claims struct {
+	Identity  domain.Identity
+	JTI       string
+	FamilyID  string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	Role     string `json:"role"`
+	FamilyID string `json:"fam,omitempty"`
+}
+
+func NewIssuer(opts Options) (*Issuer, error) {
+	if opts.Keys == nil {
+		return nil, errors.New("signing key ring must be set")
+	}
+	if opts.AccessTTL <= 0 || opts.RefreshTTL <= 0 || opts.ChallengeTTL <= 0 {
+		return nil, errors.New("token lifetimes must be positive")
+	}
+	if opts.ClockSkew < 0 {
+		return nil, errors.New("clock skew must not be negative")
+	}
+
+	return &Issuer{opts: opts, now: time.Now}, nil
+}
+
+func (i *Issuer) IssueAccess(identity domain.Identity) (Token, error) {
+	return i.issue(identity, "", i.opts.AccessTTL, TypeAccess)
+}
+
+// IssueRefresh выпускает refresh токен цепочки familyID
+func (i *Issuer) IssueRefresh(identity domain.Identity, familyID string) (Token, error) {
+	if familyID == "" {
+		return Token{}, errors.New("refresh token requires a family id")
+	}
+	return i.issue(identity, familyID, i.opts.RefreshTTL, TypeRefresh)
+}
+
+// IssueChallenge выпускает короткоживущий токен для шага двухфакторной аутентификации
+func (i *Issuer) IssueChallenge(identity domain.Identity) (Token, error) {
+	return i.issue(identity, "", i.opts.ChallengeTTL, TypeChallenge)
+}
+
+func (i *Issuer) VerifyChallenge(tokenString string) (Claims, error) {
+	return i.verify(tokenString, TypeChallenge)
+}
+
+func (i *Issuer) VerifyAccess(tokenString string) (Claims, error) {
+	return i.verify(tokenString, TypeAccess)
+}
+
+func (i *Issuer) VerifyRefresh(tokenString string) (Claims, error) {
+	c, err := i.verify(tokenString, TypeRefresh)
+	if err != nil {
+		return Claims{}, err
+	}
+	if c.FamilyID == "" {
+		return Claims{}, fmt.Errorf("%w: missing fam claim", ErrInvalidToken)
+	}
+	return c, nil
+}
+
+func (i *Issuer) issue(identity domain.Identity, familyID string, ttl time.Duration, typ string) (Token, error) {
+	jti, err := randtoken.New()
+	if err != nil {
+		return Token{}, err
+	}
+
+	// Время в токене хранится с точностью до секунды, усекаем заранее,
+	// чтобы сроки в ответе совпадали с exp
+	now := i.now().Truncate(time.Second)
+	expiresAt := now.Add(ttl)
+
+	tokenString, err := i.opts.Keys.sign(claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    i.opts.Issuer,
+			Subject:   identity.Sub(),
+			Audience:  jwt.ClaimStrings{i.opts.Audience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        jti,
+		},
+		Role:     identity.Role,
+		FamilyID: familyID,
+	}, typ)
+	if err != nil {
+		return Token{}, err
+	}
+
+	return Token{Value: tokenString, JTI: jti, IssuedAt: now, ExpiresAt: expiresAt}, nil
+}
+
+func (i *Issuer) verify(tokenString string, typ string) (Claims, error) {
+	var c claims
+	_, err := jwt.ParseWithClaims(tokenString, &c, func(token *jwt.Token) (interface{}, error) {
+		if token.Header["typ"] != typ {
+			return nil, fmt.Errorf("unexpected token type %v", token.Header["typ"])
+		}
+		return i.opts.Keys.keyFunc(token)
+	},
+		jwt.WithValidMethods([]string{AlgRS256, AlgEdDSA}),
+		jwt.WithIssuer(i.opts.Issuer),
+		jwt.WithAudience(i.opts.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(i.opts.ClockSkew),
+		jwt.WithTimeFunc(i.now),
+	)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	identity, err := domain.ParseSub(c.Subject)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: invalid sub: %v", ErrInvalidToken, err)
+	}
+
+	// Роль дублируется в отдельном claim и обязана совпадать с типом subject
+	if c.Role != identity.Role {
+		return Claims{}, fmt.Errorf("%w: role claim does not match subject", ErrInvalidToken)
+	}
+	if c.ID == "" || c.IssuedAt == nil || c.NotBefore == nil {
+		return Claims{}, fmt.Errorf("%w: missing jti, iat or nbf", ErrInvalidToken)
+	}
+
+	return Claims{
+		Identity:  identity,
+		JTI:       c.ID,
+		FamilyID:  c.FamilyID,
+		IssuedAt:  c.IssuedAt.Time,
+		ExpiresAt: c.ExpiresAt.Time,
+	}, nil
+}
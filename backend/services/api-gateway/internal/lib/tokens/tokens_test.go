@@ -0,0 +1,350 @@
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/golang-jwt/jwt/v5"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://myhelp.test"
+	testAudience = "myhelp-api"
+	testSkew     = 30 * time.Second
+	testAccess   = 15 * time.Minute
+)
+
+var testNow = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+type testKeys struct {
+	active  SigningKey
+	retired SigningKey
+	rsa     SigningKey
+	foreign SigningKey
+}
+
+func newEd25519Key(t *testing.T, id string) SigningKey {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	return SigningKey{ID: id, Algorithm: AlgEdDSA, private: private, public: public}
+}
+
+func newTestIssuer(t *testing.T) (*Issuer, testKeys) {
+	t.Helper()
+
+	rsaPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	keys := testKeys{
+		active:  newEd25519Key(t, "active"),
+		retired: newEd25519Key(t, "retired"),
+		rsa:     SigningKey{ID: "rsa", Algorithm: AlgRS256, private: rsaPrivate, public: rsaPrivate.Public()},
+		// Ключ не из связки: подписанные им токены не должны проходить проверку
+		foreign: newEd25519Key(t, "active"),
+	}
+
+	ring, err := NewKeyRing("active", []SigningKey{keys.active, keys.retired, keys.rsa})
+	if err != nil {
+		t.Fatalf("NewKeyRing() error = %v", err)
+	}
+
+	issuer, err := NewIssuer(Options{
+		Keys:         ring,
+		Issuer:       testIssuer,
+		Audience:     testAudience,
+		AccessTTL:    testAccess,
+		RefreshTTL:   24 * time.Hour,
+		ChallengeTTL: 5 * time.Minute,
+		ClockSkew:    testSkew,
+	})
+	if err != nil {
+		t.Fatalf("NewIssuer() error = %v", err)
+	}
+	issuer.now = func() time.Time { return testNow }
+
+	return issuer, keys
+}
+
+// validClaims - содержимое корректного access токена пациента 5, выпущенного в testNow
+func validClaims() claims {
+	return claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    testIssuer,
+			Subject:   "patient:5",
+			Audience:  jwt.ClaimStrings{testAudience},
+			ExpiresAt: jwt.NewNumericDate(testNow.Add(testAccess)),
+			NotBefore: jwt.NewNumericDate(testNow),
+			IssuedAt:  jwt.NewNumericDate(testNow),
+			ID:        "jti-1",
+		},
+		Role: domain.RolePatient,
+	}
+}
+
+func sign(t *testing.T, key SigningKey, kid, typ string, c claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(key.method(), c)
+	token.Header["kid"] = kid
+	token.Header["typ"] = typ
+	value, err := token.SignedString(key.private)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	return value
+}
+
+func TestIssueVerifyRoundTrip(t *testing.T) {
+	issuer, _ := newTestIssuer(t)
+
+	for _, identity := range []domain.Identity{
+		{Subject: 5, Role: domain.RolePatient},
+		{Subject: 1, Role: domain.RoleAdmin},
+		{Subject: 7, Role: domain.RoleDoctor},
+	} {
+		t.Run(identity.Role, func(t *testing.T) {
+			testIssueVerifyRoundTrip(t, issuer, identity)
+		})
+	}
+}
+
+func testIssueVerifyRoundTrip(t *testing.T, issuer *Issuer, identity domain.Identity) {
+	tests := []struct {
+		name   string
+		issue  func() (Token, error)
+		verify func(string) (Claims, error)
+		family string
+		ttl    time.Duration
+	}{
+		{"access", func() (Token, error) { return issuer.IssueAccess(identity) }, issuer.VerifyAccess, "", testAccess},
+		{"refresh", func() (Token, error) { return issuer.IssueRefresh(identity, "family-1") }, issuer.VerifyRefresh, "family-1", 24 * time.Hour},
+		{"challenge", func() (Token, error) { return issuer.IssueChallenge(identity) }, issuer.VerifyChallenge, "", 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.issue()
+			if err != nil {
+				t.Fatalf("issue error = %v", err)
+			}
+			if !token.IssuedAt.Equal(testNow) || !token.ExpiresAt.Equal(testNow.Add(tt.ttl)) {
+				t.Fatalf("token times = %v..%v, want %v..%v", token.IssuedAt, token.ExpiresAt, testNow, testNow.Add(tt.ttl))
+			}
+
+			got, err := tt.verify(token.Value)
+			if err != nil {
+				t.Fatalf("verify error = %v", err)
+			}
+			if got.Identity != identity || got.JTI != token.JTI || got.FamilyID != tt.family {
+				t.Fatalf("verify = %+v, want identity %+v, jti %q, family %q", got, identity, token.JTI, tt.family)
+			}
+			if !got.ExpiresAt.Equal(token.ExpiresAt) {
+				t.Fatalf("ExpiresAt = %v, want %v", got.ExpiresAt, token.ExpiresAt)
+			}
+		})
+	}
+}
+
+func TestIssueRefreshRequiresFamily(t *testing.T) {
+	issuer, _ := newTestIssuer(t)
+	if _, err := issuer.IssueRefresh(domain.Identity{Subject: 5, Role: domain.RolePatient}, ""); err == nil {
+		t.Fatal("IssueRefresh() without family error = nil")
+	}
+}
+
+func TestVerifyClock(t *testing.T) {
+	issuer, _ := newTestIssuer(t)
+	token, err := issuer.IssueAccess(domain.Identity{Subject: 5, Role: domain.RolePatient})
+	if err != nil {
+		t.Fatalf("IssueAccess() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		wantErr bool
+	}{
+		{"just issued", testNow, false},
+		{"before expiry", testNow.Add(testAccess - time.Second), false},
+		{"expired within leeway", testNow.Add(testAccess + testSkew - time.Second), false},
+		{"expired beyond leeway", testNow.Add(testAccess + testSkew + time.Second), true},
+		// Часы проверяющего отстают: nbf и iat в будущем
+		{"issued in future within leeway", testNow.Add(-testSkew + time.Second), false},
+		{"issued in future beyond leeway", testNow.Add(-testSkew - time.Second), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer.now = func() time.Time { return tt.now }
+			_, err := issuer.VerifyAccess(token.Value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyAccess() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("VerifyAccess() error = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestVerifyAccessRejects(t *testing.T) {
+	issuer, keys := newTestIssuer(t)
+	identity := domain.Identity{Subject: 5, Role: domain.RolePatient}
+
+	refresh, err := issuer.IssueRefresh(identity, "family-1")
+	if err != nil {
+		t.Fatalf("IssueRefresh() error = %v", err)
+	}
+	challenge, err := issuer.IssueChallenge(identity)
+	if err != nil {
+		t.Fatalf("IssueChallenge() error = %v", err)
+	}
+	access, err := issuer.IssueAccess(identity)
+	if err != nil {
+		t.Fatalf("IssueAccess() error = %v", err)
+	}
+
+	with := func(modify func(*claims)) claims {
+		c := validClaims()
+		modify(&c)
+		return c
+	}
+
+	// Подпись заменяется подписью другого содержимого той же длины
+	parts := strings.Split(access.Value, ".")
+	otherParts := strings.Split(sign(t, keys.active, "active", TypeAccess, with(func(c *claims) { c.ID = "jti-2" })), ".")
+	tamperedSignature := parts[0] + "." + parts[1] + "." + otherParts[2]
+	tamperedPayload := parts[0] + "." + otherParts[1] + "." + parts[2]
+
+	noneToken, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("SignedString(none) error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"refresh token as access", refresh.Value},
+		{"mfa token as access", challenge.Value},
+		{"access typ missing", sign(t, keys.active, "active", "", validClaims())},
+		{"wrong issuer", sign(t, keys.active, "active", TypeAccess, with(func(c *claims) { c.Issuer = "https://evil.test" }))},
+		{"missing issuer", sign(t, keys.active, "active", TypeAccess, with(func(c *claims) { c.Issuer = "" }))},
+		{"wrong audience", sign(t, keys.active, "active", TypeAccess, with(func(c *claims) { c.Audience = jwt.ClaimStrings{"other-api"} }))},
+		{"missing expiry", sign(t, keys.active, "active", TypeAccess, with(func(c *claims) { c.ExpiresAt = nil }))},
+		{"missing jti", sign(t, keys.active, "active", TypeAccess, with(func(c *claims) { c.ID = "" }))},
+		{"missing nbf", sign(t, keys.active, "active", TypeAccess, with(func(c *claims) { c.NotBefore = nil }))},
+		{"unknown kid", sign(t, keys.active, "missing", TypeAccess, validClaims())},
+		{"empty kid", sign(t, keys.active, "", TypeAccess, validClaims())},
+		{"kid of another key", sign(t, keys.active, "retired", TypeAccess, validClaims())},
+		{"kid with different algorithm", sign(t, keys.active, "rsa", TypeAccess, validClaims())},
+		{"key outside ring", sign(t, keys.foreign, "active", TypeAccess, validClaims())},
+		{"tampered signature", tamperedSignature},
+		{"tampered payload", tamperedPayload},
+		{"alg none", noneToken},
+		{"sub without type", sign(t, keys.active, "active", TypeAccess, with(func(c *claims) { c.Subject = "5" }))},
+		{"sub with unknown role", sign(t, keys.active, "active", TypeAccess, with(func(c *claims) { c.Subject = "nurse:5"; c.Role = "nurse" }))},
+		{"sub with zero id", sign(t, keys.active, "active", TypeAccess, with(func(c *claims) { c.Subject = "patient:0" }))},
+		{"sub with non-numeric id", sign(t, keys.active, "active", TypeAccess, with(func(c *claims) { c.Subject = "patient:abc" }))},
+		{"role claim differs from sub", sign(t, keys.active, "active", TypeAccess, with(func(c *claims) { c.Role = domain.RoleAdmin }))},
+		{"role claim missing", sign(t, keys.active, "active", TypeAccess, with(func(c *claims) { c.Role = "" }))},
+		{"garbage", "not.a.token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := issuer.VerifyAccess(tt.token)
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("VerifyAccess() error = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestVerifyAcceptsRetiredAndRSAKeys(t *testing.T) {
+	issuer, keys := newTestIssuer(t)
+
+	// Токены, подписанные предыдущими ключами связки, проверяются до истечения срока
+	for _, key := range []SigningKey{keys.retired, keys.rsa} {
+		t.Run(key.ID, func(t *testing.T) {
+			got, err := issuer.VerifyAccess(sign(t, key, key.ID, TypeAccess, validClaims()))
+			if err != nil {
+				t.Fatalf("VerifyAccess() error = %v", err)
+			}
+			if got.Identity != (domain.Identity{Subject: 5, Role: domain.RolePatient}) {
+				t.Fatalf("Identity = %+v", got.Identity)
+			}
+		})
+	}
+}
+
+func TestVerifyRefreshRejects(t *testing.T) {
+	issuer, keys := newTestIssuer(t)
+	identity := domain.Identity{Subject: 1, Role: domain.RoleAdmin}
+
+	access, err := issuer.IssueAccess(identity)
+	if err != nil {
+		t.Fatalf("IssueAccess() error = %v", err)
+	}
+	challenge, err := issuer.IssueChallenge(identity)
+	if err != nil {
+		t.Fatalf("IssueChallenge() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"access token as refresh", access.Value},
+		{"mfa token as refresh", challenge.Value},
+		{"refresh without family", sign(t, keys.active, "active", TypeRefresh, validClaims())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := issuer.VerifyRefresh(tt.token); !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("VerifyRefresh() error = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestNewIssuerValidatesOptions(t *testing.T) {
+	ring, err := NewKeyRing("active", []SigningKey{newEd25519Key(t, "active")})
+	if err != nil {
+		t.Fatalf("NewKeyRing() error = %v", err)
+	}
+	valid := Options{Keys: ring, AccessTTL: time.Minute, RefreshTTL: time.Hour, ChallengeTTL: time.Minute}
+
+	tests := []struct {
+		name   string
+		modify func(*Options)
+	}{
+		{"no keys", func(o *Options) { o.Keys = nil }},
+		{"zero access ttl", func(o *Options) { o.AccessTTL = 0 }},
+		{"negative refresh ttl", func(o *Options) { o.RefreshTTL = -time.Second }},
+		{"zero challenge ttl", func(o *Options) { o.ChallengeTTL = 0 }},
+		{"negative clock skew", func(o *Options) { o.ClockSkew = -time.Second }},
+	}
+
+	if _, err := NewIssuer(valid); err != nil {
+		t.Fatalf("NewIssuer(valid) error = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := valid
+			tt.modify(&opts)
+			if _, err := NewIssuer(opts); err == nil {
+				t.Fatal("NewIssuer() error = nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Секрет из приложения B RFC 6238 для SHA1: ASCII "12345678901234567890"
+var rfcSecret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+// Векторы RFC 6238 (8 цифр), усеченные до 6 цифр: младшие разряды совпадают
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestGenerateRFC6238(t *testing.T) {
+	key := []byte("12345678901234567890")
+	for _, v := range rfcVectors {
+		if got := generate(key, v.unix/period); got != v.code {
+			t.Errorf("generate(T=%d) = %s, want %s", v.unix, got, v.code)
+		}
+	}
+}
+
+func TestValidateRFC6238(t *testing.T) {
+	for _, v := range rfcVectors {
+		now := time.Unix(v.unix, 0)
+		counter, ok := Validate(rfcSecret, v.code, now)
+		if !ok || counter != v.unix/period {
+			t.Errorf("Validate(T=%d) = %d, %v; want %d, true", v.unix, counter, ok, v.unix/period)
+		}
+	}
+
+	// Секрет принимается и в нижнем регистре
+	if _, ok := Validate(strings.ToLower(rfcSecret), "287082", time.Unix(59, 0)); !ok {
+		t.Error("Validate() with lowercase secret = false")
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	// Код шага T=1111111111 (счетчик 37037037)
+	const code = "050471"
+	base := time.Unix(1111111111, 0)
+
+	tests := []struct {
+		name        string
+		now         time.Time
+		wantOK      bool
+		wantCounter int64
+	}{
+		{"same step", base, true, 37037037},
+		{"start of step", time.Unix(37037037*period, 0), true, 37037037},
+		{"end of step", time.Unix(37037038*period-1, 0), true, 37037037},
+		{"previous step on phone", base.Add(period * time.Second), true, 37037037},
+		{"next step on phone", base.Add(-period * time.Second), true, 37037037},
+		{"two steps late", base.Add(2 * period * time.Second), false, 0},
+		{"two steps early", base.Add(-2 * period * time.Second), false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter, ok := Validate(rfcSecret, code, tt.now)
+			if ok != tt.wantOK || counter != tt.wantCounter {
+				t.Fatalf("Validate() = %d, %v; want %d, %v", counter, ok, tt.wantCounter, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsMalformed(t *testing.T) {
+	now := time.Unix(59, 0)
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{"wrong code", rfcSecret, "287083"},
+		{"eight digits", rfcSecret, "94287082"},
+		{"five digits", rfcSecret, "28708"},
+		{"empty code", rfcSecret, ""},
+		{"invalid secret", "not base32!", "287082"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := Validate(tt.secret, tt.code, now); ok {
+				t.Fatal("Validate() = true")
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error = %v", err)
+	}
+	key, err := encoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Fatalf("secret %q decodes to %d bytes, err = %v; want 20 bytes", secret, len(key), err)
+	}
+
+	// Секрет подходит для проверки собственных кодов
+	now := time.Now()
+	if _, ok := Validate(secret, generate(key, now.Unix()/period), now); !ok {
+		t.Fatal("Validate() of generated code = false")
+	}
+}
@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"context"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+)
+
+func (s *Storage) GetAdminTOTP(ctx context.Context, adminID int) (domain.AdminTOTP, error) {
+	query := `
+	SELECT email, coalesce(totp_secret, ''), totp_enabled
+	FROM admins
+	WHERE id=$1 and is_active=true
+`
+	var totp domain.AdminTOTP
+	err := s.connection.QueryRow(ctx, query, adminID).Scan(&totp.Email, &totp.Secret, &totp.Enabled)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.AdminTOTP{}, repository.ErrorNotFound
+	}
+	if err != nil {
+		s.logger.Error("Failed to query database", "adminID", adminID, "error", err)
+		return domain.AdminTOTP{}, errors.Wrap(err, "failed to query database: attempt to get admin totp")
+	}
+
+	return totp, nil
+}
+
+// SetAdminTOTPSecret сохраняет секрет для подключения 2FA. Подключенную 2FA перезаписать нельзя.
+func (s *Storage) SetAdminTOTPSecret(ctx context.Context, adminID int, secret string) error {
+	query := `
+	UPDATE admins
+	SET totp_secret = $2, totp_last_counter = NULL
+	WHERE id = $1 AND totp_enabled = false
+`
+	tag, err := s.connection.Exec(ctx, query, adminID, secret)
+	if err != nil {
+		s.logger.Error("Failed to set totp secret", "adminID", adminID, "error", err)
+		return errors.Wrap(err, "failed to set totp secret")
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrorAlreadyExists
+	}
+
+	return nil
+}
+
+// EnableAdminTOTP включает 2FA и заменяет резервные коды
+func (s *Storage) EnableAdminTOTP(ctx context.Context, adminID int, counter int64, recoveryCodeHashes []string) error {
+
+	tx, err := s.connection.Begin(ctx)
+	if err != nil {
+		s.logger.Error("Failed to begin transaction", "error", err)
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+	UPDATE admins
+	SET totp_enabled = true, totp_last_counter = $2
+	WHERE id = $1 AND totp_enabled = false AND totp_secret IS NOT NULL
+`, adminID, counter)
+	if err != nil {
+		s.logger.Error("Failed to enable totp", "adminID", adminID, "error", err)
+		return errors.Wrap(err, "failed to enable totp")
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrorAlreadyExists
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM admin_recovery_codes WHERE admin_id = $1`, adminID)
+	if err != nil {
+		s.logger.Error("Failed to delete recovery codes", "adminID", adminID, "error", err)
+		return errors.Wrap(err, "failed to delete recovery codes")
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		_, err = tx.Exec(ctx, `
+	INSERT INTO admin_recovery_codes (admin_id, code_hash) VALUES ($1, $2)
+`, adminID, hash)
+		if err != nil {
+			s.logger.Error("Failed to save recovery code", "adminID", adminID, "error", err)
+			return errors.Wrap(err, "failed to save recovery code")
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		s.logger.Error("Failed to commit transaction", "error", err)
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	return nil
+}
+
+// UseAdminTOTPCounter фиксирует шаг принятого кода. false - код этого или более позднего шага уже использован.
+func (s *Storage) UseAdminTOTPCounter(ctx context.Context, adminID int, counter int64) (bool, error) {
+	query := `
+	UPDATE admins
+	SET totp_last_counter = $2
+	WHERE id = $1 AND (totp_last_counter IS NULL OR totp_last_counter < $2)
+`
+	tag, err := s.connection.Exec(ctx, query, adminID, counter)
+	if err != nil {
+		s.logger.Error("Failed to update totp counter", "adminID", adminID, "error", err)
+		return false, errors.Wrap(err, "failed to update totp counter")
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// UseAdminRecoveryCode гасит резервный код. false - кода нет или он уже использован.
+func (s *Storage) UseAdminRecoveryCode(ctx context.Context, adminID int, codeHash string) (bool, error) {
+	query := `
+	UPDATE admin_recovery_codes
+	SET used_at = now()
+	WHERE admin_id = $1 AND code_hash = $2 AND used_at IS NULL
+`
+	tag, err := s.connection.Exec(ctx, query, adminID, codeHash)
+	if err != nil {
+		s.logger.Error("Failed to use recovery code", "adminID", adminID, "error", err)
+		return false, errors.Wrap(err, "failed to use recovery code")
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
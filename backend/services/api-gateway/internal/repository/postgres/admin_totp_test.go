@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository"
+	"testing"
+	"time"
+)
+
+func createTestAdmin(t *testing.T, storage *Storage) int {
+	t.Helper()
+	ctx := context.Background()
+
+	name := fmt.Sprintf("totp-test-%d", time.Now().UnixNano())
+	var adminID int
+	err := storage.connection.QueryRow(ctx, `
+	INSERT INTO admins (username, email, password, totp_secret)
+	VALUES ($1, $1 || '@example.com', $1, 'GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ')
+	RETURNING id
+`, name).Scan(&adminID)
+	if err != nil {
+		t.Fatalf("insert admin error = %v", err)
+	}
+	t.Cleanup(func() { storage.connection.Exec(ctx, `DELETE FROM admins WHERE id = $1`, adminID) })
+	return adminID
+}
+
+func TestUseAdminTOTPCounterRejectsReplay(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+	adminID := createTestAdmin(t, storage)
+
+	if err := storage.EnableAdminTOTP(ctx, adminID, 100, nil); err != nil {
+		t.Fatalf("EnableAdminTOTP() error = %v", err)
+	}
+
+	tests := []struct {
+		counter int64
+		want    bool
+	}{
+		// Шаг, которым подтверждали подключение, повторно не принимается
+		{100, false},
+		{101, true},
+		{101, false},
+		{99, false},
+		{103, true},
+		{102, false},
+	}
+
+	for _, tt := range tests {
+		got, err := storage.UseAdminTOTPCounter(ctx, adminID, tt.counter)
+		if err != nil {
+			t.Fatalf("UseAdminTOTPCounter(%d) error = %v", tt.counter, err)
+		}
+		if got != tt.want {
+			t.Fatalf("UseAdminTOTPCounter(%d) = %v, want %v", tt.counter, got, tt.want)
+		}
+	}
+}
+
+func TestUseAdminRecoveryCodeOnce(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+	adminID := createTestAdmin(t, storage)
+
+	if err := storage.EnableAdminTOTP(ctx, adminID, 1, []string{"hash-1", "hash-2"}); err != nil {
+		t.Fatalf("EnableAdminTOTP() error = %v", err)
+	}
+
+	tests := []struct {
+		hash string
+		want bool
+	}{
+		{"hash-1", true},
+		{"hash-1", false},
+		{"hash-unknown", false},
+		{"hash-2", true},
+	}
+
+	for _, tt := range tests {
+		got, err := storage.UseAdminRecoveryCode(ctx, adminID, tt.hash)
+		if err != nil {
+			t.Fatalf("UseAdminRecoveryCode(%q) error = %v", tt.hash, err)
+		}
+		if got != tt.want {
+			t.Fatalf("UseAdminRecoveryCode(%q) = %v, want %v", tt.hash, got, tt.want)
+		}
+	}
+}
+
+func TestGetAdminTOTPDeactivated(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+	adminID := createTestAdmin(t, storage)
+
+	if _, err := storage.connection.Exec(ctx, `UPDATE admins SET is_active = false WHERE id = $1`, adminID); err != nil {
+		t.Fatalf("deactivate admin error = %v", err)
+	}
+	if _, err := storage.GetAdminTOTP(ctx, adminID); !errors.Is(err, repository.ErrorNotFound) {
+		t.Fatalf("GetAdminTOTP() error = %v, want ErrorNotFound", err)
+	}
+}
@@ -0,0 +1,229 @@
+package postgres
+
+import (
+	"context"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+	"time"
+)
+
+func (s *Storage) ListAdmins(ctx context.Context) ([]domain.Admin, error) {
+	query := `
+	SELECT id, username, email, admin_role, is_active
+	FROM admins
+	ORDER BY id
+`
+	rows, err := s.connection.Query(ctx, query)
+	if err != nil {
+		s.logger.Error("Failed to query admins", "error", err)
+		return nil, errors.Wrap(err, "failed to query admins")
+	}
+	defer rows.Close()
+
+	admins := make([]domain.Admin, 0)
+	for rows.Next() {
+		var admin domain.Admin
+		if err = rows.Scan(&admin.Id, &admin.Username, &admin.Email, &admin.AdminRole, &admin.IsActive); err != nil {
+			return nil, errors.Wrap(err, "failed to scan row")
+		}
+		admins = append(admins, admin)
+	}
+
+	return admins, rows.Err()
+}
+
+// GetAdminByID возвращает администратора вместе с хешем пароля
+func (s *Storage) GetAdminByID(ctx context.Context, adminID int) (domain.Admin, error) {
+	var admin domain.Admin
+
+	query := `
+	SELECT id, username, email, admin_role, password, is_active
+	FROM admins
+	WHERE id = $1
+`
+	err := s.connection.QueryRow(ctx, query, adminID).
+		Scan(&admin.Id, &admin.Username, &admin.Email, &admin.AdminRole, &admin.Password, &admin.IsActive)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Admin{}, repository.ErrorNotFound
+	}
+	if err != nil {
+		s.logger.Error("Failed to get admin", "adminID", adminID, "error", err)
+		return domain.Admin{}, errors.Wrap(err, "failed to get admin")
+	}
+
+	return admin, nil
+}
+
+// InviteAdmin создает администратора с заведомо неизвестным паролем и токен приглашения,
+// по которому приглашенный задает свой пароль
+func (s *Storage) InviteAdmin(ctx context.Context, admin domain.Admin, tokenHash string, expiresAt time.Time) (domain.Admin, error) {
+
+	tx, err := s.connection.Begin(ctx)
+	if err != nil {
+		s.logger.Error("Failed to begin transaction", "error", err)
+		return domain.Admin{}, errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+	INSERT INTO admins (username, email, password, admin_role, is_active)
+	VALUES ($1, $2, $3, $4, true)
+	RETURNING id, is_active
+`, admin.Username, admin.Email, admin.Password, admin.AdminRole).Scan(&admin.Id, &admin.IsActive)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return domain.Admin{}, repository.ErrorAlreadyExists
+	}
+	if err != nil {
+		s.logger.Error("Failed to create admin", "email", admin.Email, "error", err)
+		return domain.Admin{}, errors.Wrap(err, "failed to create admin")
+	}
+
+	_, err = tx.Exec(ctx, `
+	INSERT INTO admin_invites (admin_id, token_hash, expires_at)
+	VALUES ($1, $2, $3)
+`, admin.Id, tokenHash, expiresAt)
+	if err != nil {
+		s.logger.Error("Failed to save admin invite", "adminID", admin.Id, "error", err)
+		return domain.Admin{}, errors.Wrap(err, "failed to save admin invite")
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		s.logger.Error("Failed to commit transaction", "error", err)
+		return domain.Admin{}, errors.Wrap(err, "failed to commit transaction")
+	}
+
+	admin.Password = ""
+	return admin, nil
+}
+
+// AcceptAdminInvite гасит действующее приглашение и сохраняет пароль администратора.
+// Возвращает id администратора.
+func (s *Storage) AcceptAdminInvite(ctx context.Context, tokenHash string, passwordHash string) (int, error) {
+
+	tx, err := s.connection.Begin(ctx)
+	if err != nil {
+		s.logger.Error("Failed to begin transaction", "error", err)
+		return 0, errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	var adminID int
+	err = tx.QueryRow(ctx, `
+	UPDATE admin_invites
+	SET used_at = now()
+	WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+	RETURNING admin_id
+`, tokenHash).Scan(&adminID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, repository.ErrorInvalidToken
+	}
+	if err != nil {
+		s.logger.Error("Failed to consume admin invite", "error", err)
+		return 0, errors.Wrap(err, "failed to consume admin invite")
+	}
+
+	_, err = tx.Exec(ctx, `
+	UPDATE admins
+	SET password = $1
+	WHERE id = $2
+`, passwordHash, adminID)
+	if err != nil {
+		s.logger.Error("Failed to update password", "adminID", adminID, "error", err)
+		return 0, errors.Wrap(err, "failed to update password")
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		s.logger.Error("Failed to commit transaction", "error", err)
+		return 0, errors.Wrap(err, "failed to commit transaction")
+	}
+
+	return adminID, nil
+}
+
+// UpdateAdmin меняет роль и признак активности администратора. Для деактивированного
+// администратора отзываются все refresh токены.
+func (s *Storage) UpdateAdmin(ctx context.Context, adminID int, adminRole string, isActive bool) (domain.Admin, error) {
+
+	tx, err := s.connection.Begin(ctx)
+	if err != nil {
+		s.logger.Error("Failed to begin transaction", "error", err)
+		return domain.Admin{}, errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	var admin domain.Admin
+	err = tx.QueryRow(ctx, `
+	UPDATE admins
+	SET admin_role = $2, is_active = $3
+	WHERE id = $1
+	RETURNING id, username, email, admin_role, is_active
+`, adminID, adminRole, isActive).Scan(&admin.Id, &admin.Username, &admin.Email, &admin.AdminRole, &admin.IsActive)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Admin{}, repository.ErrorNotFound
+	}
+	if err != nil {
+		s.logger.Error("Failed to update admin", "adminID", adminID, "error", err)
+		return domain.Admin{}, errors.Wrap(err, "failed to update admin")
+	}
+
+	if !isActive {
+		_, err = tx.Exec(ctx, `
+	UPDATE refresh_tokens
+	SET revoked_at = now()
+	WHERE subject_role = $1 AND subject_id = $2 AND revoked_at IS NULL
+`, domain.RoleAdmin, adminID)
+		if err != nil {
+			s.logger.Error("Failed to revoke refresh tokens", "adminID", adminID, "error", err)
+			return domain.Admin{}, errors.Wrap(err, "failed to revoke refresh tokens")
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		s.logger.Error("Failed to commit transaction", "error", err)
+		return domain.Admin{}, errors.Wrap(err, "failed to commit transaction")
+	}
+
+	return admin, nil
+}
+
+// ChangeAdminPassword сохраняет новый хеш пароля и отзывает все refresh токены администратора
+func (s *Storage) ChangeAdminPassword(ctx context.Context, adminID int, passwordHash string) error {
+
+	tx, err := s.connection.Begin(ctx)
+	if err != nil {
+		s.logger.Error("Failed to begin transaction", "error", err)
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+	UPDATE admins
+	SET password = $1
+	WHERE id = $2
+`, passwordHash, adminID)
+	if err != nil {
+		s.logger.Error("Failed to update password", "adminID", adminID, "error", err)
+		return errors.Wrap(err, "failed to update password")
+	}
+
+	_, err = tx.Exec(ctx, `
+	UPDATE refresh_tokens
+	SET revoked_at = now()
+	WHERE subject_role = $1 AND subject_id = $2 AND revoked_at IS NULL
+`, domain.RoleAdmin, adminID)
+	if err != nil {
+		s.logger.Error("Failed to revoke refresh tokens", "adminID", adminID, "error", err)
+		return errors.Wrap(err, "failed to revoke refresh tokens")
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		s.logger.Error("Failed to commit transaction", "error", err)
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	return nil
+}
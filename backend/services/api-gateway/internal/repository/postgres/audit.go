@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/audit"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+	"time"
+)
+
+func (s *Storage) SaveAuditEvent(ctx context.Context, event audit.Event) error {
+	var changes []byte
+	if len(event.Changes) > 0 {
+		var err error
+		if changes, err = json.Marshal(event.Changes); err != nil {
+			return errors.Wrap(err, "failed to marshal audit changes")
+		}
+	}
+
+	var actorID *int
+	if event.ActorID != 0 {
+		actorID = &event.ActorID
+	}
+
+	query := `
+	INSERT INTO audit_events (occurred_at, service, actor_role, actor_id, action, target_type, target_id, changes, request_id, ip)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, nullif($9, ''), nullif($10, ''))
+`
+	_, err := s.connection.Exec(ctx, query,
+		event.OccurredAt,
+		event.Service,
+		event.ActorRole,
+		actorID,
+		event.Action,
+		event.TargetType,
+		event.TargetID,
+		changes,
+		event.RequestID,
+		event.IP,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to save audit event")
+	}
+
+	return nil
+}
+
+// Условие выборки журнала: пустые параметры не фильтруют
+const auditFilterCondition = `
+	WHERE ($1 = '' OR actor_role = $1)
+	  AND ($2 = 0 OR actor_id = $2)
+	  AND ($3 = '' OR action = $3)
+	  AND ($4 = '' OR target_type = $4)
+	  AND ($5 = '' OR target_id = $5)
+	  AND ($6::timestamptz IS NULL OR occurred_at >= $6)
+	  AND ($7::timestamptz IS NULL OR occurred_at < $7)
+`
+
+const auditColumns = `id, occurred_at, service, actor_role, actor_id, action, target_type, target_id, changes,
+	       coalesce(request_id, ''), coalesce(ip, '')`
+
+// ListAuditEvents возвращает страницу событий, новые первыми, и общее число подходящих событий
+func (s *Storage) ListAuditEvents(ctx context.Context, filter audit.Filter) ([]audit.Event, int, error) {
+	var total int
+	err := s.connection.QueryRow(ctx,
+		`SELECT count(*) FROM audit_events`+auditFilterCondition,
+		auditFilterArgs(filter)...,
+	).Scan(&total)
+	if err != nil {
+		s.logger.Error("Failed to count audit events", "error", err)
+		return nil, 0, errors.Wrap(err, "failed to count audit events")
+	}
+
+	events := make([]audit.Event, 0)
+	err = s.queryAuditEvents(ctx, `
+	SELECT `+auditColumns+`
+	FROM audit_events`+auditFilterCondition+`
+	ORDER BY occurred_at DESC, id DESC
+	LIMIT $8 OFFSET $9
+`, append(auditFilterArgs(filter), filter.Limit, filter.Offset), func(event audit.Event) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+// ExportAuditEvents передает в fn все подходящие события по порядку, не загружая их в память целиком
+func (s *Storage) ExportAuditEvents(ctx context.Context, filter audit.Filter, fn func(audit.Event) error) error {
+	return s.queryAuditEvents(ctx, `
+	SELECT `+auditColumns+`
+	FROM audit_events`+auditFilterCondition+`
+	ORDER BY occurred_at, id
+`, auditFilterArgs(filter), fn)
+}
+
+// PurgeAuditEvents удаляет события старше before. Триггер таблицы разрешает удаление
+// только при включенном audit.purge в текущей транзакции.
+func (s *Storage) PurgeAuditEvents(ctx context.Context, before time.Time) (int64, error) {
+
+	tx, err := s.connection.Begin(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, `SELECT set_config('audit.purge', 'on', true)`); err != nil {
+		return 0, errors.Wrap(err, "failed to enable audit purge")
+	}
+
+	tag, err := tx.Exec(ctx, `DELETE FROM audit_events WHERE occurred_at < $1`, before)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to purge audit events")
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, errors.Wrap(err, "failed to commit transaction")
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (s *Storage) queryAuditEvents(ctx context.Context, query string, args []interface{}, fn func(audit.Event) error) error {
+	rows, err := s.connection.Query(ctx, query, args...)
+	if err != nil {
+		s.logger.Error("Failed to query audit events", "error", err)
+		return errors.Wrap(err, "failed to query audit events")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return err
+		}
+		if err = fn(event); err != nil {
+			return err
+		}
+	}
+
+	return errors.Wrap(rows.Err(), "failed to query audit events")
+}
+
+func scanAuditEvent(rows pgx.Rows) (audit.Event, error) {
+	var (
+		event   audit.Event
+		actorID *int
+		changes []byte
+	)
+	err := rows.Scan(
+		&event.ID,
+		&event.OccurredAt,
+		&event.Service,
+		&event.ActorRole,
+		&actorID,
+		&event.Action,
+		&event.TargetType,
+		&event.TargetID,
+		&changes,
+		&event.RequestID,
+		&event.IP,
+	)
+	if err != nil {
+		return audit.Event{}, errors.Wrap(err, "failed to scan row")
+	}
+	if actorID != nil {
+		event.ActorID = *actorID
+	}
+	if changes != nil {
+		if err = json.Unmarshal(changes, &event.Changes); err != nil {
+			return audit.Event{}, errors.Wrap(err, "failed to unmarshal audit changes")
+		}
+	}
+	return event, nil
+}
+
+func auditFilterArgs(filter audit.Filter) []interface{} {
+	return []interface{}{
+		filter.ActorRole,
+		filter.ActorID,
+		filter.Action,
+		filter.TargetType,
+		filter.TargetID,
+		nullableTime(filter.From),
+		nullableTime(filter.To),
+	}
+}
+
+func nullableTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
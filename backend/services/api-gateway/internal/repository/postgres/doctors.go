@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+)
+
+// GetDoctorPassword возвращает id врача и хеш пароля по email. Врач без учетных данных не найден.
+func (s *Storage) GetDoctorPassword(ctx context.Context, email string) (int, string, error) {
+	query := `
+	SELECT id, password FROM doctors WHERE email = $1 AND password IS NOT NULL
+`
+	var (
+		doctorID     int
+		passwordHash string
+	)
+	err := s.connection.QueryRow(ctx, query, email).Scan(&doctorID, &passwordHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, "", repository.ErrorNotFound
+	}
+	if err != nil {
+		s.logger.Error("Failed to query database", "error", err)
+		return 0, "", errors.Wrap(err, "failed to query database: attempt to get doctor password")
+	}
+
+	return doctorID, passwordHash, nil
+}
+
+func (s *Storage) UpdateDoctorPasswordHash(ctx context.Context, doctorID int, passwordHash string) error {
+	query := `
+	UPDATE doctors
+	SET password = $1
+	WHERE id = $2
+`
+	_, err := s.connection.Exec(ctx, query, passwordHash, doctorID)
+	if err != nil {
+		s.logger.Error("Failed to update password hash", "doctorID", doctorID, "error", err)
+		return errors.Wrap(err, "failed to update doctor password hash")
+	}
+
+	return nil
+}
+
+func (s *Storage) GetDoctorByID(ctx context.Context, doctorID int) (domain.Doctor, error) {
+	query := `
+	SELECT id, surname, name, patronymic, specialization_id, coalesce(email, '')
+	FROM doctors
+	WHERE id = $1
+`
+	var doctor domain.Doctor
+	err := s.connection.QueryRow(ctx, query, doctorID).Scan(
+		&doctor.Id,
+		&doctor.Surname,
+		&doctor.Name,
+		&doctor.Patronymic,
+		&doctor.SpecializationID,
+		&doctor.Email,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Doctor{}, repository.ErrorNotFound
+	}
+	if err != nil {
+		s.logger.Error("Failed to query database", "doctorID", doctorID, "error", err)
+		return domain.Doctor{}, errors.Wrap(err, "failed to query database: attempt to get doctor")
+	}
+
+	return doctor, nil
+}
+
+// SetDoctorCredentials задает email и хеш пароля врача и отзывает его refresh токены
+func (s *Storage) SetDoctorCredentials(ctx context.Context, doctorID int, email, passwordHash string) error {
+
+	tx, err := s.connection.Begin(ctx)
+	if err != nil {
+		s.logger.Error("Failed to begin transaction", "error", err)
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+	UPDATE doctors
+	SET email = $1, password = $2
+	WHERE id = $3
+`, email, passwordHash, doctorID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return repository.ErrorAlreadyExists
+	}
+	if err != nil {
+		s.logger.Error("Failed to update doctor credentials", "doctorID", doctorID, "error", err)
+		return errors.Wrap(err, "failed to update doctor credentials")
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrorNotFound
+	}
+
+	_, err = tx.Exec(ctx, `
+	UPDATE refresh_tokens
+	SET revoked_at = now()
+	WHERE subject_role = $1 AND subject_id = $2 AND revoked_at IS NULL
+`, domain.RoleDoctor, doctorID)
+	if err != nil {
+		s.logger.Error("Failed to revoke refresh tokens", "doctorID", doctorID, "error", err)
+		return errors.Wrap(err, "failed to revoke refresh tokens")
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		s.logger.Error("Failed to commit transaction", "error", err)
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	return nil
+}
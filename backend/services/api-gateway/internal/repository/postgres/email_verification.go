@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"context"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+	"time"
+)
+
+func (s *Storage) GetPatientStatus(ctx context.Context, patientID int) (string, error) {
+	query := `
+	SELECT status FROM patients WHERE id=$1 and is_deleted=false
+`
+	var status string
+	err := s.connection.QueryRow(ctx, query, patientID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", repository.ErrorNotFound
+	}
+	if err != nil {
+		s.logger.Error("Failed to query database", "patientID", patientID, "error", err)
+		return "", errors.Wrap(err, "failed to query database: attempt to get patient status")
+	}
+
+	return status, nil
+}
+
+// GetPendingPatient возвращает id неподтвержденного пациента и время отправки последнего письма
+func (s *Storage) GetPendingPatient(ctx context.Context, email string) (int, time.Time, error) {
+	query := `
+	SELECT p.id, coalesce(max(t.created_at), 'epoch'::timestamptz)
+	FROM patients p
+	LEFT JOIN email_verification_tokens t ON t.patient_id = p.id
+	WHERE p.email=$1 AND p.is_deleted=false AND p.status=$2
+	GROUP BY p.id
+`
+	var patientID int
+	var lastSentAt time.Time
+	err := s.connection.QueryRow(ctx, query, email, domain.PatientStatusPending).Scan(&patientID, &lastSentAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, time.Time{}, repository.ErrorNotFound
+	}
+	if err != nil {
+		s.logger.Error("Failed to query database", "error", err)
+		return 0, time.Time{}, errors.Wrap(err, "failed to query database: attempt to get pending patient")
+	}
+
+	return patientID, lastSentAt, nil
+}
+
+// CreateEmailVerificationToken сохраняет хеш нового токена и гасит ранее выданные токены пациента
+func (s *Storage) CreateEmailVerificationToken(ctx context.Context, patientID int, tokenHash string, expiresAt time.Time) error {
+
+	tx, err := s.connection.Begin(ctx)
+	if err != nil {
+		s.logger.Error("Failed to begin transaction", "error", err)
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+	UPDATE email_verification_tokens
+	SET used_at = now()
+	WHERE patient_id = $1 AND used_at IS NULL
+`, patientID)
+	if err != nil {
+		s.logger.Error("Failed to invalidate verification tokens", "patientID", patientID, "error", err)
+		return errors.Wrap(err, "failed to invalidate verification tokens")
+	}
+
+	_, err = tx.Exec(ctx, `
+	INSERT INTO email_verification_tokens (patient_id, token_hash, expires_at)
+	VALUES ($1, $2, $3)
+`, patientID, tokenHash, expiresAt)
+	if err != nil {
+		s.logger.Error("Failed to create verification token", "patientID", patientID, "error", err)
+		return errors.Wrap(err, "failed to create verification token")
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		s.logger.Error("Failed to commit transaction", "error", err)
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	return nil
+}
+
+// VerifyEmail гасит токен подтверждения и переводит пациента в active. Возвращает id пациента.
+func (s *Storage) VerifyEmail(ctx context.Context, tokenHash string) (int, error) {
+
+	tx, err := s.connection.Begin(ctx)
+	if err != nil {
+		s.logger.Error("Failed to begin transaction", "error", err)
+		return 0, errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	var patientID int
+	err = tx.QueryRow(ctx, `
+	UPDATE email_verification_tokens
+	SET used_at = now()
+	WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+	RETURNING patient_id
+`, tokenHash).Scan(&patientID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, repository.ErrorInvalidToken
+	}
+	if err != nil {
+		s.logger.Error("Failed to consume verification token", "error", err)
+		return 0, errors.Wrap(err, "failed to consume verification token")
+	}
+
+	_, err = tx.Exec(ctx, `
+	UPDATE patients
+	SET status = $1, email_verified_at = now()
+	WHERE id = $2 AND status = $3
+`, domain.PatientStatusActive, patientID, domain.PatientStatusPending)
+	if err != nil {
+		s.logger.Error("Failed to activate patient", "patientID", patientID, "error", err)
+		return 0, errors.Wrap(err, "failed to activate patient")
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		s.logger.Error("Failed to commit transaction", "error", err)
+		return 0, errors.Wrap(err, "failed to commit transaction")
+	}
+
+	return patientID, nil
+}
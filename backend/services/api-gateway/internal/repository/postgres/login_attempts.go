@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// UpdateLoginAttempts изменяет счетчик под блокировкой строки: параллельные попытки входа
+// по одному ключу выполняются по очереди и не обходят лимит
+func (s *Storage) UpdateLoginAttempts(ctx context.Context, key string, update func(*domain.LoginAttempts)) error {
+	tx, err := s.connection.Begin(ctx)
+	if err != nil {
+		s.logger.Error("Failed to begin transaction", "error", err)
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	// Строка создается заранее, иначе блокировать при первой попытке нечего
+	_, err = tx.Exec(ctx, `
+	INSERT INTO login_attempts (key) VALUES ($1) ON CONFLICT (key) DO NOTHING
+`, key)
+	if err != nil {
+		s.logger.Error("Failed to create login attempts", "error", err)
+		return errors.Wrap(err, "failed to create login attempts")
+	}
+
+	var (
+		attempts    domain.LoginAttempts
+		lockedUntil *time.Time
+	)
+	err = tx.QueryRow(ctx, `
+	SELECT failures, last_failure_at, locked_until
+	FROM login_attempts
+	WHERE key = $1
+	FOR UPDATE
+`, key).Scan(&attempts.Failures, &attempts.LastFailureAt, &lockedUntil)
+	if err != nil {
+		s.logger.Error("Failed to query login attempts", "error", err)
+		return errors.Wrap(err, "failed to query login attempts")
+	}
+	if lockedUntil != nil {
+		attempts.LockedUntil = *lockedUntil
+	}
+
+	update(&attempts)
+
+	lockedUntil = nil
+	if !attempts.LockedUntil.IsZero() {
+		lockedUntil = &attempts.LockedUntil
+	}
+	_, err = tx.Exec(ctx, `
+	UPDATE login_attempts
+	SET failures = $2, last_failure_at = $3, locked_until = $4
+	WHERE key = $1
+`, key, attempts.Failures, attempts.LastFailureAt, lockedUntil)
+	if err != nil {
+		s.logger.Error("Failed to update login attempts", "error", err)
+		return errors.Wrap(err, "failed to update login attempts")
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		s.logger.Error("Failed to commit transaction", "error", err)
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	return nil
+}
+
+func (s *Storage) ResetLoginAttempts(ctx context.Context, key string) error {
+	query := `
+	DELETE FROM login_attempts WHERE key = $1
+`
+	_, err := s.connection.Exec(ctx, query, key)
+	if err != nil {
+		s.logger.Error("Failed to reset login attempts", "error", err)
+		return errors.Wrap(err, "failed to reset login attempts")
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/lockout"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestStorage подключается к БД из TEST_DATABASE_URL со схемой, накатанной всеми миграциями
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	storage, err := New(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)), url)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { storage.Close() })
+	return storage
+}
+
+func TestLoginAttemptsConcurrentAcquire(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	key := fmt.Sprintf("ip:test-%d", time.Now().UnixNano())
+	t.Cleanup(func() { storage.ResetLoginAttempts(ctx, key) })
+
+	policy := lockout.Policy{FreeAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour}
+	guard := lockout.New(storage, policy)
+
+	const requests = 20
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			remaining, err := guard.Acquire(ctx, key)
+			if err != nil {
+				t.Errorf("Acquire() error = %v", err)
+				return
+			}
+			if remaining == 0 {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if want := policy.FreeAttempts + 1; allowed != want {
+		t.Fatalf("allowed = %d of %d parallel attempts, want %d", allowed, requests, want)
+	}
+
+	var failures int
+	var locked bool
+	err := storage.connection.QueryRow(ctx, `
+	SELECT failures, locked_until > now() FROM login_attempts WHERE key = $1
+`, key).Scan(&failures, &locked)
+	if err != nil {
+		t.Fatalf("query login_attempts error = %v", err)
+	}
+	if failures != policy.FreeAttempts+1 || !locked {
+		t.Fatalf("failures = %d, locked = %v, want %d and locked", failures, locked, policy.FreeAttempts+1)
+	}
+}
+
+func TestLoginAttemptsReleaseClearsLock(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	key := fmt.Sprintf("ip:test-%d", time.Now().UnixNano())
+	t.Cleanup(func() { storage.ResetLoginAttempts(ctx, key) })
+
+	guard := lockout.New(storage, lockout.Policy{FreeAttempts: 1, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour})
+	for i := 0; i < 2; i++ {
+		if remaining, err := guard.Acquire(ctx, key); err != nil || remaining != 0 {
+			t.Fatalf("Acquire() = %v, %v; want allowed", remaining, err)
+		}
+	}
+	if err := guard.Release(ctx, key); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if remaining, err := guard.Acquire(ctx, key); err != nil || remaining != 0 {
+		t.Fatalf("Acquire() after release = %v, %v; want allowed", remaining, err)
+	}
+}
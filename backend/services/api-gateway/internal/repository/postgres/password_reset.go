@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+	"time"
+)
+
+func (s *Storage) GetPatientIDByEmail(ctx context.Context, email string) (int, error) {
+	query := `
+	SELECT id FROM patients WHERE email=$1 and is_deleted=false
+`
+	var patientID int
+	err := s.connection.QueryRow(ctx, query, email).Scan(&patientID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, repository.ErrorNotFound
+	}
+	if err != nil {
+		s.logger.Error("Failed to query database", "email", email, "error", err)
+		return 0, errors.Wrap(err, "failed to query database: attempt to get patient id")
+	}
+
+	return patientID, nil
+}
+
+// CreatePasswordResetToken сохраняет хеш нового токена и гасит ранее выданные токены пациента
+func (s *Storage) CreatePasswordResetToken(ctx context.Context, patientID int, tokenHash string, expiresAt time.Time) error {
+
+	tx, err := s.connection.Begin(ctx)
+	if err != nil {
+		s.logger.Error("Failed to begin transaction", "error", err)
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+	UPDATE password_reset_tokens
+	SET used_at = now()
+	WHERE patient_id = $1 AND used_at IS NULL
+`, patientID)
+	if err != nil {
+		s.logger.Error("Failed to invalidate reset tokens", "patientID", patientID, "error", err)
+		return errors.Wrap(err, "failed to invalidate reset tokens")
+	}
+
+	_, err = tx.Exec(ctx, `
+	INSERT INTO password_reset_tokens (patient_id, token_hash, expires_at)
+	VALUES ($1, $2, $3)
+`, patientID, tokenHash, expiresAt)
+	if err != nil {
+		s.logger.Error("Failed to create reset token", "patientID", patientID, "error", err)
+		return errors.Wrap(err, "failed to create reset token")
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		s.logger.Error("Failed to commit transaction", "error", err)
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	return nil
+}
+
+// ResetPassword гасит действующий токен сброса, сохраняет новый хеш пароля
+// и отзывает все выданные пациенту refresh токены. Возвращает id пациента.
+func (s *Storage) ResetPassword(ctx context.Context, tokenHash string, passwordHash string) (int, error) {
+
+	tx, err := s.connection.Begin(ctx)
+	if err != nil {
+		s.logger.Error("Failed to begin transaction", "error", err)
+		return 0, errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	var patientID int
+	err = tx.QueryRow(ctx, `
+	UPDATE password_reset_tokens
+	SET used_at = now()
+	WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+	RETURNING patient_id
+`, tokenHash).Scan(&patientID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, repository.ErrorInvalidToken
+	}
+	if err != nil {
+		s.logger.Error("Failed to consume reset token", "error", err)
+		return 0, errors.Wrap(err, "failed to consume reset token")
+	}
+
+	_, err = tx.Exec(ctx, `
+	UPDATE patients
+	SET password = $1
+	WHERE id = $2
+`, passwordHash, patientID)
+	if err != nil {
+		s.logger.Error("Failed to update password", "patientID", patientID, "error", err)
+		return 0, errors.Wrap(err, "failed to update password")
+	}
+
+	_, err = tx.Exec(ctx, `
+	UPDATE refresh_tokens
+	SET revoked_at = now()
+	WHERE subject_role = $1 AND subject_id = $2 AND revoked_at IS NULL
+`, domain.RolePatient, patientID)
+	if err != nil {
+		s.logger.Error("Failed to revoke refresh tokens", "patientID", patientID, "error", err)
+		return 0, errors.Wrap(err, "failed to revoke refresh tokens")
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		s.logger.Error("Failed to commit transaction", "error", err)
+		return 0, errors.Wrap(err, "failed to commit transaction")
+	}
+
+	return patientID, nil
+}
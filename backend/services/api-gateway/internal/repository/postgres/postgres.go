@@ -3,64 +3,106 @@ package postgres
 import (
 	"context"
 	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/tracing"
 	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository"
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pkg/errors"
 	"log/slog"
 )
 
 type Storage struct {
-	connection *pgx.Conn
+	connection *pgxpool.Pool
 	logger     *slog.Logger
 }
 
 func New(ctx context.Context, logger *slog.Logger, url string) (*Storage, error) {
-	conn, err := pgx.Connect(ctx, url)
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		logger.Error("Failed to parse postgres connection string", "error", err)
+		return nil, errors.Wrap(err, "failed to parse postgres connection string")
+	}
+	// Каждый запрос попадает в трассу отдельным спаном
+	config.ConnConfig.Tracer = tracing.QueryTracer{}
+
+	conn, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		logger.Error("Failed to connect to postgres", "error", err)
 		return nil, errors.Wrapf(err, "failed to connect to postgres")
 	}
+	// Пул подключается лениво, без проверки сервис стартовал бы с недоступной БД
+	if err = conn.Ping(ctx); err != nil {
+		conn.Close()
+		logger.Error("Failed to ping postgres", "error", err)
+		return nil, errors.Wrap(err, "failed to ping postgres")
+	}
 
 	return &Storage{conn, logger}, nil
 }
 
 func (s *Storage) Close() error {
 	if s.connection != nil {
-		return s.connection.Close(context.Background())
+		s.connection.Close()
 	}
 	return nil
 }
 
-func (s *Storage) RegisterUser(user domain.User) (domain.User, error) {
-	isExistPatient, err := s.CheckUserByEmail(user.Email)
+// Stat возвращает статистику пула соединений для метрик
+func (s *Storage) Stat() *pgxpool.Stat {
+	return s.connection.Stat()
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	return s.connection.Ping(ctx)
+}
+
+// MigrationVersion возвращает версию схемы из таблицы schema_migrations, которую ведет migrate
+func (s *Storage) MigrationVersion(ctx context.Context) (int, bool, error) {
+	var (
+		version int
+		dirty   bool
+	)
+	err := s.connection.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		return 0, false, errors.Wrap(err, "failed to get migration version")
+	}
+	return version, dirty, nil
+}
+
+func (s *Storage) RegisterUser(ctx context.Context, user domain.User) (domain.User, error) {
+	isExistPatient, err := s.CheckUserByEmail(ctx, user.Email)
+	if err != nil {
+		return domain.User{}, err
+	}
 	if isExistPatient {
-		return domain.User{}, errors.New("User already exists")
+		return domain.User{}, repository.ErrorAlreadyExists
 	}
 
 	query := `
-		INSERT INTO patients (name, polic, email, password)
-        VALUES ($1, $2, $3, $4)
+		INSERT INTO patients (name, polic, email, password, status)
+        VALUES ($1, $2, $3, $4, $5)
         RETURNING id
 `
 	var patientId int
-	err = s.connection.QueryRow(context.Background(), query,
+	err = s.connection.QueryRow(ctx, query,
 		user.Name,
 		user.Polic,
 		user.Email,
 		user.Password,
+		domain.PatientStatusPending,
 	).Scan(&patientId)
 	if err != nil {
 		return domain.User{}, errors.Wrap(err, "failed to register user")
 	}
 	user.Id = patientId
+	user.Status = domain.PatientStatusPending
 	return user, nil
 }
 
-func (s *Storage) GetPassword(email string) (int, string, error) {
+func (s *Storage) GetPassword(ctx context.Context, email string) (int, string, error) {
 	query := `
 	SELECT id, password FROM patients WHERE email=$1 and is_deleted=false
 `
-	rows, err := s.connection.Query(context.Background(), query, email)
+	rows, err := s.connection.Query(ctx, query, email)
 	if err != nil {
 		return 0, "", errors.Wrap(err, "failed to query database")
 	}
@@ -74,16 +116,16 @@ func (s *Storage) GetPassword(email string) (int, string, error) {
 			return 0, "", errors.Wrap(err, "failed to scan row")
 		}
 	} else {
-		return 0, "", errors.New("user not found")
+		return 0, "", repository.ErrorNotFound
 	}
 	return user.Id, user.Password, nil
 }
 
-func (s *Storage) GetAdminPassword(email string) (int, string, error) {
+func (s *Storage) GetAdminPassword(ctx context.Context, email string) (int, string, error) {
 	query := `
 	SELECT id, password FROM admins WHERE email=$1 and is_active=true
 `
-	rows, err := s.connection.Query(context.Background(), query, email)
+	rows, err := s.connection.Query(ctx, query, email)
 	if err != nil {
 		return 0, "", errors.Wrap(err, "failed to query database")
 	}
@@ -97,88 +139,79 @@ func (s *Storage) GetAdminPassword(email string) (int, string, error) {
 			return 0, "", errors.Wrap(err, "failed to scan row")
 		}
 	} else {
-		return 0, "", errors.New("user not found")
+		return 0, "", repository.ErrorNotFound
 	}
 	return user.Id, user.Password, nil
 }
 
-func (s *Storage) UpdatePassword(email string, password string) error {
+// UpdatePassword сохраняет новый хеш пароля пациента
+func (s *Storage) UpdatePassword(ctx context.Context, email string, passwordHash string) error {
 
-	s.logger.Debug("Updating password", "email", email, "password", password)
+	s.logger.Debug("Updating password", "email", email)
 
 	query := `
 	UPDATE patients
 	SET password=$1
 	WHERE email=$2
 `
-	_, err := s.connection.Exec(context.Background(), query, password, email)
+	_, err := s.connection.Exec(ctx, query, passwordHash, email)
 	if err != nil {
 		s.logger.Error("Failed to update password", "email", email, "error", err)
 		return errors.Wrap(err, repository.ErrorNotFound.Error())
 	}
 
-	s.logger.Debug("Successfully updated password", "email", email, "password", password)
+	s.logger.Debug("Successfully updated password", "email", email)
 	return nil
 }
 
-func (s *Storage) CheckUserByEmail(email string) (bool, error) {
+func (s *Storage) UpdatePatientPasswordHash(ctx context.Context, patientID int, passwordHash string) error {
 	query := `
-	select email
-	from patients
-	where email=$1 and is_deleted=false
+	UPDATE patients
+	SET password=$1
+	WHERE id=$2
 `
-	rows, err := s.connection.Query(context.Background(), query, email)
+	_, err := s.connection.Exec(ctx, query, passwordHash, patientID)
 	if err != nil {
-		s.logger.Error("Failed to query database", "email", email, "error", err)
-		return false, errors.Wrap(err, "failed to query database: attempt to check user by email")
-	}
-	defer rows.Close()
-
-	if rows.Next() {
-		s.logger.Debug("User found", "email", email)
-		return true, nil
+		s.logger.Error("Failed to update password hash", "patientID", patientID, "error", err)
+		return errors.Wrap(err, "failed to update patient password hash")
 	}
 
-	s.logger.Debug("User not found", "email", email)
-	return false, nil
+	return nil
 }
 
-func (s *Storage) GetUser(email string) (domain.User, error) {
-	var user domain.User
-
+func (s *Storage) UpdateAdminPasswordHash(ctx context.Context, adminID int, passwordHash string) error {
 	query := `
-	select email, password
-	from patients
-	where email=$1 and is_deleted=false
+	UPDATE admins
+	SET password=$1
+	WHERE id=$2
 `
-	err := s.connection.QueryRow(context.Background(), query, email).Scan(&user.Email, &user.Password)
-	if err == pgx.ErrNoRows {
-		return domain.User{}, errors.New("user not found")
-	}
+	_, err := s.connection.Exec(ctx, query, passwordHash, adminID)
 	if err != nil {
-		s.logger.Error("Failed to query database", "email", email, "error", err)
-		return domain.User{}, errors.Wrap(err, "failed to query database: attempt to get user")
+		s.logger.Error("Failed to update password hash", "adminID", adminID, "error", err)
+		return errors.Wrap(err, "failed to update admin password hash")
 	}
 
-	return user, nil
+	return nil
 }
 
-func (s *Storage) GetAdmin(email string) (domain.Admin, error) {
-	var user domain.Admin
-
+func (s *Storage) CheckUserByEmail(ctx context.Context, email string) (bool, error) {
 	query := `
-	select email, password
-	from admins
-	where email=$1 and is_active=true
+	select email
+	from patients
+	where email=$1 and is_deleted=false
 `
-	err := s.connection.QueryRow(context.Background(), query, email).Scan(&user.Email, &user.Password)
-	if err == pgx.ErrNoRows {
-		return domain.Admin{}, errors.New("admin not found")
-	}
+	rows, err := s.connection.Query(ctx, query, email)
 	if err != nil {
 		s.logger.Error("Failed to query database", "email", email, "error", err)
-		return domain.Admin{}, errors.Wrap(err, "failed to query database: attempt to get admin")
+		return false, errors.Wrap(err, "failed to query database: attempt to check user by email")
 	}
+	defer rows.Close()
 
-	return user, nil
+	if rows.Next() {
+		s.logger.Debug("User found", "email", email)
+		return true, nil
+	}
+
+	s.logger.Debug("User not found", "email", email)
+	return false, nil
 }
@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/lib/ratelimit"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// TakeRateLimitToken атомарно пополняет ведро за прошедшее время и списывает токен, если он есть.
+// Ведро общее для всех экземпляров gateway.
+func (s *Storage) TakeRateLimitToken(ctx context.Context, key string, rule ratelimit.Rule) (time.Duration, error) {
+	query := `
+	INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+	VALUES ($1, $2 - 1, true, now())
+	ON CONFLICT (key) DO UPDATE
+	SET tokens = CASE
+	        WHEN least($2, b.tokens + extract(epoch FROM now() - b.updated_at) * $3) >= 1
+	        THEN least($2, b.tokens + extract(epoch FROM now() - b.updated_at) * $3) - 1
+	        ELSE least($2, b.tokens + extract(epoch FROM now() - b.updated_at) * $3)
+	    END,
+	    allowed = least($2, b.tokens + extract(epoch FROM now() - b.updated_at) * $3) >= 1,
+	    updated_at = now()
+	RETURNING tokens, allowed
+`
+	var (
+		tokens  float64
+		allowed bool
+	)
+	err := s.connection.QueryRow(ctx, query, key, float64(rule.Burst), rule.Rate).Scan(&tokens, &allowed)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to take rate limit token")
+	}
+	if allowed {
+		return 0, nil
+	}
+
+	return rule.RetryAfter(tokens), nil
+}
+
+// PurgeRateLimitBuckets удаляет ведра, которые не использовались дольше idle
+func (s *Storage) PurgeRateLimitBuckets(ctx context.Context, idle time.Duration) error {
+	_, err := s.connection.Exec(ctx, `
+	DELETE FROM rate_limit_buckets
+	WHERE updated_at < now() - make_interval(secs => $1)
+`, idle.Seconds())
+	if err != nil {
+		return errors.Wrap(err, "failed to purge rate limit buckets")
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package postgres
+
+import (
+	"context"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+)
+
+func (s *Storage) SaveRefreshToken(ctx context.Context, token domain.RefreshToken) error {
+	query := `
+	INSERT INTO refresh_tokens (jti, family_id, subject_role, subject_id, token_hash, user_agent, ip, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+`
+	_, err := s.connection.Exec(ctx, query,
+		token.JTI,
+		token.FamilyID,
+		token.Identity.Role,
+		token.Identity.Subject,
+		token.TokenHash,
+		token.UserAgent,
+		token.IP,
+		token.ExpiresAt,
+	)
+	if err != nil {
+		s.logger.Error("Failed to save refresh token", "subject", token.Identity.Sub(), "error", err)
+		return errors.Wrap(err, "failed to save refresh token")
+	}
+
+	return nil
+}
+
+// RotateRefreshToken гасит предъявленный токен и сохраняет следующий токен той же цепочки.
+// Повторное предъявление уже замененного токена считается кражей: отзывается вся цепочка
+// и возвращается ErrorTokenReused.
+func (s *Storage) RotateRefreshToken(ctx context.Context, tokenHash string, next domain.RefreshToken) error {
+
+	tx, err := s.connection.Begin(ctx)
+	if err != nil {
+		s.logger.Error("Failed to begin transaction", "error", err)
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	var familyID string
+	err = tx.QueryRow(ctx, `
+	UPDATE refresh_tokens
+	SET revoked_at = now(), replaced_by = $2
+	WHERE token_hash = $1 AND family_id = $3 AND subject_role = $4 AND subject_id = $5
+	  AND revoked_at IS NULL AND expires_at > now()
+	RETURNING family_id
+`, tokenHash, next.JTI, next.FamilyID, next.Identity.Role, next.Identity.Subject).Scan(&familyID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		tx.Rollback(ctx)
+		return s.handleRejectedRefreshToken(ctx, tokenHash)
+	}
+	if err != nil {
+		s.logger.Error("Failed to revoke refresh token", "error", err)
+		return errors.Wrap(err, "failed to revoke refresh token")
+	}
+
+	_, err = tx.Exec(ctx, `
+	INSERT INTO refresh_tokens (jti, family_id, subject_role, subject_id, token_hash, user_agent, ip, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+`, next.JTI, familyID, next.Identity.Role, next.Identity.Subject, next.TokenHash, next.UserAgent, next.IP, next.ExpiresAt)
+	if err != nil {
+		s.logger.Error("Failed to save refresh token", "subject", next.Identity.Sub(), "error", err)
+		return errors.Wrap(err, "failed to save refresh token")
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		s.logger.Error("Failed to commit transaction", "error", err)
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	return nil
+}
+
+func (s *Storage) handleRejectedRefreshToken(ctx context.Context, tokenHash string) error {
+	var familyID string
+	var replacedBy *string
+	err := s.connection.QueryRow(ctx, `
+	SELECT family_id, replaced_by FROM refresh_tokens WHERE token_hash = $1
+`, tokenHash).Scan(&familyID, &replacedBy)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return repository.ErrorInvalidToken
+	}
+	if err != nil {
+		s.logger.Error("Failed to query refresh token", "error", err)
+		return errors.Wrap(err, "failed to query refresh token")
+	}
+
+	if replacedBy == nil {
+		// Токен отозван выходом из системы или истек
+		return repository.ErrorInvalidToken
+	}
+
+	s.logger.Warn("Refresh token reuse detected, revoking token family", "familyID", familyID)
+	if err := s.RevokeRefreshTokenFamily(ctx, familyID); err != nil {
+		return err
+	}
+
+	return repository.ErrorTokenReused
+}
+
+func (s *Storage) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	query := `
+	UPDATE refresh_tokens
+	SET revoked_at = now()
+	WHERE family_id = $1 AND revoked_at IS NULL
+`
+	_, err := s.connection.Exec(ctx, query, familyID)
+	if err != nil {
+		s.logger.Error("Failed to revoke refresh token family", "familyID", familyID, "error", err)
+		return errors.Wrap(err, "failed to revoke refresh token family")
+	}
+
+	return nil
+}
+
+// RevokeRefreshTokenByHash завершает сессию, к которой относится токен
+func (s *Storage) RevokeRefreshTokenByHash(ctx context.Context, tokenHash string) error {
+	query := `
+	UPDATE refresh_tokens
+	SET revoked_at = now()
+	WHERE revoked_at IS NULL
+	  AND family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $1)
+`
+	_, err := s.connection.Exec(ctx, query, tokenHash)
+	if err != nil {
+		s.logger.Error("Failed to revoke refresh token", "error", err)
+		return errors.Wrap(err, "failed to revoke refresh token")
+	}
+
+	return nil
+}
+
+func (s *Storage) RevokeAllRefreshTokens(ctx context.Context, identity domain.Identity) error {
+	query := `
+	UPDATE refresh_tokens
+	SET revoked_at = now()
+	WHERE subject_role = $1 AND subject_id = $2 AND revoked_at IS NULL
+`
+	_, err := s.connection.Exec(ctx, query, identity.Role, identity.Subject)
+	if err != nil {
+		s.logger.Error("Failed to revoke refresh tokens", "subject", identity.Sub(), "error", err)
+		return errors.Wrap(err, "failed to revoke refresh tokens")
+	}
+
+	return nil
+}
+
+func (s *Storage) ListSessions(ctx context.Context, identity domain.Identity) ([]domain.Session, error) {
+	query := `
+	SELECT t.family_id,
+	       coalesce(t.user_agent, ''),
+	       coalesce(t.ip, ''),
+	       (SELECT min(f.created_at) FROM refresh_tokens f WHERE f.family_id = t.family_id),
+	       t.created_at,
+	       t.expires_at
+	FROM refresh_tokens t
+	WHERE t.subject_role = $1 AND t.subject_id = $2
+	  AND t.revoked_at IS NULL AND t.expires_at > now()
+	ORDER BY t.created_at DESC
+`
+	rows, err := s.connection.Query(ctx, query, identity.Role, identity.Subject)
+	if err != nil {
+		s.logger.Error("Failed to query sessions", "subject", identity.Sub(), "error", err)
+		return nil, errors.Wrap(err, "failed to query sessions")
+	}
+	defer rows.Close()
+
+	sessions := make([]domain.Session, 0)
+	for rows.Next() {
+		var session domain.Session
+		err = rows.Scan(
+			&session.Id,
+			&session.UserAgent,
+			&session.IP,
+			&session.StartedAt,
+			&session.LastUsedAt,
+			&session.ExpiresAt,
+		)
+		if err != nil {
+			s.logger.Error("Failed to scan row", "error", err)
+			return nil, errors.Wrap(err, "failed to scan row")
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Error("Error iterating rows", "error", err)
+		return nil, errors.Wrap(err, "error iterating rows")
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession завершает сессию, если она принадлежит пользователю
+func (s *Storage) RevokeSession(ctx context.Context, identity domain.Identity, familyID string) (bool, error) {
+	query := `
+	UPDATE refresh_tokens
+	SET revoked_at = now()
+	WHERE family_id = $1 AND subject_role = $2 AND subject_id = $3 AND revoked_at IS NULL
+`
+	tag, err := s.connection.Exec(ctx, query, familyID, identity.Role, identity.Subject)
+	if err != nil {
+		s.logger.Error("Failed to revoke session", "familyID", familyID, "error", err)
+		return false, errors.Wrap(err, "failed to revoke session")
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/domain"
+	"github.com/daariikk/MyHelp/services/api-gateway/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+func (s *Storage) GetPatientByID(ctx context.Context, patientID int) (domain.User, error) {
+	var user domain.User
+
+	query := `
+	SELECT id, coalesce(surname, ''), coalesce(name, ''), coalesce(patronymic, ''), polic, email, is_deleted, status
+	FROM patients
+	WHERE id = $1 AND is_deleted = false
+`
+	err := s.connection.QueryRow(ctx, query, patientID).Scan(
+		&user.Id,
+		&user.Surname,
+		&user.Name,
+		&user.Patronymic,
+		&user.Polic,
+		&user.Email,
+		&user.IsDeleted,
+		&user.Status,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.User{}, repository.ErrorNotFound
+	}
+	if err != nil {
+		s.logger.Error("Failed to get patient", "patientID", patientID, "error", err)
+		return domain.User{}, errors.Wrap(err, "failed to get patient")
+	}
+
+	return user, nil
+}
+
+// Условие поиска пациентов: $1 - часть email, $2 - начало полиса, $3 - часть ФИО
+const patientSearchCondition = `
+	WHERE is_deleted = false
+	  AND ($1 = '' OR email ILIKE '%' || $1 || '%')
+	  AND ($2 = '' OR polic ILIKE $2 || '%')
+	  AND ($3 = '' OR concat_ws(' ', surname, name, patronymic) ILIKE '%' || $3 || '%')
+`
+
+// SearchPatients возвращает страницу пациентов, подходящих под фильтр, и общее число найденных
+func (s *Storage) SearchPatients(ctx context.Context, filter domain.UserFilter) ([]domain.User, int, error) {
+	query := `
+	SELECT id, coalesce(surname, ''), coalesce(name, ''), coalesce(patronymic, ''), polic, email, is_deleted, status,
+	       count(*) OVER ()
+	FROM patients` + patientSearchCondition + `
+	ORDER BY id
+	LIMIT $4 OFFSET $5
+`
+	rows, err := s.connection.Query(ctx, query,
+		escapeLike(filter.Email),
+		escapeLike(filter.Polic),
+		escapeLike(filter.Name),
+		filter.Limit,
+		filter.Offset,
+	)
+	if err != nil {
+		s.logger.Error("Failed to search patients", "error", err)
+		return nil, 0, errors.Wrap(err, "failed to search patients")
+	}
+	defer rows.Close()
+
+	users := make([]domain.User, 0)
+	total := 0
+	for rows.Next() {
+		var user domain.User
+		err = rows.Scan(
+			&user.Id,
+			&user.Surname,
+			&user.Name,
+			&user.Patronymic,
+			&user.Polic,
+			&user.Email,
+			&user.IsDeleted,
+			&user.Status,
+			&total,
+		)
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "failed to scan row")
+		}
+		users = append(users, user)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, errors.Wrap(err, "failed to search patients")
+	}
+
+	// За пределами последней страницы оконная функция не вернет ни одной строки
+	if len(users) == 0 && filter.Offset > 0 {
+		err = s.connection.QueryRow(ctx, `
+	SELECT count(*)
+	FROM patients`+patientSearchCondition,
+			escapeLike(filter.Email), escapeLike(filter.Polic), escapeLike(filter.Name)).Scan(&total)
+		if err != nil {
+			s.logger.Error("Failed to count patients", "error", err)
+			return nil, 0, errors.Wrap(err, "failed to count patients")
+		}
+	}
+
+	return users, total, nil
+}
+
+// escapeLike экранирует символы шаблона LIKE, чтобы фильтр искал их буквально
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
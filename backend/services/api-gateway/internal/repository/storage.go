@@ -5,4 +5,6 @@ import "errors"
 var (
 	ErrorAlreadyExists = errors.New("patient with this data already exists")
 	ErrorNotFound      = errors.New("patient is not found")
+	ErrorInvalidToken  = errors.New("token is invalid or expired")
+	ErrorTokenReused   = errors.New("refresh token reuse detected")
 )
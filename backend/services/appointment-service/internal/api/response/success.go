@@ -12,9 +12,6 @@ type SuccessResponse struct {
 
 func SendSuccessResponse(w http.ResponseWriter, data interface{}, statusCode int) {
 	w.Header().Set("Content-Type", ContentTypeJSON)
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(SuccessResponse{
 		Status: "success",
@@ -1,94 +1,119 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/daariikk/MyHelp/services/appointment-service/internal/api/response"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/api/rest/helper"
 	"github.com/daariikk/MyHelp/services/appointment-service/internal/domain"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/lib/audit"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/lib/metrics"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/repository"
 	"github.com/go-chi/chi/v5"
 	"log/slog"
 	"net/http"
 	"strconv"
-	"time"
 )
 
 type AppointmentWrapper interface {
-	NewAppointment(appointment domain.Appointment) error
-	UpdateAppointment(appointment domain.Appointment) error
-	DeleteAppointment(appointmentID int) error
+	NewAppointment(ctx context.Context, patientID, slotID int, actor domain.Actor) (*domain.Appointment, error)
+	UpdateAppointment(ctx context.Context, appointment domain.Appointment) error
+	TransitionAppointment(ctx context.Context, appointmentID int, to domain.Status, actor domain.Actor) (domain.Status, error)
+	GetAppointment(ctx context.Context, appointmentID int) (*domain.Appointment, error)
+	RescheduleAppointment(ctx context.Context, appointmentID, slotID int, actor domain.Actor) (*domain.Appointment, error)
 }
 
-func CreateAppointmentHandler(logger *slog.Logger, wrapper AppointmentWrapper) func(http.ResponseWriter, *http.Request) {
+func CreateAppointmentHandler(logger *slog.Logger, wrapper AppointmentWrapper, recorder *audit.Recorder) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Info("CreateAppointmentHandler starting...")
+		logger.InfoContext(r.Context(), "CreateAppointmentHandler starting...")
+
+		caller, err := helper.CallerFromRequest(r)
+		if err != nil {
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error get caller: %v", err))
+			response.SendFailureResponse(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
 
 		var newAppointment domain.AppointmentDTO
-		var appointment domain.Appointment
 
-		err := json.NewDecoder(r.Body).Decode(&newAppointment)
+		err = json.NewDecoder(r.Body).Decode(&newAppointment)
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error parse body: %e", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error parse body: %e", err))
 			response.SendFailureResponse(w, "Error parse body", http.StatusBadRequest)
 			return
 		}
 
-		if newAppointment.DoctorID == 0 {
-			response.SendFailureResponse(w, "doctorID is missing", http.StatusBadRequest)
+		if newAppointment.SlotID == 0 {
+			response.SendFailureResponse(w, "slotID is missing", http.StatusBadRequest)
 			return
 		}
-		appointment.DoctorID = newAppointment.DoctorID
 
+		// Пациент записывает только себя, администратор указывает пациента явно
+		if caller.Role == helper.RolePatient && newAppointment.PatientID == 0 {
+			newAppointment.PatientID = caller.PatientID
+		}
 		if newAppointment.PatientID == 0 {
 			response.SendFailureResponse(w, "PatientID is missing", http.StatusBadRequest)
 			return
 		}
-		appointment.PatientID = newAppointment.PatientID
-
-		appointment.Date, err = time.Parse("2006-01-02", newAppointment.Date) // Формат даты: ГГГГ-ММ-ДД
-		if err != nil {
-			logger.Error(fmt.Sprintf("Error parsing date: %v", err))
-			response.SendFailureResponse(w, "Invalid date format. Expected format: YYYY-MM-DD", http.StatusBadRequest)
-			return
-		}
-
-		appointment.Time, err = time.Parse("15:04:05", newAppointment.Time) // Формат времени: HH:MM:SS
-		if err != nil {
-			logger.Error(fmt.Sprintf("Error parsing time: %v", err))
-			response.SendFailureResponse(w, "Invalid time format. Expected format: HH:MM:SS", http.StatusBadRequest)
+		if !caller.CanAccess(newAppointment.PatientID) {
+			response.SendFailureResponse(w, "Forbidden", http.StatusForbidden)
 			return
 		}
 
-		err = wrapper.NewAppointment(appointment)
+		appointment, err := wrapper.NewAppointment(r.Context(), newAppointment.PatientID, newAppointment.SlotID, caller.Actor())
 		if err != nil {
-			if err.Error() == "Record is busy" {
-				logger.Error(fmt.Sprintf("Error create appointment: %e", err))
-				response.SendFailureResponse(w, "Record is busy", http.StatusInternalServerError)
+			if errors.Is(err, repository.ErrorSlotBusy) {
+				metrics.BookingConflicts.Inc()
+				logger.InfoContext(r.Context(), fmt.Sprintf("Slot is already booked: %v", err))
+				response.SendFailureResponse(w, "Record is busy", http.StatusConflict)
+				return
+			}
+			if errors.Is(err, repository.ErrorSlotNotFound) {
+				logger.InfoContext(r.Context(), fmt.Sprintf("Slot is not found: %v", err))
+				response.SendFailureResponse(w, "Record is not found", http.StatusNotFound)
 				return
 			}
-			logger.Error(fmt.Sprintf("Error create appointment: %e", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error create appointment: %e", err))
 			response.SendFailureResponse(w, "Error create appointment", http.StatusInternalServerError)
 			return
 		}
-		logger.Info("CreateAppointmentHandler end...")
+		metrics.AppointmentsCreated.Inc()
+		recorder.Record(r, audit.Event{
+			Action:     "appointment.create",
+			TargetType: "appointment",
+			TargetID:   strconv.Itoa(appointment.Id),
+			Changes:    audit.Diff(nil, *appointment),
+		})
+
+		logger.InfoContext(r.Context(), "CreateAppointmentHandler end...")
 		response.SendSuccessResponse(w, "Appointment created", http.StatusCreated)
 	}
 }
 
-func UpdateAppointmentHandler(logger *slog.Logger, wrapper AppointmentWrapper) func(http.ResponseWriter, *http.Request) {
+func UpdateAppointmentHandler(logger *slog.Logger, wrapper AppointmentWrapper, recorder *audit.Recorder) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Info("UpdateAppointmentHandler starting...")
+		logger.InfoContext(r.Context(), "UpdateAppointmentHandler starting...")
 		appointmentIDStr := chi.URLParam(r, "appointmentID")
 		appointmentID, err := strconv.ParseInt(appointmentIDStr, 10, 64)
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error parse appointmentID: %e", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error parse appointmentID: %e", err))
 			response.SendFailureResponse(w, "Error parse appointmentID", http.StatusBadRequest)
 			return
 		}
+		before, ok := authorizeAppointment(w, r, logger, wrapper, int(appointmentID))
+		if !ok {
+			return
+		}
+
 		var appointment domain.Appointment
 		err = json.NewDecoder(r.Body).Decode(&appointment)
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error parse body: %e", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error parse body: %e", err))
 			response.SendFailureResponse(w, "Error parse body", http.StatusBadRequest)
+			return
 		}
 		if appointment.Rating == 0 {
 			response.SendFailureResponse(w, "Rating is missing", http.StatusBadRequest)
@@ -96,35 +121,240 @@ func UpdateAppointmentHandler(logger *slog.Logger, wrapper AppointmentWrapper) f
 		}
 
 		appointment.Id = int(appointmentID)
-		err = wrapper.UpdateAppointment(appointment)
+		err = wrapper.UpdateAppointment(r.Context(), appointment)
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error update appointment: %e", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error update appointment: %e", err))
 			response.SendFailureResponse(w, "Error update appointment", http.StatusInternalServerError)
 			return
 		}
-		logger.Info("UpdateAppointmentHandler end...")
+		after := *before
+		after.Rating = appointment.Rating
+		recorder.Record(r, audit.Event{
+			Action:     "appointment.update",
+			TargetType: "appointment",
+			TargetID:   strconv.Itoa(appointment.Id),
+			Changes:    audit.Diff(before, after),
+		})
+
+		logger.InfoContext(r.Context(), "UpdateAppointmentHandler end...")
 		response.SendSuccessResponse(w, "Appointment updated", http.StatusOK)
 	}
 }
 
-func CancelAppointmentHandler(logger *slog.Logger, wrapper AppointmentWrapper) func(http.ResponseWriter, *http.Request) {
+func CancelAppointmentHandler(logger *slog.Logger, wrapper AppointmentWrapper, recorder *audit.Recorder) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Info("CancelAppointmentHandler starting...")
+		logger.InfoContext(r.Context(), "CancelAppointmentHandler starting...")
 		appointmentIDStr := chi.URLParam(r, "appointmentID")
 		appointmentID, err := strconv.ParseInt(appointmentIDStr, 10, 64)
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error parse appointmentID: %e", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error parse appointmentID: %e", err))
 			response.SendFailureResponse(w, "Error parse appointmentID", http.StatusBadRequest)
 			return
 		}
 
-		err = wrapper.DeleteAppointment(int(appointmentID))
+		if _, ok := authorizeAppointment(w, r, logger, wrapper, int(appointmentID)); !ok {
+			return
+		}
+		caller, _ := helper.CallerFromRequest(r)
+
+		// Пациент отменяет запись сам, администратор - от имени клиники
+		to := domain.CANCELED_BY_PATIENT
+		if caller.Role == helper.RoleAdmin {
+			to = domain.CANCELED_BY_CLINIC
+		}
+
+		from, err := wrapper.TransitionAppointment(r.Context(), int(appointmentID), to, caller.Actor())
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error cancel appointment: %e", err))
+			if errors.Is(err, domain.ErrorIllegalTransition) {
+				logger.InfoContext(r.Context(), fmt.Sprintf("Appointment is not cancelled: %v", err))
+				response.SendFailureResponse(w, fmt.Sprintf("Appointment with status %s cannot be cancelled", from.Code()), http.StatusUnprocessableEntity)
+				return
+			}
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error cancel appointment: %e", err))
 			response.SendFailureResponse(w, "Error cancel appointment", http.StatusInternalServerError)
 			return
 		}
-		logger.Info("CancelAppointmentHandler end...")
+		metrics.AppointmentsCancelled.Inc()
+		metrics.StatusTransitions.WithLabelValues(from.Code(), to.Code()).Inc()
+		recorder.Record(r, audit.Event{
+			Action:     "appointment.cancel",
+			TargetType: "appointment",
+			TargetID:   strconv.Itoa(int(appointmentID)),
+			Changes:    statusDiff(from, to),
+		})
+
+		logger.InfoContext(r.Context(), "CancelAppointmentHandler end...")
 		response.SendSuccessResponse(w, "Appointment cancelled", http.StatusOK)
 	}
 }
+
+// RescheduleAppointmentHandler переносит запись в слот slotID того же врача или врача той же специализации
+func RescheduleAppointmentHandler(logger *slog.Logger, wrapper AppointmentWrapper, recorder *audit.Recorder) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.InfoContext(r.Context(), "RescheduleAppointmentHandler starting...")
+		appointmentIDStr := chi.URLParam(r, "appointmentID")
+		appointmentID, err := strconv.ParseInt(appointmentIDStr, 10, 64)
+		if err != nil {
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error parse appointmentID: %e", err))
+			response.SendFailureResponse(w, "Error parse appointmentID", http.StatusBadRequest)
+			return
+		}
+
+		before, ok := authorizeAppointment(w, r, logger, wrapper, int(appointmentID))
+		if !ok {
+			return
+		}
+
+		var request domain.AppointmentDTO
+		err = json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error parse body: %e", err))
+			response.SendFailureResponse(w, "Error parse body", http.StatusBadRequest)
+			return
+		}
+		if request.SlotID == 0 {
+			response.SendFailureResponse(w, "slotID is missing", http.StatusBadRequest)
+			return
+		}
+
+		caller, _ := helper.CallerFromRequest(r)
+		after, err := wrapper.RescheduleAppointment(r.Context(), int(appointmentID), request.SlotID, caller.Actor())
+		if err != nil {
+			switch {
+			case errors.Is(err, repository.ErrorAppointmentNotFound):
+				response.SendFailureResponse(w, fmt.Sprintf("Appointment with appointmentID=%v not found", appointmentID), http.StatusNotFound)
+			case errors.Is(err, repository.ErrorSlotNotFound):
+				response.SendFailureResponse(w, "Record is not found", http.StatusNotFound)
+			case errors.Is(err, repository.ErrorSlotBusy):
+				metrics.BookingConflicts.Inc()
+				response.SendFailureResponse(w, "Record is busy", http.StatusConflict)
+			case errors.Is(err, domain.ErrorIllegalTransition):
+				response.SendFailureResponse(w, fmt.Sprintf("Appointment with status %s cannot be rescheduled", before.Status), http.StatusUnprocessableEntity)
+			case errors.Is(err, repository.ErrorSlotNotAllowed):
+				response.SendFailureResponse(w, "Record must be in the future and belong to the same doctor or specialization", http.StatusUnprocessableEntity)
+			default:
+				logger.ErrorContext(r.Context(), fmt.Sprintf("Error reschedule appointment: %v", err))
+				response.SendFailureResponse(w, "Error reschedule appointment", http.StatusInternalServerError)
+				return
+			}
+			logger.InfoContext(r.Context(), fmt.Sprintf("Appointment is not rescheduled: %v", err))
+			return
+		}
+		metrics.AppointmentsRescheduled.Inc()
+		recorder.Record(r, audit.Event{
+			Action:     "appointment.reschedule",
+			TargetType: "appointment",
+			TargetID:   strconv.Itoa(after.Id),
+			Changes:    audit.Diff(before, after),
+		})
+
+		logger.InfoContext(r.Context(), "RescheduleAppointmentHandler end...")
+		response.SendSuccessResponse(w, "Appointment rescheduled", http.StatusOK)
+	}
+}
+
+// TransitionAppointmentHandler меняет статус записи по таблице переходов domain.Status.
+// Доступен администратору и врачу, к которому сделана запись. Принадлежность записи врачу
+// проверяет хранилище под блокировкой строки
+func TransitionAppointmentHandler(logger *slog.Logger, wrapper AppointmentWrapper, recorder *audit.Recorder) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.InfoContext(r.Context(), "TransitionAppointmentHandler starting...")
+		appointmentIDStr := chi.URLParam(r, "appointmentID")
+		appointmentID, err := strconv.ParseInt(appointmentIDStr, 10, 64)
+		if err != nil {
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error parse appointmentID: %e", err))
+			response.SendFailureResponse(w, "Error parse appointmentID", http.StatusBadRequest)
+			return
+		}
+
+		caller, err := helper.CallerFromRequest(r)
+		if err != nil {
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error get caller: %v", err))
+			response.SendFailureResponse(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if caller.Role != helper.RoleAdmin && caller.Role != helper.RoleDoctor {
+			response.SendFailureResponse(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		var request domain.AppointmentDTO
+		err = json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error parse body: %e", err))
+			response.SendFailureResponse(w, "Error parse body", http.StatusBadRequest)
+			return
+		}
+		to, ok := domain.ParseStatus(request.Status)
+		if !ok {
+			response.SendFailureResponse(w, "Invalid status", http.StatusBadRequest)
+			return
+		}
+
+		from, err := wrapper.TransitionAppointment(r.Context(), int(appointmentID), to, caller.Actor())
+		if err != nil {
+			if errors.Is(err, repository.ErrorAppointmentNotFound) {
+				response.SendFailureResponse(w, fmt.Sprintf("Appointment with appointmentID=%v not found", appointmentID), http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, repository.ErrorNotAppointmentDoctor) {
+				response.SendFailureResponse(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			if errors.Is(err, domain.ErrorIllegalTransition) {
+				logger.InfoContext(r.Context(), fmt.Sprintf("Appointment status is not changed: %v", err))
+				response.SendFailureResponse(w, fmt.Sprintf("Transition from %s to %s is not allowed", from.Code(), to.Code()), http.StatusUnprocessableEntity)
+				return
+			}
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error change appointment status: %v", err))
+			response.SendFailureResponse(w, "Error change appointment status", http.StatusInternalServerError)
+			return
+		}
+		metrics.StatusTransitions.WithLabelValues(from.Code(), to.Code()).Inc()
+		recorder.Record(r, audit.Event{
+			Action:     "appointment.status",
+			TargetType: "appointment",
+			TargetID:   strconv.Itoa(int(appointmentID)),
+			Changes:    statusDiff(from, to),
+		})
+
+		logger.InfoContext(r.Context(), "TransitionAppointmentHandler end...")
+		response.SendSuccessResponse(w, "Appointment status changed", http.StatusOK)
+	}
+}
+
+func statusDiff(from, to domain.Status) map[string]audit.Change {
+	type status struct {
+		Status string `json:"status"`
+	}
+	return audit.Diff(status{from.Code()}, status{to.Code()})
+}
+
+// authorizeAppointment проверяет, что запись существует и принадлежит вызывающему, и возвращает ее.
+// При отказе ответ уже отправлен.
+func authorizeAppointment(w http.ResponseWriter, r *http.Request, logger *slog.Logger, wrapper AppointmentWrapper, appointmentID int) (*domain.Appointment, bool) {
+	caller, err := helper.CallerFromRequest(r)
+	if err != nil {
+		logger.ErrorContext(r.Context(), fmt.Sprintf("Error get caller: %v", err))
+		response.SendFailureResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	appointment, err := wrapper.GetAppointment(r.Context(), appointmentID)
+	if err != nil {
+		if errors.Is(err, repository.ErrorAppointmentNotFound) {
+			response.SendFailureResponse(w, fmt.Sprintf("Appointment with appointmentID=%v not found", appointmentID), http.StatusNotFound)
+			return nil, false
+		}
+		logger.ErrorContext(r.Context(), fmt.Sprintf("Error get appointment: %v", err))
+		response.SendFailureResponse(w, "Error get appointment", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if !caller.CanAccess(appointment.PatientID) {
+		response.SendFailureResponse(w, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+
+	return appointment, true
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"context"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/api/rest/helper"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/domain"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/lib/audit"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/repository"
+	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeAppointments применяет таблицу переходов domain к записям в памяти.
+// Слот занимается под мьютексом, как условным UPDATE в хранилище.
+type fakeAppointments struct {
+	mu       sync.Mutex
+	statuses map[int]domain.Status
+	patients map[int]int
+	doctors  map[int]int
+	slots    map[int]bool
+}
+
+func (f *fakeAppointments) NewAppointment(_ context.Context, patientID, slotID int, _ domain.Actor) (*domain.Appointment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	available, ok := f.slots[slotID]
+	if !ok {
+		return nil, errors.Wrapf(repository.ErrorSlotNotFound, "slotID=%v", slotID)
+	}
+	if !available {
+		return nil, errors.Wrapf(repository.ErrorSlotBusy, "slotID=%v", slotID)
+	}
+	f.slots[slotID] = false
+
+	id := len(f.statuses) + 1
+	f.statuses[id] = domain.SCHEDULED
+	f.patients[id] = patientID
+	return &domain.Appointment{Id: id, PatientID: patientID, ScheduleSlotID: slotID, Status: domain.SCHEDULED.Code()}, nil
+}
+
+func (f *fakeAppointments) UpdateAppointment(context.Context, domain.Appointment) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeAppointments) RescheduleAppointment(context.Context, int, int, domain.Actor) (*domain.Appointment, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeAppointments) GetAppointment(_ context.Context, appointmentID int) (*domain.Appointment, error) {
+	status, ok := f.statuses[appointmentID]
+	if !ok {
+		return nil, repository.ErrorAppointmentNotFound
+	}
+	return &domain.Appointment{Id: appointmentID, PatientID: f.patients[appointmentID], Status: status.Code()}, nil
+}
+
+func (f *fakeAppointments) TransitionAppointment(_ context.Context, appointmentID int, to domain.Status, actor domain.Actor) (domain.Status, error) {
+	from, ok := f.statuses[appointmentID]
+	if !ok {
+		return 0, errors.Wrapf(repository.ErrorAppointmentNotFound, "appointment with id %d", appointmentID)
+	}
+	if actor.Role == domain.ActorDoctor && actor.ID != f.doctors[appointmentID] {
+		return from, errors.Wrapf(repository.ErrorNotAppointmentDoctor, "appointment with id %d", appointmentID)
+	}
+	if err := from.Transition(to); err != nil {
+		return from, errors.Wrapf(err, "appointment with id %d", appointmentID)
+	}
+	f.statuses[appointmentID] = to
+	return from, nil
+}
+
+type discardAudit struct{}
+
+func (discardAudit) SaveAuditEvent(context.Context, audit.Event) error { return nil }
+
+func newTestRouter(wrapper AppointmentWrapper) http.Handler {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	recorder := audit.New("appointment-service", discardAudit{}, logger, nil)
+
+	router := chi.NewRouter()
+	router.Post("/", CreateAppointmentHandler(logger, wrapper, recorder))
+	router.Post("/{appointmentID}/status", TransitionAppointmentHandler(logger, wrapper, recorder))
+	router.Delete("/{appointmentID}", CancelAppointmentHandler(logger, wrapper, recorder))
+	return router
+}
+
+func TestTransitionAppointmentHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     string
+		doctorID string
+		from     domain.Status
+		body     string
+		wantCode int
+		want     domain.Status
+	}{
+		{"confirm", helper.RoleAdmin, "", domain.SCHEDULED, `{"status":"confirmed"}`, http.StatusOK, domain.CONFIRMED},
+		{"check in", helper.RoleAdmin, "", domain.CONFIRMED, `{"status":"CHECKED_IN"}`, http.StatusOK, domain.CHECKED_IN},
+		{"complete", helper.RoleAdmin, "", domain.CHECKED_IN, `{"status":"completed"}`, http.StatusOK, domain.COMPLETED},
+		{"skip check in", helper.RoleAdmin, "", domain.SCHEDULED, `{"status":"completed"}`, http.StatusUnprocessableEntity, domain.SCHEDULED},
+		{"reopen completed", helper.RoleAdmin, "", domain.COMPLETED, `{"status":"scheduled"}`, http.StatusUnprocessableEntity, domain.COMPLETED},
+		{"cancel no-show", helper.RoleAdmin, "", domain.NO_SHOW, `{"status":"canceled_by_clinic"}`, http.StatusUnprocessableEntity, domain.NO_SHOW},
+		{"unknown status", helper.RoleAdmin, "", domain.SCHEDULED, `{"status":"lost"}`, http.StatusBadRequest, domain.SCHEDULED},
+		{"patient", helper.RolePatient, "", domain.SCHEDULED, `{"status":"confirmed"}`, http.StatusForbidden, domain.SCHEDULED},
+		{"doctor of appointment", helper.RoleDoctor, "3", domain.CHECKED_IN, `{"status":"completed"}`, http.StatusOK, domain.COMPLETED},
+		{"doctor illegal transition", helper.RoleDoctor, "3", domain.SCHEDULED, `{"status":"completed"}`, http.StatusUnprocessableEntity, domain.SCHEDULED},
+		{"other doctor", helper.RoleDoctor, "4", domain.CHECKED_IN, `{"status":"completed"}`, http.StatusForbidden, domain.CHECKED_IN},
+		{"doctor without id", helper.RoleDoctor, "", domain.CHECKED_IN, `{"status":"completed"}`, http.StatusUnauthorized, domain.CHECKED_IN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrapper := &fakeAppointments{statuses: map[int]domain.Status{1: tt.from}, patients: map[int]int{1: 7}, doctors: map[int]int{1: 3}}
+
+			req := httptest.NewRequest(http.MethodPost, "/1/status", strings.NewReader(tt.body))
+			req.Header.Set(helper.HeaderRole, tt.role)
+			req.Header.Set(helper.HeaderPatientID, "7")
+			req.Header.Set(helper.HeaderAdminID, "1")
+			req.Header.Set(helper.HeaderDoctorID, tt.doctorID)
+			rec := httptest.NewRecorder()
+			newTestRouter(wrapper).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d, body %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if got := wrapper.statuses[1]; got != tt.want {
+				t.Fatalf("appointment status = %s, want %s", got.Code(), tt.want.Code())
+			}
+		})
+	}
+}
+
+func TestTransitionAppointmentHandlerNotFound(t *testing.T) {
+	wrapper := &fakeAppointments{statuses: map[int]domain.Status{}}
+
+	req := httptest.NewRequest(http.MethodPost, "/1/status", strings.NewReader(`{"status":"confirmed"}`))
+	req.Header.Set(helper.HeaderRole, helper.RoleAdmin)
+	rec := httptest.NewRecorder()
+	newTestRouter(wrapper).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status code = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestCancelAppointmentHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     string
+		from     domain.Status
+		wantCode int
+		want     domain.Status
+	}{
+		{"patient cancels scheduled", helper.RolePatient, domain.SCHEDULED, http.StatusOK, domain.CANCELED_BY_PATIENT},
+		{"admin cancels confirmed", helper.RoleAdmin, domain.CONFIRMED, http.StatusOK, domain.CANCELED_BY_CLINIC},
+		{"patient cancels checked in", helper.RolePatient, domain.CHECKED_IN, http.StatusUnprocessableEntity, domain.CHECKED_IN},
+		{"patient cancels twice", helper.RolePatient, domain.CANCELED_BY_PATIENT, http.StatusUnprocessableEntity, domain.CANCELED_BY_PATIENT},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrapper := &fakeAppointments{statuses: map[int]domain.Status{1: tt.from}, patients: map[int]int{1: 7}}
+
+			req := httptest.NewRequest(http.MethodDelete, "/1", nil)
+			req.Header.Set(helper.HeaderRole, tt.role)
+			req.Header.Set(helper.HeaderPatientID, "7")
+			rec := httptest.NewRecorder()
+			newTestRouter(wrapper).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d, body %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if got := wrapper.statuses[1]; got != tt.want {
+				t.Fatalf("appointment status = %s, want %s", got.Code(), tt.want.Code())
+			}
+		})
+	}
+}
+
+func TestCreateAppointmentHandlerConcurrentBooking(t *testing.T) {
+	const requests = 20
+	wrapper := &fakeAppointments{statuses: map[int]domain.Status{}, patients: map[int]int{}, slots: map[int]bool{5: true}}
+	router := newTestRouter(wrapper)
+
+	var (
+		wg    sync.WaitGroup
+		start = make(chan struct{})
+		mu    sync.Mutex
+		codes = make(map[int]int)
+	)
+	for patientID := 1; patientID <= requests; patientID++ {
+		wg.Add(1)
+		go func(patientID int) {
+			defer wg.Done()
+			<-start
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"slotID":5}`))
+			req.Header.Set(helper.HeaderRole, helper.RolePatient)
+			req.Header.Set(helper.HeaderPatientID, strconv.Itoa(patientID))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			mu.Lock()
+			codes[rec.Code]++
+			mu.Unlock()
+		}(patientID)
+	}
+	close(start)
+	wg.Wait()
+
+	if codes[http.StatusCreated] != 1 || codes[http.StatusConflict] != requests-1 {
+		t.Fatalf("status codes = %v, want one %d and %d of %d", codes, http.StatusCreated, requests-1, http.StatusConflict)
+	}
+	if len(wrapper.statuses) != 1 {
+		t.Fatalf("appointments = %d, want 1", len(wrapper.statuses))
+	}
+}
+
+func TestCreateAppointmentHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{"free slot", `{"slotID":5}`, http.StatusCreated},
+		{"busy slot", `{"slotID":6}`, http.StatusConflict},
+		{"unknown slot", `{"slotID":404}`, http.StatusNotFound},
+		{"missing slot", `{}`, http.StatusBadRequest},
+		{"other patient", `{"slotID":5,"patientID":8}`, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrapper := &fakeAppointments{statuses: map[int]domain.Status{}, patients: map[int]int{}, slots: map[int]bool{5: true, 6: false}}
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set(helper.HeaderRole, helper.RolePatient)
+			req.Header.Set(helper.HeaderPatientID, "7")
+			rec := httptest.NewRecorder()
+			newTestRouter(wrapper).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d, body %s", rec.Code, tt.wantCode, rec.Body)
+			}
+		})
+	}
+}
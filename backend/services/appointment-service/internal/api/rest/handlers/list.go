@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/api/response"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/api/rest/helper"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/domain"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+type AppointmentLister interface {
+	ListAppointments(ctx context.Context, filter domain.AppointmentFilter) ([]domain.AppointmentSummary, error)
+}
+
+// AppointmentPage - страница списка записей. NextCursor пуст на последней странице
+type AppointmentPage struct {
+	Items      []domain.AppointmentSummaryDTO `json:"items"`
+	NextCursor string                         `json:"nextCursor,omitempty"`
+}
+
+// GetAppointmentsHandler возвращает записи к врачу постранично.
+// Фильтры: patient_id, doctor_id, specialization_id, status, from, to (ГГГГ-ММ-ДД);
+// sort=date|-date; cursor, limit. Курсор действует только с теми же sort и фильтрами.
+// Пациент видит только свои записи.
+func GetAppointmentsHandler(logger *slog.Logger, wrapper AppointmentLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "GetAppointmentsHandler starting...")
+
+		caller, err := helper.CallerFromRequest(r)
+		if err != nil {
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error get caller: %v", err))
+			response.SendFailureResponse(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		filter, err := appointmentFilter(r.URL.Query())
+		if err != nil {
+			response.SendFailureResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if caller.Role == helper.RolePatient {
+			if filter.PatientID != 0 && filter.PatientID != caller.PatientID {
+				response.SendFailureResponse(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			filter.PatientID = caller.PatientID
+		}
+
+		// Курсор от другой сортировки или других фильтров указал бы на чужую позицию
+		if filter.After != nil && (filter.After.Desc != filter.Desc || filter.After.Filter != appointmentFilterFingerprint(filter)) {
+			response.SendFailureResponse(w, "Cursor does not match the query", http.StatusBadRequest)
+			return
+		}
+
+		// Лишняя запись показывает, что есть следующая страница
+		limit := filter.Limit
+		filter.Limit++
+		appointments, err := wrapper.ListAppointments(r.Context(), filter)
+		if err != nil {
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error list appointments: %v", err))
+			response.SendFailureResponse(w, "Error get appointments", http.StatusInternalServerError)
+			return
+		}
+
+		var page AppointmentPage
+		if len(appointments) > limit {
+			appointments = appointments[:limit]
+			last := appointments[limit-1]
+			page.NextCursor = encodeAppointmentCursor(domain.AppointmentCursor{
+				Date:   last.Date,
+				Time:   last.Time,
+				ID:     last.Id,
+				Desc:   filter.Desc,
+				Filter: appointmentFilterFingerprint(filter),
+			})
+		}
+
+		page.Items = make([]domain.AppointmentSummaryDTO, len(appointments))
+		for i, appointment := range appointments {
+			page.Items[i] = domain.AppointmentSummaryDTO{
+				Id:                   appointment.Id,
+				PatientID:            appointment.PatientID,
+				DoctorID:             appointment.DoctorID,
+				DoctorFIO:            appointment.DoctorFIO,
+				DoctorSpecialization: appointment.DoctorSpecialization,
+				Date:                 appointment.Date.Format("2006-01-02"),
+				Time:                 appointment.Time.Format("15:04:05"),
+				Status:               appointment.Status,
+			}
+		}
+
+		logger.InfoContext(r.Context(), "GetAppointmentsHandler works successful", slog.Int("found", len(page.Items)))
+		response.SendSuccessResponse(w, page, http.StatusOK)
+	}
+}
+
+func appointmentFilter(query url.Values) (domain.AppointmentFilter, error) {
+	var filter domain.AppointmentFilter
+
+	for name, target := range map[string]*int{
+		"patient_id":        &filter.PatientID,
+		"doctor_id":         &filter.DoctorID,
+		"specialization_id": &filter.SpecializationID,
+	} {
+		if value := query.Get(name); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil || id < 1 {
+				return domain.AppointmentFilter{}, errors.New("Invalid " + name)
+			}
+			*target = id
+		}
+	}
+
+	if value := query.Get("status"); value != "" {
+		status, ok := domain.ParseStatus(value)
+		if !ok {
+			return domain.AppointmentFilter{}, errors.New("Invalid status")
+		}
+		filter.Status = status.Code()
+	}
+
+	for name, target := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := query.Get(name); value != "" {
+			date, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				return domain.AppointmentFilter{}, errors.New("Invalid " + name + ". Expected format: YYYY-MM-DD")
+			}
+			*target = date
+		}
+	}
+
+	switch query.Get("sort") {
+	case "", "date":
+	case "-date":
+		filter.Desc = true
+	default:
+		return domain.AppointmentFilter{}, errors.New("Invalid sort: expected date or -date")
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := decodeAppointmentCursor(value)
+		if err != nil {
+			return domain.AppointmentFilter{}, errors.New("Invalid cursor")
+		}
+		filter.After = &cursor
+	}
+
+	filter.Limit = defaultPageLimit
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return domain.AppointmentFilter{}, errors.New("Invalid limit")
+		}
+		filter.Limit = min(limit, maxPageLimit)
+	}
+
+	return filter, nil
+}
+
+// appointmentFilterFingerprint - отпечаток условий выборки без курсора и размера страницы
+func appointmentFilterFingerprint(filter domain.AppointmentFilter) string {
+	raw := fmt.Sprintf("%d|%d|%d|%s|%s|%s",
+		filter.PatientID,
+		filter.DoctorID,
+		filter.SpecializationID,
+		filter.Status,
+		formatFilterDate(filter.From),
+		formatFilterDate(filter.To),
+	)
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:8])
+}
+
+func formatFilterDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
+
+// Курсор непрозрачен для клиента: дата, время и id последней записи страницы,
+// направление сортировки и отпечаток фильтров
+func encodeAppointmentCursor(cursor domain.AppointmentCursor) string {
+	sort := "date"
+	if cursor.Desc {
+		sort = "-date"
+	}
+	raw := strings.Join([]string{
+		cursor.Date.Format("2006-01-02"),
+		cursor.Time.Format("15:04:05.999999"),
+		strconv.Itoa(cursor.ID),
+		sort,
+		cursor.Filter,
+	}, "|")
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeAppointmentCursor(value string) (domain.AppointmentCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return domain.AppointmentCursor{}, err
+	}
+
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 5 {
+		return domain.AppointmentCursor{}, errors.New("malformed cursor")
+	}
+
+	var cursor domain.AppointmentCursor
+	if cursor.Date, err = time.Parse("2006-01-02", parts[0]); err != nil {
+		return domain.AppointmentCursor{}, err
+	}
+	if cursor.Time, err = time.Parse("15:04:05.999999", parts[1]); err != nil {
+		return domain.AppointmentCursor{}, err
+	}
+	if cursor.ID, err = strconv.Atoi(parts[2]); err != nil {
+		return domain.AppointmentCursor{}, err
+	}
+	switch parts[3] {
+	case "date":
+	case "-date":
+		cursor.Desc = true
+	default:
+		return domain.AppointmentCursor{}, errors.New("malformed cursor sort")
+	}
+	cursor.Filter = parts[4]
+
+	return cursor, nil
+}
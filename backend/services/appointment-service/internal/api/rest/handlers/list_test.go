@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/api/rest/helper"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/domain"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeLister отдает записи по порядку id после курсора, без фильтрации
+type fakeLister struct {
+	appointments []domain.AppointmentSummary
+}
+
+func (f *fakeLister) ListAppointments(_ context.Context, filter domain.AppointmentFilter) ([]domain.AppointmentSummary, error) {
+	result := make([]domain.AppointmentSummary, 0, filter.Limit)
+	for _, appointment := range f.appointments {
+		if filter.After != nil && appointment.Id <= filter.After.ID {
+			continue
+		}
+		if len(result) == filter.Limit {
+			break
+		}
+		result = append(result, appointment)
+	}
+	return result, nil
+}
+
+func listAppointments(t *testing.T, lister AppointmentLister, query string) (int, AppointmentPage) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+	req.Header.Set(helper.HeaderRole, helper.RoleAdmin)
+	rec := httptest.NewRecorder()
+	GetAppointmentsHandler(slog.New(slog.NewTextHandler(io.Discard, nil)), lister).ServeHTTP(rec, req)
+
+	var res struct {
+		Data AppointmentPage `json:"data"`
+	}
+	if rec.Code == http.StatusOK {
+		body, _ := io.ReadAll(rec.Body)
+		if err := json.Unmarshal(body, &res); err != nil {
+			t.Fatalf("decode response error = %v", err)
+		}
+	}
+	return rec.Code, res.Data
+}
+
+func TestGetAppointmentsHandlerCursor(t *testing.T) {
+	lister := &fakeLister{}
+	for id := 1; id <= 3; id++ {
+		lister.appointments = append(lister.appointments, domain.AppointmentSummary{
+			Id:     id,
+			Date:   time.Date(2099, 1, id, 0, 0, 0, 0, time.UTC),
+			Time:   time.Date(0, 1, 1, 9, 0, 0, 0, time.UTC),
+			Status: domain.SCHEDULED.Code(),
+		})
+	}
+
+	code, first := listAppointments(t, lister, "doctor_id=3&limit=2")
+	if code != http.StatusOK || len(first.Items) != 2 || first.NextCursor == "" {
+		t.Fatalf("first page = %d %+v, want 2 items and a cursor", code, first)
+	}
+
+	code, second := listAppointments(t, lister, "doctor_id=3&limit=2&cursor="+first.NextCursor)
+	if code != http.StatusOK || len(second.Items) != 1 || second.Items[0].Id != 3 || second.NextCursor != "" {
+		t.Fatalf("second page = %d %+v, want only id 3", code, second)
+	}
+
+	// Размер страницы в отпечаток не входит
+	if code, _ = listAppointments(t, lister, "doctor_id=3&limit=5&cursor="+first.NextCursor); code != http.StatusOK {
+		t.Fatalf("other limit: status = %d, want 200", code)
+	}
+
+	for _, query := range []string{
+		"doctor_id=3&limit=2&sort=-date&cursor=" + first.NextCursor,
+		"doctor_id=4&limit=2&cursor=" + first.NextCursor,
+		"limit=2&cursor=" + first.NextCursor,
+		"doctor_id=3&status=completed&limit=2&cursor=" + first.NextCursor,
+		"doctor_id=3&from=2099-01-01&limit=2&cursor=" + first.NextCursor,
+		"doctor_id=3&limit=2&cursor=not-a-cursor",
+	} {
+		if code, _ = listAppointments(t, lister, query); code != http.StatusBadRequest {
+			t.Fatalf("%s: status = %d, want 400", query, code)
+		}
+	}
+}
@@ -1,18 +1,54 @@
 package handlers
 
-import "net/http"
+import (
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/api/response"
+	"github.com/daariikk/MyHelp/services/jwtverify"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
 
-func CorsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, PATCH, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+// Заголовки, которыми gateway передает личность вызывающего
+var identityHeaders = []string{"X-Role", "X-Patient-ID", "X-Admin-ID", "X-Doctor-ID"}
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+var subjectHeaders = map[string]string{
+	"patient": "X-Patient-ID",
+	"admin":   "X-Admin-ID",
+	"doctor":  "X-Doctor-ID",
+}
+
+// AuthMiddleware проверяет access токен локально по ключам из JWKS gateway
+// и заменяет заголовки личности значениями из токена.
+func AuthMiddleware(logger *slog.Logger, verifier *jwtverify.Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, header := range identityHeaders {
+				r.Header.Del(header)
+			}
+
+			scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+			if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+				response.SendFailureResponse(w, "Missing bearer token", http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := verifier.Verify(r.Context(), strings.TrimSpace(token))
+			if err != nil {
+				logger.DebugContext(r.Context(), "Invalid access token", slog.String("error", err.Error()))
+				response.SendFailureResponse(w, "Invalid or expired access token", http.StatusUnauthorized)
+				return
+			}
+
+			header, ok := subjectHeaders[claims.Role]
+			if !ok {
+				response.SendFailureResponse(w, "Invalid token claims", http.StatusUnauthorized)
+				return
+			}
+			r.Header.Set(header, strconv.Itoa(claims.SubjectID))
+			r.Header.Set("X-Role", claims.Role)
 
-		next.ServeHTTP(w, r)
-	})
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -0,0 +1,75 @@
+package helper
+
+import (
+	"errors"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/domain"
+	"net/http"
+	"strconv"
+)
+
+// Заголовки выставляет api-gateway после проверки access токена
+const (
+	HeaderPatientID = "X-Patient-ID"
+	HeaderAdminID   = "X-Admin-ID"
+	HeaderDoctorID  = "X-Doctor-ID"
+	HeaderRole      = "X-Role"
+)
+
+const (
+	RolePatient = "patient"
+	RoleAdmin   = "admin"
+	RoleDoctor  = "doctor"
+)
+
+type Caller struct {
+	Role      string
+	PatientID int
+	AdminID   int
+	DoctorID  int
+}
+
+// CallerFromRequest возвращает личность вызывающего, проверенную api-gateway
+func CallerFromRequest(r *http.Request) (Caller, error) {
+	caller := Caller{Role: r.Header.Get(HeaderRole)}
+
+	switch caller.Role {
+	case RolePatient:
+		patientID, err := strconv.Atoi(r.Header.Get(HeaderPatientID))
+		if err != nil || patientID <= 0 {
+			return Caller{}, errors.New("patient identity is invalid")
+		}
+		caller.PatientID = patientID
+	case RoleAdmin:
+		// id администратора нужен только для истории статусов, его отсутствие не ошибка
+		caller.AdminID, _ = strconv.Atoi(r.Header.Get(HeaderAdminID))
+	case RoleDoctor:
+		doctorID, err := strconv.Atoi(r.Header.Get(HeaderDoctorID))
+		if err != nil || doctorID <= 0 {
+			return Caller{}, errors.New("doctor identity is invalid")
+		}
+		caller.DoctorID = doctorID
+	case "":
+		return Caller{}, errors.New("caller identity is missing")
+	default:
+		return Caller{}, errors.New("caller role is not allowed")
+	}
+
+	return caller, nil
+}
+
+// CanAccess проверяет, что пациент работает только со своими записями
+func (c Caller) CanAccess(patientID int) bool {
+	return c.Role == RoleAdmin || c.PatientID == patientID
+}
+
+// Actor возвращает вызывающего для истории статусов записи
+func (c Caller) Actor() domain.Actor {
+	switch c.Role {
+	case RoleAdmin:
+		return domain.Actor{Role: c.Role, ID: c.AdminID}
+	case RoleDoctor:
+		return domain.Actor{Role: c.Role, ID: c.DoctorID}
+	default:
+		return domain.Actor{Role: c.Role, ID: c.PatientID}
+	}
+}
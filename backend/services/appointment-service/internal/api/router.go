@@ -2,27 +2,55 @@ package api
 
 import (
 	"github.com/daariikk/MyHelp/services/appointment-service/internal/api/rest/handlers"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/config"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/lib/audit"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/lib/health"
+	"github.com/daariikk/MyHelp/services/jwtverify"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/lib/metrics"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/lib/requestid"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/lib/tracing"
 	"github.com/daariikk/MyHelp/services/appointment-service/internal/repository/postgres"
 	"github.com/go-chi/chi/v5"
 	"log/slog"
 )
 
-func NewRouter(logger *slog.Logger, storage *postgres.Storage) *chi.Mux {
+// Имя сервиса в журнале аудита
+const auditService = "appointment-service"
+
+func NewRouter(cfg *config.Config, logger *slog.Logger, storage *postgres.Storage, checker *health.Checker, verifier *jwtverify.Verifier) *chi.Mux {
 	router := chi.NewRouter()
-	router.Use(handlers.CorsMiddleware)
+	router.Use(requestid.Middleware)
+	router.Use(tracing.Middleware)
+	router.Use(metrics.Middleware)
+
+	// Пробы оркестратора проходят без токена
+	router.Get("/healthz", checker.LivenessHandler())
+	router.Get("/readyz", checker.ReadinessHandler())
+
+	router.Group(func(r chi.Router) {
+		r.Use(handlers.AuthMiddleware(logger, verifier))
+
+		recorder := audit.New(auditService, storage, logger, cfg.TrustedProxies)
+
+		r.Route("/MyHelp/schedule/appointments", func(r chi.Router) {
+			// Создать запись к врачу
+			r.Post("/", handlers.CreateAppointmentHandler(logger, storage, recorder))
+
+			// Получить записи к врачу с фильтрами и постраничной выдачей
+			r.Get("/", handlers.GetAppointmentsHandler(logger, storage))
 
-	router.Route("/MyHelp/schedule/appointments", func(r chi.Router) {
-		// Создать запись к врачу
-		r.Post("/", handlers.CreateAppointmentHandler(logger, storage))
+			// Изменить запись к врачу
+			r.Patch("/{appointmentID}", handlers.UpdateAppointmentHandler(logger, storage, recorder))
 
-		// Получить записи к врачу по id пациента patientID
-		//r.Get("/", handlers.GetAppointmentsHandler(logger, storage))
+			// Перенести запись к врачу в другой слот
+			r.Post("/{appointmentID}/reschedule", handlers.RescheduleAppointmentHandler(logger, storage, recorder))
 
-		// Изменить запись к врачу
-		r.Patch("/{appointmentID}", handlers.UpdateAppointmentHandler(logger, storage))
+			// Сменить статус записи к врачу (администратор или врач этой записи)
+			r.Post("/{appointmentID}/status", handlers.TransitionAppointmentHandler(logger, storage, recorder))
 
-		// Удалить/отменить запись к врачу
-		r.Delete("/{appointmentID}", handlers.CancelAppointmentHandler(logger, storage))
+			// Удалить/отменить запись к врачу
+			r.Delete("/{appointmentID}", handlers.CancelAppointmentHandler(logger, storage, recorder))
+		})
 	})
 
 	return router
@@ -5,8 +5,15 @@ import (
 	"fmt"
 	"github.com/daariikk/MyHelp/services/appointment-service/internal/api"
 	"github.com/daariikk/MyHelp/services/appointment-service/internal/config"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/lib/health"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/lib/logger/handlers/slogctx"
 	"github.com/daariikk/MyHelp/services/appointment-service/internal/lib/logger/handlers/slogpretty"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/lib/metrics"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/lib/requestid"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/lib/tracing"
 	"github.com/daariikk/MyHelp/services/appointment-service/internal/repository/postgres"
+	"github.com/daariikk/MyHelp/services/jwtverify"
+	"github.com/daariikk/MyHelp/services/migrations"
 	"log"
 	"log/slog"
 	"net/http"
@@ -24,7 +31,12 @@ type App struct {
 	logger *slog.Logger
 	config *config.Config
 	server *http.Server
-	db     *postgres.Storage
+	// nil, если метрики отключены
+	metricsServer *http.Server
+	checker       *health.Checker
+	db            *postgres.Storage
+	// Досылает накопленные спаны при остановке
+	shutdownTracing func(context.Context) error
 }
 
 type DBParams struct {
@@ -36,6 +48,16 @@ type DBParams struct {
 func New(config *config.Config) *App {
 	logger := setupLogger(config.Env)
 
+	shutdownTracing, err := tracing.Setup(context.Background(), serviceName, tracing.Options{
+		Exporter:    config.Tracing.Exporter,
+		Endpoint:    config.Tracing.Endpoint,
+		FilePath:    config.Tracing.FilePath,
+		SampleRatio: config.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Fatalf("tracing setup error: %s", err)
+	}
+
 	dbUrlConnection := CreateDBConnectionUrl(*config)
 	logger.Debug("DB url connection", slog.String("url", dbUrlConnection))
 	ctx := context.Background()
@@ -45,7 +67,37 @@ func New(config *config.Config) *App {
 		log.Fatalf("db create error: %s", err)
 	}
 
-	router := api.NewRouter(logger, storage)
+	verifier, err := jwtverify.New(jwtverify.Options{
+		JWKSURL:         config.Auth.JWKSURL,
+		Issuer:          config.Auth.Issuer,
+		Audience:        config.Auth.Audience,
+		ClockSkew:       config.Auth.ClockSkew,
+		RefreshInterval: config.Auth.RefreshInterval,
+		RequestID:       requestid.FromContext,
+	})
+	if err != nil {
+		log.Fatalf("jwt verifier create error: %s", err)
+	}
+
+	metrics.RegisterPool(storage)
+
+	checker := health.New(readinessTimeout)
+	checker.Add("database", health.Database(storage))
+	checker.Add("migrations", health.Migrations(storage, migrations.Latest()))
+
+	router := api.NewRouter(config, logger, storage, checker, verifier)
+
+	var metricsServer *http.Server
+	if config.MetricsAddress != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		metricsServer = &http.Server{
+			Addr:         config.MetricsAddress,
+			Handler:      mux,
+			ReadTimeout:  config.Timeout,
+			WriteTimeout: config.Timeout,
+		}
+	}
 
 	return &App{
 		logger: logger,
@@ -57,20 +109,31 @@ func New(config *config.Config) *App {
 			WriteTimeout: config.Timeout,
 			IdleTimeout:  config.IdleTimeout,
 		},
-		db: storage,
+		metricsServer:   metricsServer,
+		checker:         checker,
+		db:              storage,
+		shutdownTracing: shutdownTracing,
 	}
 }
 
 func (a *App) Run() {
 	defer a.db.Close()
 
-	errChan := make(chan error, 1)
+	errChan := make(chan error, 2)
 	go func() {
 		a.logger.Info("Starting server", slog.String("addr", a.config.Address))
 		if err := a.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			errChan <- err
 		}
 	}()
+	if a.metricsServer != nil {
+		go func() {
+			a.logger.Info("Starting metrics server", slog.String("addr", a.metricsServer.Addr))
+			if err := a.metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				errChan <- err
+			}
+		}()
+	}
 
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
@@ -84,12 +147,23 @@ func (a *App) Run() {
 		a.logger.Info("Shutting down server...")
 	}
 
+	a.checker.Shutdown()
+	time.Sleep(a.config.ShutdownDelay)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := a.server.Shutdown(ctx); err != nil {
 		a.logger.Error("Server forced to shutdown", slog.String("error", err.Error()))
 	}
+	if a.metricsServer != nil {
+		if err := a.metricsServer.Shutdown(ctx); err != nil {
+			a.logger.Error("Metrics server forced to shutdown", slog.String("error", err.Error()))
+		}
+	}
+	if err := a.shutdownTracing(ctx); err != nil {
+		a.logger.Error("Failed to flush traces", slog.String("error", err.Error()))
+	}
 
 	a.logger.Info("Server exiting")
 }
@@ -114,7 +188,8 @@ func setupLogger(env string) *slog.Logger {
 		)
 	}
 
-	return log
+	// id запроса из контекста добавляется к записям в любом окружении
+	return slog.New(slogctx.NewHandler(log.Handler()))
 }
 
 func setupPrettySlog() *slog.Logger {
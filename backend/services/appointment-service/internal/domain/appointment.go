@@ -2,29 +2,73 @@ package domain
 
 import "time"
 
-// статусы записи
-const (
-	SCHEDULED = 1
-	COMPLETED = 2
-	CANCELED  = 3
-)
-
 type Appointment struct {
-	Id        int       `json:"appointmentID"`
-	DoctorID  int       `json:"doctorID"`
-	PatientID int       `json:"patientID"`
-	Date      time.Time `json:"date"`
-	Time      time.Time `json:"time"`
-	Status    string    `json:"status"`
-	Rating    float64   `json:"rating"`
+	Id             int       `json:"appointmentID"`
+	DoctorID       int       `json:"doctorID"`
+	PatientID      int       `json:"patientID"`
+	ScheduleSlotID int       `json:"slotID"` // 0, если при переходе на слоты запись не сопоставилась со слотом
+	Date           time.Time `json:"date"`
+	Time           time.Time `json:"time"`
+	Status         string    `json:"status"`
+	Rating         float64   `json:"rating"`
 }
 
 type AppointmentDTO struct {
 	Id        int     `json:"appointmentID"`
 	DoctorID  int     `json:"doctorID"`
 	PatientID int     `json:"patientID"`
+	SlotID    int     `json:"slotID"`
 	Date      string  `json:"date"`
 	Time      string  `json:"time"`
 	Status    string  `json:"status"`
 	Rating    float64 `json:"rating"`
 }
+
+// AppointmentSummary - запись к врачу в списке вместе с данными врача
+type AppointmentSummary struct {
+	Id                   int
+	PatientID            int
+	DoctorID             int
+	DoctorFIO            string
+	DoctorSpecialization string
+	Date                 time.Time
+	Time                 time.Time
+	Status               string
+}
+
+type AppointmentSummaryDTO struct {
+	Id                   int    `json:"appointmentID"`
+	PatientID            int    `json:"patientID"`
+	DoctorID             int    `json:"doctorID"`
+	DoctorFIO            string `json:"doctorFIO"`
+	DoctorSpecialization string `json:"doctorSpecialization"`
+	Date                 string `json:"date"`
+	Time                 string `json:"time"`
+	Status               string `json:"status"`
+}
+
+// AppointmentCursor - позиция последней выданной записи, следующая страница начинается после нее.
+// Desc и Filter запоминают запрос, для которого выдан курсор: с другим запросом он неприменим
+type AppointmentCursor struct {
+	Date   time.Time
+	Time   time.Time
+	ID     int
+	Desc   bool
+	Filter string
+}
+
+// AppointmentFilter - условия выборки записей. Нулевые значения не фильтруют
+type AppointmentFilter struct {
+	PatientID        int
+	DoctorID         int
+	SpecializationID int
+	// Код статуса из status_appointment, например SCHEDULED
+	Status string
+	// Границы по дате приема включительно
+	From time.Time
+	To   time.Time
+	// Сначала поздние записи
+	Desc  bool
+	After *AppointmentCursor
+	Limit int
+}
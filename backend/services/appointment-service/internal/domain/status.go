@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Status - статус записи, id из таблицы status_appointment
+type Status int
+
+// статусы записи
+const (
+	SCHEDULED           Status = 1
+	COMPLETED           Status = 2
+	CANCELED_BY_PATIENT Status = 3
+	CONFIRMED           Status = 4
+	CHECKED_IN          Status = 5
+	NO_SHOW             Status = 6
+	CANCELED_BY_CLINIC  Status = 7
+)
+
+var ErrorIllegalTransition = errors.New("illegal appointment status transition")
+
+var statusCodes = map[Status]string{
+	SCHEDULED:           "SCHEDULED",
+	COMPLETED:           "COMPLETED",
+	CANCELED_BY_PATIENT: "CANCELED_BY_PATIENT",
+	CONFIRMED:           "CONFIRMED",
+	CHECKED_IN:          "CHECKED_IN",
+	NO_SHOW:             "NO_SHOW",
+	CANCELED_BY_CLINIC:  "CANCELED_BY_CLINIC",
+}
+
+// Допустимые переходы. Завершенная, неявка и отмененные записи конечны.
+// Неявку можно отметить и без подтверждения: пациент мог не ответить на него
+var transitions = map[Status][]Status{
+	SCHEDULED:  {CONFIRMED, NO_SHOW, CANCELED_BY_PATIENT, CANCELED_BY_CLINIC},
+	CONFIRMED:  {CHECKED_IN, NO_SHOW, CANCELED_BY_PATIENT, CANCELED_BY_CLINIC},
+	CHECKED_IN: {COMPLETED},
+}
+
+// Code возвращает код статуса из status_appointment
+func (s Status) Code() string {
+	if code, ok := statusCodes[s]; ok {
+		return code
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", int(s))
+}
+
+// ParseStatus находит статус по коду без учета регистра
+func ParseStatus(code string) (Status, bool) {
+	code = strings.ToUpper(code)
+	for status, statusCode := range statusCodes {
+		if statusCode == code {
+			return status, true
+		}
+	}
+	return 0, false
+}
+
+// Transition проверяет, что из статуса s можно перейти в to
+func (s Status) Transition(to Status) error {
+	for _, allowed := range transitions[s] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: from %s to %s", ErrorIllegalTransition, s.Code(), to.Code())
+}
+
+// Occupies сообщает, что запись в этом статусе занимает слот расписания
+func (s Status) Occupies() bool {
+	return s == SCHEDULED || s == CONFIRMED || s == CHECKED_IN
+}
+
+// Reschedulable сообщает, что запись можно перенести в другой слот
+func (s Status) Reschedulable() bool {
+	return s == SCHEDULED || s == CONFIRMED
+}
+
+// ActorDoctor - роль врача: врач меняет статус только записей к себе
+const ActorDoctor = "doctor"
+
+// Actor - кто меняет статус записи, для истории переходов
+type Actor struct {
+	Role string
+	ID   int
+}
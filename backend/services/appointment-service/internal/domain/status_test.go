@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+var allStatuses = []Status{SCHEDULED, CONFIRMED, CHECKED_IN, COMPLETED, NO_SHOW, CANCELED_BY_PATIENT, CANCELED_BY_CLINIC}
+
+func TestStatusTransition(t *testing.T) {
+	legal := map[Status]map[Status]bool{
+		SCHEDULED:  {CONFIRMED: true, NO_SHOW: true, CANCELED_BY_PATIENT: true, CANCELED_BY_CLINIC: true},
+		CONFIRMED:  {CHECKED_IN: true, NO_SHOW: true, CANCELED_BY_PATIENT: true, CANCELED_BY_CLINIC: true},
+		CHECKED_IN: {COMPLETED: true},
+	}
+
+	// Проверяются все пары статусов, включая переход в тот же статус
+	for _, from := range allStatuses {
+		for _, to := range allStatuses {
+			t.Run(from.Code()+"->"+to.Code(), func(t *testing.T) {
+				err := from.Transition(to)
+				if legal[from][to] {
+					if err != nil {
+						t.Fatalf("Transition() error = %v, want nil", err)
+					}
+					return
+				}
+				if !errors.Is(err, ErrorIllegalTransition) {
+					t.Fatalf("Transition() error = %v, want ErrorIllegalTransition", err)
+				}
+			})
+		}
+	}
+}
+
+func TestStatusTransitionUnknown(t *testing.T) {
+	if err := Status(42).Transition(SCHEDULED); !errors.Is(err, ErrorIllegalTransition) {
+		t.Fatalf("Transition() from unknown status error = %v, want ErrorIllegalTransition", err)
+	}
+	if err := SCHEDULED.Transition(Status(42)); !errors.Is(err, ErrorIllegalTransition) {
+		t.Fatalf("Transition() to unknown status error = %v, want ErrorIllegalTransition", err)
+	}
+}
+
+func TestStatusOccupiesAndReschedulable(t *testing.T) {
+	tests := []struct {
+		status        Status
+		occupies      bool
+		reschedulable bool
+	}{
+		{SCHEDULED, true, true},
+		{CONFIRMED, true, true},
+		{CHECKED_IN, true, false},
+		{COMPLETED, false, false},
+		{NO_SHOW, false, false},
+		{CANCELED_BY_PATIENT, false, false},
+		{CANCELED_BY_CLINIC, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status.Code(), func(t *testing.T) {
+			if got := tt.status.Occupies(); got != tt.occupies {
+				t.Errorf("Occupies() = %v, want %v", got, tt.occupies)
+			}
+			if got := tt.status.Reschedulable(); got != tt.reschedulable {
+				t.Errorf("Reschedulable() = %v, want %v", got, tt.reschedulable)
+			}
+		})
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	for _, status := range allStatuses {
+		got, ok := ParseStatus(status.Code())
+		if !ok || got != status {
+			t.Errorf("ParseStatus(%q) = %v, %v, want %v, true", status.Code(), got, ok, status)
+		}
+	}
+
+	if got, ok := ParseStatus("checked_in"); !ok || got != CHECKED_IN {
+		t.Errorf("ParseStatus is case sensitive: got %v, %v", got, ok)
+	}
+	for _, code := range []string{"", "CANCELED", "UNKNOWN"} {
+		if _, ok := ParseStatus(code); ok {
+			t.Errorf("ParseStatus(%q) ok = true, want false", code)
+		}
+	}
+}
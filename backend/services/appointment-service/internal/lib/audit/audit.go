@@ -0,0 +1,169 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Заголовки запроса, из которых берутся личность вызывающего и id запроса
+const (
+	HeaderRequestID = "X-Request-ID"
+	headerRole      = "X-Role"
+)
+
+var subjectHeaders = map[string]string{
+	"patient": "X-Patient-ID",
+	"admin":   "X-Admin-ID",
+	"doctor":  "X-Doctor-ID",
+}
+
+// Роль для действий без аутентифицированного вызывающего
+const RoleAnonymous = "anonymous"
+
+// Event - запись журнала аудита
+type Event struct {
+	ID         int64             `json:"id"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Service    string            `json:"service"`
+	ActorRole  string            `json:"actor_role"`
+	ActorID    int               `json:"actor_id,omitempty"`
+	Action     string            `json:"action"`
+	TargetType string            `json:"target_type"`
+	TargetID   string            `json:"target_id"`
+	Changes    map[string]Change `json:"changes,omitempty"`
+	RequestID  string            `json:"request_id,omitempty"`
+	IP         string            `json:"ip,omitempty"`
+}
+
+// Change - значение поля до и после действия
+type Change struct {
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+type Store interface {
+	SaveAuditEvent(context.Context, Event) error
+}
+
+type Recorder struct {
+	service        string
+	store          Store
+	logger         *slog.Logger
+	trustedProxies []netip.Prefix
+}
+
+// New создает журнал. trustedProxies - адреса api-gateway: X-Forwarded-For принимается только от них.
+func New(service string, store Store, logger *slog.Logger, trustedProxies []netip.Prefix) *Recorder {
+	return &Recorder{service: service, store: store, logger: logger, trustedProxies: trustedProxies}
+}
+
+// Record дополняет событие данными запроса и сохраняет его. Если вызывающий не указан явно,
+// он берется из заголовков, выставленных после проверки access токена.
+// Ошибка сохранения только логируется: действие уже выполнено.
+func (rec *Recorder) Record(r *http.Request, event Event) {
+	event.Service = rec.service
+	event.OccurredAt = time.Now()
+	event.RequestID = r.Header.Get(HeaderRequestID)
+	event.IP = rec.clientIP(r)
+	if event.ActorRole == "" {
+		event.ActorRole, event.ActorID = actor(r)
+	}
+
+	if err := rec.store.SaveAuditEvent(r.Context(), event); err != nil {
+		rec.logger.Error("Failed to save audit event",
+			slog.String("action", event.Action),
+			slog.String("target_type", event.TargetType),
+			slog.String("target_id", event.TargetID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// Diff возвращает поля, которые различаются в JSON-представлении before и after.
+// nil означает отсутствие объекта: до создания или после удаления.
+func Diff(before, after interface{}) map[string]Change {
+	beforeFields := fields(before)
+	afterFields := fields(after)
+
+	changes := make(map[string]Change)
+	for name, value := range beforeFields {
+		if other, ok := afterFields[name]; !ok || !bytes.Equal(value, other) {
+			changes[name] = Change{Before: value, After: nullIfEmpty(other)}
+		}
+	}
+	for name, value := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			changes[name] = Change{Before: json.RawMessage("null"), After: value}
+		}
+	}
+	return changes
+}
+
+func fields(value interface{}) map[string]json.RawMessage {
+	result := make(map[string]json.RawMessage)
+	if value == nil {
+		return result
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return result
+	}
+	_ = json.Unmarshal(data, &result)
+	return result
+}
+
+func nullIfEmpty(value json.RawMessage) json.RawMessage {
+	if value == nil {
+		return json.RawMessage("null")
+	}
+	return value
+}
+
+func actor(r *http.Request) (string, int) {
+	role := r.Header.Get(headerRole)
+	header, ok := subjectHeaders[role]
+	if !ok {
+		return RoleAnonymous, 0
+	}
+	id, _ := strconv.Atoi(r.Header.Get(header))
+	return role, id
+}
+
+// clientIP - адрес клиента. X-Forwarded-For учитывается, только если запрос пришел от
+// доверенного прокси: gateway дописывает адрес клиента последним значением.
+// Иначе клиент, обратившийся к сервису напрямую, подставил бы в журнал любой адрес.
+func (rec *Recorder) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" && rec.trustedProxy(host) {
+		parts := strings.Split(forwarded, ",")
+		return strings.TrimSpace(parts[len(parts)-1])
+	}
+	return host
+}
+
+func (rec *Recorder) trustedProxy(host string) bool {
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range rec.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	rec := New("test", nil, nil, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/24"),
+		netip.MustParsePrefix("::1/128"),
+	})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"direct request", "203.0.113.5:4000", "", "203.0.113.5"},
+		{"spoofed header from client", "203.0.113.5:4000", "198.51.100.1", "203.0.113.5"},
+		{"gateway", "10.0.0.2:4000", "198.51.100.1", "198.51.100.1"},
+		{"gateway appends to client header", "10.0.0.2:4000", "192.0.2.99, 198.51.100.1", "198.51.100.1"},
+		{"gateway over ipv6", "[::1]:4000", "198.51.100.1", "198.51.100.1"},
+		{"gateway as ipv4-mapped ipv6", "[::ffff:10.0.0.2]:4000", "198.51.100.1", "198.51.100.1"},
+		{"gateway without header", "10.0.0.2:4000", "", "10.0.0.2"},
+		{"outside trusted range", "10.0.1.2:4000", "198.51.100.1", "10.0.1.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := rec.clientIP(r); got != tt.want {
+				t.Fatalf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIPWithoutTrustedProxies(t *testing.T) {
+	rec := New("test", nil, nil, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "127.0.0.1:4000"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	if got := rec.clientIP(r); got != "127.0.0.1" {
+		t.Fatalf("clientIP() = %q, want 127.0.0.1", got)
+	}
+}
@@ -0,0 +1,32 @@
+package slogctx
+
+import (
+	"context"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/lib/requestid"
+	"log/slog"
+)
+
+// Handler добавляет к записи лога id запроса из контекста.
+// Работает только для вызовов с контекстом: logger.InfoContext(r.Context(), ...).
+type Handler struct {
+	slog.Handler
+}
+
+func NewHandler(next slog.Handler) *Handler {
+	return &Handler{Handler: next}
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if id := requestid.FromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{Handler: h.Handler.WithGroup(name)}
+}
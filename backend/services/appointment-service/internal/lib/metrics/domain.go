@@ -0,0 +1,37 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	AppointmentsCreated = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "appointments_created_total",
+		Help:      "Appointments booked.",
+	})
+
+	AppointmentsCancelled = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "appointments_cancelled_total",
+		Help:      "Appointments cancelled.",
+	})
+
+	AppointmentsRescheduled = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "appointments_rescheduled_total",
+		Help:      "Appointments moved to another slot.",
+	})
+
+	// Попытки записаться на уже занятый слот
+	BookingConflicts = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "booking_conflicts_total",
+		Help:      "Booking attempts rejected because the slot is already taken.",
+	})
+)
+
+// StatusTransitions - смены статуса записи по исходному и новому статусу
+var StatusTransitions = factory.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "appointment_status_transitions_total",
+	Help:      "Appointment status changes by source and target status.",
+}, []string{"from", "to"})
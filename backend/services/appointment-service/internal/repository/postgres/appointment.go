@@ -4,213 +4,195 @@ import (
 	"context"
 	"fmt"
 	"github.com/daariikk/MyHelp/services/appointment-service/internal/domain"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/lib/tracing"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/repository"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pkg/errors"
 	"log/slog"
-	"time"
 )
 
 type Storage struct {
-	connection *pgx.Conn
+	connection *pgxpool.Pool
 	logger     *slog.Logger
 }
 
 func New(ctx context.Context, logger *slog.Logger, url string) (*Storage, error) {
-	conn, err := pgx.Connect(ctx, url)
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		logger.Error("Failed to parse postgres connection string", "error", err)
+		return nil, errors.Wrap(err, "failed to parse postgres connection string")
+	}
+	// Каждый запрос попадает в трассу отдельным спаном
+	config.ConnConfig.Tracer = tracing.QueryTracer{}
+
+	conn, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		logger.Error("Failed to connect to postgres", "error", err)
 		return nil, errors.Wrapf(err, "failed to connect to postgres")
 	}
+	// Пул подключается лениво, без проверки сервис стартовал бы с недоступной БД
+	if err = conn.Ping(ctx); err != nil {
+		conn.Close()
+		logger.Error("Failed to ping postgres", "error", err)
+		return nil, errors.Wrap(err, "failed to ping postgres")
+	}
 
 	return &Storage{conn, logger}, nil
 }
 
 func (s *Storage) Close() error {
 	if s.connection != nil {
-		return s.connection.Close(context.Background())
+		s.connection.Close()
 	}
 	return nil
 }
 
-func (s *Storage) checkAvailableRecord(doctorID int, date time.Time, time time.Time) (bool, error) {
-	var isAvailable bool
+// Stat возвращает статистику пула соединений для метрик
+func (s *Storage) Stat() *pgxpool.Stat {
+	return s.connection.Stat()
+}
 
-	query := `
-	select is_available from doctor_schedules
-	where doctor_id = $1 and date = $2 and start_time = $3 
-`
-	err := s.connection.QueryRow(context.Background(), query,
-		doctorID,
-		date,
-		time).Scan(&isAvailable)
+func (s *Storage) Ping(ctx context.Context) error {
+	return s.connection.Ping(ctx)
+}
+
+// MigrationVersion возвращает версию схемы из таблицы schema_migrations, которую ведет migrate
+func (s *Storage) MigrationVersion(ctx context.Context) (int, bool, error) {
+	var (
+		version int
+		dirty   bool
+	)
+	err := s.connection.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
 	if err != nil {
-		s.logger.Error("impossible to check the availability of the record")
-		return false, err
+		return 0, false, errors.Wrap(err, "failed to get migration version")
 	}
-	return isAvailable, nil
+	return version, dirty, nil
 }
 
-func (s *Storage) NewAppointment(appointment domain.Appointment) error {
-	isAvailable, err := s.checkAvailableRecord(appointment.DoctorID, appointment.Date, appointment.Time)
+// NewAppointment записывает пациента в слот расписания и возвращает созданную запись.
+// Слот занимается условным UPDATE в той же транзакции, что и вставка записи, поэтому
+// из параллельных запросов на один слот успешно завершается только один
+func (s *Storage) NewAppointment(ctx context.Context, patientID, slotID int, actor domain.Actor) (*domain.Appointment, error) {
+	tx, err := s.connection.Begin(ctx)
 	if err != nil {
-		return err
+		s.logger.Error("Failed to begin transaction", "error", err)
+		return nil, errors.Wrap(err, "failed to begin transaction")
 	}
+	defer tx.Rollback(ctx)
 
-	if !isAvailable {
-		s.logger.Error(fmt.Sprintf("Recording for doctorID=%v date=%v and time=%v is not available because it is already busy", appointment.DoctorID, appointment.Date, appointment.Time))
-		return errors.New("Record is busy")
+	appointment := domain.Appointment{
+		PatientID:      patientID,
+		ScheduleSlotID: slotID,
 	}
 
-	tx, err := s.connection.Begin(context.Background())
+	// Строка слота блокируется до конца транзакции, конкурирующий UPDATE дождется ее
+	// и уже не увидит is_available = true
+	err = tx.QueryRow(ctx, `
+	UPDATE doctor_schedules
+	SET is_available = false
+	WHERE id = $1 AND is_available
+	RETURNING doctor_id, date, start_time
+`, slotID).Scan(&appointment.DoctorID, &appointment.Date, &appointment.Time)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, s.unavailableSlotError(ctx, tx, slotID)
+	}
 	if err != nil {
-		s.logger.Error("Failed to begin transaction", "error", err)
-		return err
+		s.logger.Error("Failed to reserve doctor schedule slot", "slotID", slotID, "error", err)
+		return nil, errors.Wrap(err, "failed to reserve doctor schedule slot")
 	}
-	defer func() {
-		if err != nil {
-			tx.Rollback(context.Background())
-		}
-	}()
 
-	query := `
-	INSERT INTO appointments (doctor_id, patient_id, date, time, status_id)
-	VALUES ($1, $2, $3, $4, $5)
+	err = tx.QueryRow(ctx, `
+	INSERT INTO appointments (doctor_id, patient_id, schedule_slot_id, date, time, status_id)
+	VALUES ($1, $2, $3, $4, $5, $6)
 	RETURNING id
-`
-	var appointmentID int
-	err = s.connection.QueryRow(context.Background(), query,
+`,
 		appointment.DoctorID,
 		appointment.PatientID,
+		appointment.ScheduleSlotID,
 		appointment.Date,
 		appointment.Time,
-		domain.SCHEDULED).Scan(&appointmentID)
-	if err != nil {
-		s.logger.Error(fmt.Sprintf("Error create appointment in database: doctorID=%v patientID=%v date=%v time=%v",
-			appointment.DoctorID,
-			appointment.PatientID,
-			appointment.Date,
-			appointment.Time))
-		return err
+		domain.SCHEDULED).Scan(&appointment.Id)
+	// Слот мог быть свободен при уже существующей активной записи, если расписание правили вручную
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, errors.Wrapf(repository.ErrorSlotBusy, "slotID=%v", slotID)
 	}
-	updateQuery := `
-        UPDATE doctor_schedules
-        SET is_available = false
-        WHERE doctor_id = $1 
-        AND date = $2 
-        AND start_time = $3 
-    `
-	_, err = tx.Exec(context.Background(), updateQuery,
-		appointment.DoctorID,
-		appointment.Date,
-		appointment.Time)
 	if err != nil {
-		s.logger.Error("Failed to update doctor schedule availability",
-			"doctorID", appointment.DoctorID,
-			"date", appointment.Date,
-			"time", appointment.Time,
-			"error", err)
-		return err
+		s.logger.Error(fmt.Sprintf("Error create appointment in database: slotID=%v patientID=%v", slotID, patientID))
+		return nil, errors.Wrap(err, "failed to create appointment")
+	}
+	appointment.Status = domain.SCHEDULED.Code()
+
+	if err = s.recordTransition(ctx, tx, appointment.Id, nil, domain.SCHEDULED, actor); err != nil {
+		return nil, err
 	}
 
 	// Завершаем транзакцию
-	err = tx.Commit(context.Background())
-	if err != nil {
+	if err = tx.Commit(ctx); err != nil {
 		s.logger.Error("Failed to commit transaction", "error", err)
-		return err
+		return nil, errors.Wrap(err, "failed to commit transaction")
 	}
 
-	s.logger.Info("New appointment", "id", appointmentID)
+	s.logger.Info("New appointment", "id", appointment.Id, "slotID", slotID)
 
-	return nil
+	return &appointment, nil
 }
 
-func (s *Storage) UpdateAppointment(appointment domain.Appointment) error {
-	query := `
-	UPDATE appointments
-	SET rating = $1
-	WHERE id = $2
-`
-
-	_, err := s.connection.Exec(context.Background(), query, appointment.Rating, appointment.Id)
+// unavailableSlotError различает отсутствующий и уже занятый слот
+func (s *Storage) unavailableSlotError(ctx context.Context, tx pgx.Tx, slotID int) error {
+	var exists bool
+	err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM doctor_schedules WHERE id = $1)`, slotID).Scan(&exists)
 	if err != nil {
-		s.logger.Error(fmt.Sprintf("Error update rating for appointment with id=%v", appointment.Id))
-		return err
+		s.logger.Error("impossible to check the availability of the record", "error", err)
+		return errors.Wrap(err, "failed to check doctor schedule slot")
 	}
 
-	return nil
-
+	if !exists {
+		return errors.Wrapf(repository.ErrorSlotNotFound, "slotID=%v", slotID)
+	}
+	return errors.Wrapf(repository.ErrorSlotBusy, "slotID=%v", slotID)
 }
 
-func (s *Storage) DeleteAppointment(appointmentID int) error {
+func (s *Storage) UpdateAppointment(ctx context.Context, appointment domain.Appointment) error {
 	query := `
 	UPDATE appointments
-	SET status_id = $2
-	WHERE id = $1
+	SET rating = $1
+	WHERE id = $2
 `
-	tx, err := s.connection.Begin(context.Background())
-	if err != nil {
-		s.logger.Error("Failed to begin transaction", "error", err)
-		return err
-	}
-	defer func() {
-		if err != nil {
-			tx.Rollback(context.Background())
-		}
-	}()
-	_, err = s.connection.Exec(context.Background(), query, appointmentID, domain.CANCELED)
-	if err != nil {
-		s.logger.Error(fmt.Sprintf("Error delete appointment with id=%v in database", appointmentID))
-		return err
-	}
-
-	appointment, err := s.GetAppointment(appointmentID)
-	if err != nil {
-		s.logger.Error(fmt.Sprintf("Error get appointment with id=%v in database", appointmentID))
-		return err
-	}
 
-	updateQuery := `
-        UPDATE doctor_schedules
-        SET is_available = true
-        WHERE doctor_id = $1 
-        AND date = $2 
-        AND start_time <= $3 
-    `
-	_, err = tx.Exec(context.Background(), updateQuery,
-		appointment.DoctorID,
-		appointment.Date,
-		appointment.Time)
+	_, err := s.connection.Exec(ctx, query, appointment.Rating, appointment.Id)
 	if err != nil {
-		s.logger.Error("Failed to update doctor schedule availability",
-			"doctorID", appointment.DoctorID,
-			"date", appointment.Date,
-			"time", appointment.Time,
-			"error", err)
+		s.logger.Error(fmt.Sprintf("Error update rating for appointment with id=%v", appointment.Id))
 		return err
 	}
 
-	// Завершаем транзакцию
-	err = tx.Commit(context.Background())
-	if err != nil {
-		s.logger.Error("Failed to commit transaction", "error", err)
-		return err
-	}
 	return nil
+
 }
 
-func (s *Storage) GetAppointment(appointmentID int) (*domain.Appointment, error) {
+func (s *Storage) GetAppointment(ctx context.Context, appointmentID int) (*domain.Appointment, error) {
 	var appointment domain.Appointment
 	query := `
-	select id, doctor_id, patient_id, date, time from appointments
-	where id = $1
+	select appointments.id, doctor_id, patient_id, coalesce(schedule_slot_id, 0), date, time, status_appointment.code, coalesce(rating, 0)
+	from appointments
+	join status_appointment on appointments.status_id = status_appointment.id
+	where appointments.id = $1
 `
-	err := s.connection.QueryRow(context.Background(), query, appointmentID).Scan(
+	err := s.connection.QueryRow(ctx, query, appointmentID).Scan(
 		&appointment.Id,
 		&appointment.DoctorID,
 		&appointment.PatientID,
+		&appointment.ScheduleSlotID,
 		&appointment.Date,
-		&appointment.Time)
+		&appointment.Time,
+		&appointment.Status,
+		&appointment.Rating)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.Wrapf(repository.ErrorAppointmentNotFound, "appointment with id %d", appointmentID)
+		}
 		s.logger.Error(fmt.Sprintf("Error get appointment with id=%v in database", appointmentID))
 		return nil, err
 	}
@@ -0,0 +1,191 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/domain"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/repository"
+	"github.com/pkg/errors"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestStorage подключается к БД из TEST_DATABASE_URL со схемой, накатанной всеми миграциями
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	storage, err := New(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)), url)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { storage.Close() })
+	return storage
+}
+
+// seedSlot создает врача со свободным слотом и patients пациентов; все удаляется после теста
+func seedSlot(t *testing.T, storage *Storage, patients int) (int, []int) {
+	t.Helper()
+	ctx := context.Background()
+	suffix := time.Now().UnixNano()
+
+	var specializationID, doctorID, slotID int
+	err := storage.connection.QueryRow(ctx, `
+	INSERT INTO specialization (specialization) VALUES ($1) RETURNING id
+`, fmt.Sprintf("test-%d", suffix)).Scan(&specializationID)
+	if err != nil {
+		t.Fatalf("insert specialization error = %v", err)
+	}
+	// Врачи, слоты и записи удаляются каскадно вместе со специализацией
+	t.Cleanup(func() { storage.connection.Exec(ctx, `DELETE FROM specialization WHERE id = $1`, specializationID) })
+
+	err = storage.connection.QueryRow(ctx, `
+	INSERT INTO doctors (surname, name, patronymic, specialization_id) VALUES ('Test', 'Test', 'Test', $1) RETURNING id
+`, specializationID).Scan(&doctorID)
+	if err != nil {
+		t.Fatalf("insert doctor error = %v", err)
+	}
+
+	err = storage.connection.QueryRow(ctx, `
+	INSERT INTO doctor_schedules (doctor_id, date, start_time, end_time, is_available)
+	VALUES ($1, '2099-01-01', '09:00', '09:30', true)
+	RETURNING id
+`, doctorID).Scan(&slotID)
+	if err != nil {
+		t.Fatalf("insert slot error = %v", err)
+	}
+
+	patientIDs := make([]int, 0, patients)
+	for i := 0; i < patients; i++ {
+		var patientID int
+		err = storage.connection.QueryRow(ctx, `
+	INSERT INTO patients (email, polic, password) VALUES ($1, $1, 'test') RETURNING id
+`, fmt.Sprintf("test-%d-%d@example.com", suffix, i)).Scan(&patientID)
+		if err != nil {
+			t.Fatalf("insert patient error = %v", err)
+		}
+		patientIDs = append(patientIDs, patientID)
+	}
+	t.Cleanup(func() { storage.connection.Exec(ctx, `DELETE FROM patients WHERE id = ANY($1)`, patientIDs) })
+
+	return slotID, patientIDs
+}
+
+func TestNewAppointmentConcurrentBooking(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	const requests = 20
+	slotID, patientIDs := seedSlot(t, storage, requests)
+
+	var (
+		wg       sync.WaitGroup
+		start    = make(chan struct{})
+		mu       sync.Mutex
+		booked   []int
+		busy     int
+		failures []error
+	)
+	for _, patientID := range patientIDs {
+		wg.Add(1)
+		go func(patientID int) {
+			defer wg.Done()
+			<-start
+
+			appointment, err := storage.NewAppointment(ctx, patientID, slotID, domain.Actor{Role: "patient", ID: patientID})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				booked = append(booked, appointment.Id)
+			case errors.Is(err, repository.ErrorSlotBusy):
+				busy++
+			default:
+				failures = append(failures, err)
+			}
+		}(patientID)
+	}
+	close(start)
+	wg.Wait()
+
+	if len(failures) > 0 {
+		t.Fatalf("unexpected errors: %v", failures)
+	}
+	if len(booked) != 1 || busy != requests-1 {
+		t.Fatalf("booked = %d, busy = %d; want 1 and %d", len(booked), busy, requests-1)
+	}
+
+	var available bool
+	if err := storage.connection.QueryRow(ctx, `SELECT is_available FROM doctor_schedules WHERE id = $1`, slotID).Scan(&available); err != nil {
+		t.Fatalf("query slot error = %v", err)
+	}
+	if available {
+		t.Fatal("slot is_available = true after booking")
+	}
+
+	var active, transitions int
+	err := storage.connection.QueryRow(ctx, `
+	SELECT count(*) FROM appointments WHERE schedule_slot_id = $1 AND status_id IN (1, 4, 5)
+`, slotID).Scan(&active)
+	if err != nil {
+		t.Fatalf("count appointments error = %v", err)
+	}
+	if active != 1 {
+		t.Fatalf("active appointments for slot = %d, want 1", active)
+	}
+
+	// Отклоненные попытки не оставляют записей в истории статусов
+	err = storage.connection.QueryRow(ctx, `
+	SELECT count(*) FROM appointment_status_transitions WHERE appointment_id = $1
+`, booked[0]).Scan(&transitions)
+	if err != nil {
+		t.Fatalf("count transitions error = %v", err)
+	}
+	if transitions != 1 {
+		t.Fatalf("transitions = %d, want 1", transitions)
+	}
+}
+
+func TestNewAppointmentUnknownSlot(t *testing.T) {
+	storage := newTestStorage(t)
+	_, patientIDs := seedSlot(t, storage, 1)
+
+	_, err := storage.NewAppointment(context.Background(), patientIDs[0], -1, domain.Actor{Role: "patient", ID: patientIDs[0]})
+	if !errors.Is(err, repository.ErrorSlotNotFound) {
+		t.Fatalf("NewAppointment() error = %v, want ErrorSlotNotFound", err)
+	}
+}
+
+func TestTransitionAppointmentDoctor(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+	slotID, patientIDs := seedSlot(t, storage, 1)
+
+	appointment, err := storage.NewAppointment(ctx, patientIDs[0], slotID, domain.Actor{Role: "patient", ID: patientIDs[0]})
+	if err != nil {
+		t.Fatalf("NewAppointment() error = %v", err)
+	}
+
+	other := domain.Actor{Role: domain.ActorDoctor, ID: appointment.DoctorID + 1}
+	if _, err = storage.TransitionAppointment(ctx, appointment.Id, domain.CONFIRMED, other); !errors.Is(err, repository.ErrorNotAppointmentDoctor) {
+		t.Fatalf("TransitionAppointment() by other doctor error = %v, want ErrorNotAppointmentDoctor", err)
+	}
+
+	own := domain.Actor{Role: domain.ActorDoctor, ID: appointment.DoctorID}
+	from, err := storage.TransitionAppointment(ctx, appointment.Id, domain.CONFIRMED, own)
+	if err != nil {
+		t.Fatalf("TransitionAppointment() by own doctor error = %v", err)
+	}
+	if from != domain.SCHEDULED {
+		t.Fatalf("from = %s, want %s", from.Code(), domain.SCHEDULED.Code())
+	}
+}
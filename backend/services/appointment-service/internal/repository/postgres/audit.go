@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/lib/audit"
+	"github.com/pkg/errors"
+)
+
+func (s *Storage) SaveAuditEvent(ctx context.Context, event audit.Event) error {
+	var changes []byte
+	if len(event.Changes) > 0 {
+		var err error
+		if changes, err = json.Marshal(event.Changes); err != nil {
+			return errors.Wrap(err, "failed to marshal audit changes")
+		}
+	}
+
+	var actorID *int
+	if event.ActorID != 0 {
+		actorID = &event.ActorID
+	}
+
+	query := `
+	INSERT INTO audit_events (occurred_at, service, actor_role, actor_id, action, target_type, target_id, changes, request_id, ip)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, nullif($9, ''), nullif($10, ''))
+`
+	_, err := s.connection.Exec(ctx, query,
+		event.OccurredAt,
+		event.Service,
+		event.ActorRole,
+		actorID,
+		event.Action,
+		event.TargetType,
+		event.TargetID,
+		changes,
+		event.RequestID,
+		event.IP,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to save audit event")
+	}
+
+	return nil
+}
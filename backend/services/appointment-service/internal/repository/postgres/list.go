@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/domain"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// Условие выборки записей: пустые параметры не фильтруют
+const appointmentFilterCondition = `
+	WHERE ($1 = 0 OR appointments.patient_id = $1)
+	  AND ($2 = 0 OR appointments.doctor_id = $2)
+	  AND ($3 = 0 OR doctors.specialization_id = $3)
+	  AND ($4 = '' OR status_appointment.code = $4)
+	  AND ($5::date IS NULL OR appointments.date >= $5)
+	  AND ($6::date IS NULL OR appointments.date <= $6)
+`
+
+// Курсор сравнивается с ключом сортировки целиком, поэтому записи с одинаковыми датой и временем не теряются
+const (
+	appointmentAfterAsc = `
+	  AND ($7::date IS NULL OR (appointments.date, appointments.time, appointments.id) > ($7, $8::time, $9))
+	ORDER BY appointments.date, appointments.time, appointments.id
+`
+	appointmentAfterDesc = `
+	  AND ($7::date IS NULL OR (appointments.date, appointments.time, appointments.id) < ($7, $8::time, $9))
+	ORDER BY appointments.date DESC, appointments.time DESC, appointments.id DESC
+`
+)
+
+// ListAppointments возвращает до filter.Limit записей после курсора filter.After
+func (s *Storage) ListAppointments(ctx context.Context, filter domain.AppointmentFilter) ([]domain.AppointmentSummary, error) {
+	query := `
+	SELECT appointments.id,
+	       appointments.patient_id,
+	       appointments.doctor_id,
+	       CONCAT(doctors.surname, ' ', doctors.name, ' ', doctors.patronymic),
+	       specialization.specialization_doctor,
+	       appointments.date,
+	       appointments.time,
+	       status_appointment.code
+	FROM appointments
+	JOIN doctors ON appointments.doctor_id = doctors.id
+	JOIN specialization ON doctors.specialization_id = specialization.id
+	JOIN status_appointment ON appointments.status_id = status_appointment.id` + appointmentFilterCondition
+	if filter.Desc {
+		query += appointmentAfterDesc
+	} else {
+		query += appointmentAfterAsc
+	}
+	query += `	LIMIT $10`
+
+	var (
+		afterDate *time.Time
+		afterTime *time.Time
+		afterID   int
+	)
+	if filter.After != nil {
+		afterDate, afterTime, afterID = &filter.After.Date, &filter.After.Time, filter.After.ID
+	}
+
+	rows, err := s.connection.Query(ctx, query,
+		filter.PatientID,
+		filter.DoctorID,
+		filter.SpecializationID,
+		filter.Status,
+		nullableTime(filter.From),
+		nullableTime(filter.To),
+		afterDate,
+		afterTime,
+		afterID,
+		filter.Limit,
+	)
+	if err != nil {
+		s.logger.Error("Failed to list appointments", "error", err)
+		return nil, errors.Wrap(err, "failed to list appointments")
+	}
+	defer rows.Close()
+
+	appointments := make([]domain.AppointmentSummary, 0)
+	for rows.Next() {
+		var appointment domain.AppointmentSummary
+		err = rows.Scan(
+			&appointment.Id,
+			&appointment.PatientID,
+			&appointment.DoctorID,
+			&appointment.DoctorFIO,
+			&appointment.DoctorSpecialization,
+			&appointment.Date,
+			&appointment.Time,
+			&appointment.Status,
+		)
+		if err != nil {
+			s.logger.Error("Failed to scan appointment", "error", err)
+			return nil, errors.Wrap(err, "failed to scan appointment")
+		}
+		appointments = append(appointments, appointment)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Error("Error iterating appointments", "error", err)
+		return nil, errors.Wrap(err, "error iterating appointments")
+	}
+
+	return appointments, nil
+}
+
+func nullableTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
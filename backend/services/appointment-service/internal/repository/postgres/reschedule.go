@@ -0,0 +1,178 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/domain"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+)
+
+// RescheduleAppointment переносит запланированную запись в другой слот того же врача или
+// другого врача той же специализации. Новый слот занимается, старый освобождается, а перенос
+// попадает в историю в одной транзакции, поэтому при ошибке запись остается в прежнем слоте.
+// Подтвержденная запись после переноса снова ждет подтверждения
+func (s *Storage) RescheduleAppointment(ctx context.Context, appointmentID, slotID int, actor domain.Actor) (*domain.Appointment, error) {
+	tx, err := s.connection.Begin(ctx)
+	if err != nil {
+		s.logger.Error("Failed to begin transaction", "error", err)
+		return nil, errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		appointment      domain.Appointment
+		status           domain.Status
+		oldSlotID        *int
+		specializationID int
+	)
+	err = tx.QueryRow(ctx, `
+	SELECT appointments.id,
+	       appointments.patient_id,
+	       appointments.doctor_id,
+	       appointments.schedule_slot_id,
+	       appointments.date,
+	       appointments.time,
+	       appointments.status_id,
+	       coalesce(appointments.rating, 0),
+	       doctors.specialization_id
+	FROM appointments
+	JOIN doctors ON appointments.doctor_id = doctors.id
+	WHERE appointments.id = $1
+	FOR UPDATE OF appointments
+`, appointmentID).Scan(
+		&appointment.Id,
+		&appointment.PatientID,
+		&appointment.DoctorID,
+		&oldSlotID,
+		&appointment.Date,
+		&appointment.Time,
+		&status,
+		&appointment.Rating,
+		&specializationID,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errors.Wrapf(repository.ErrorAppointmentNotFound, "appointment with id %d", appointmentID)
+	}
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("Error get appointment with id=%v in database", appointmentID))
+		return nil, errors.Wrap(err, "failed to get appointment")
+	}
+
+	if !status.Reschedulable() {
+		return nil, errors.Wrapf(domain.ErrorIllegalTransition, "appointment with id %d is %s and cannot be rescheduled", appointmentID, status.Code())
+	}
+	if oldSlotID != nil && *oldSlotID == slotID {
+		return nil, errors.Wrapf(repository.ErrorSlotNotAllowed, "appointment with id %d already uses slotID=%v", appointmentID, slotID)
+	}
+
+	from := appointment
+
+	// Новый слот занимается только при всех условиях сразу, иначе причину отказа выясняет rescheduleSlotError
+	err = tx.QueryRow(ctx, `
+	UPDATE doctor_schedules
+	SET is_available = false
+	FROM doctors
+	WHERE doctor_schedules.id = $1
+	  AND doctor_schedules.is_available
+	  AND doctors.id = doctor_schedules.doctor_id
+	  AND (doctor_schedules.doctor_id = $2 OR doctors.specialization_id = $3)
+	  AND doctor_schedules.date + doctor_schedules.start_time > LOCALTIMESTAMP
+	RETURNING doctor_schedules.doctor_id, doctor_schedules.date, doctor_schedules.start_time
+`, slotID, appointment.DoctorID, specializationID).Scan(&appointment.DoctorID, &appointment.Date, &appointment.Time)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, s.rescheduleSlotError(ctx, tx, slotID, from.DoctorID, specializationID)
+	}
+	if err != nil {
+		s.logger.Error("Failed to reserve doctor schedule slot", "slotID", slotID, "error", err)
+		return nil, errors.Wrap(err, "failed to reserve doctor schedule slot")
+	}
+	appointment.ScheduleSlotID = slotID
+
+	_, err = tx.Exec(ctx, `
+	UPDATE appointments
+	SET schedule_slot_id = $2, doctor_id = $3, date = $4, time = $5, status_id = $6
+	WHERE id = $1
+`, appointment.Id, appointment.ScheduleSlotID, appointment.DoctorID, appointment.Date, appointment.Time, domain.SCHEDULED)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, errors.Wrapf(repository.ErrorSlotBusy, "slotID=%v", slotID)
+	}
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("Error move appointment with id=%v to slotID=%v", appointmentID, slotID), "error", err)
+		return nil, errors.Wrap(err, "failed to move appointment")
+	}
+
+	if oldSlotID != nil {
+		_, err = tx.Exec(ctx, `
+		UPDATE doctor_schedules
+		SET is_available = true
+		WHERE id = $1
+`, *oldSlotID)
+		if err != nil {
+			s.logger.Error("Failed to update doctor schedule availability", "slotID", *oldSlotID, "error", err)
+			return nil, errors.Wrap(err, "failed to release doctor schedule slot")
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+	INSERT INTO appointment_reschedules (appointment_id, from_slot_id, from_doctor_id, from_date, from_time, to_slot_id)
+	VALUES ($1, $2, $3, $4, $5, $6)
+`, appointment.Id, oldSlotID, from.DoctorID, from.Date, from.Time, slotID)
+	if err != nil {
+		s.logger.Error("Failed to save appointment reschedule", "appointmentID", appointmentID, "error", err)
+		return nil, errors.Wrap(err, "failed to save appointment reschedule")
+	}
+
+	if status != domain.SCHEDULED {
+		if err = s.recordTransition(ctx, tx, appointment.Id, &status, domain.SCHEDULED, actor); err != nil {
+			return nil, err
+		}
+	}
+	appointment.Status = domain.SCHEDULED.Code()
+
+	// Завершаем транзакцию
+	if err = tx.Commit(ctx); err != nil {
+		s.logger.Error("Failed to commit transaction", "error", err)
+		return nil, errors.Wrap(err, "failed to commit transaction")
+	}
+
+	s.logger.Info("Appointment rescheduled", "id", appointment.Id, "fromSlotID", oldSlotID, "toSlotID", slotID)
+
+	return &appointment, nil
+}
+
+// rescheduleSlotError объясняет, почему слот нельзя занять при переносе
+func (s *Storage) rescheduleSlotError(ctx context.Context, tx pgx.Tx, slotID, doctorID, specializationID int) error {
+	var (
+		isAvailable bool
+		sameDoctor  bool
+		inFuture    bool
+	)
+	err := tx.QueryRow(ctx, `
+	SELECT doctor_schedules.is_available,
+	       doctor_schedules.doctor_id = $2 OR doctors.specialization_id = $3,
+	       doctor_schedules.date + doctor_schedules.start_time > LOCALTIMESTAMP
+	FROM doctor_schedules
+	JOIN doctors ON doctor_schedules.doctor_id = doctors.id
+	WHERE doctor_schedules.id = $1
+`, slotID, doctorID, specializationID).Scan(&isAvailable, &sameDoctor, &inFuture)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errors.Wrapf(repository.ErrorSlotNotFound, "slotID=%v", slotID)
+	}
+	if err != nil {
+		s.logger.Error("impossible to check the availability of the record", "error", err)
+		return errors.Wrap(err, "failed to check doctor schedule slot")
+	}
+
+	switch {
+	case !sameDoctor:
+		return errors.Wrapf(repository.ErrorSlotNotAllowed, "slotID=%v belongs to another doctor and specialization", slotID)
+	case !inFuture:
+		return errors.Wrapf(repository.ErrorSlotNotAllowed, "slotID=%v is in the past", slotID)
+	default:
+		return errors.Wrapf(repository.ErrorSlotBusy, "slotID=%v", slotID)
+	}
+}
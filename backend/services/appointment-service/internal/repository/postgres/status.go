@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/domain"
+	"github.com/daariikk/MyHelp/services/appointment-service/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+)
+
+// TransitionAppointment переводит запись в статус to, если это допускает domain.Status.Transition,
+// и возвращает прежний статус. Запись, переставшая занимать слот, освобождает его.
+// Врач меняет статус только записи к себе: проверка идет под той же блокировкой строки
+func (s *Storage) TransitionAppointment(ctx context.Context, appointmentID int, to domain.Status, actor domain.Actor) (domain.Status, error) {
+	tx, err := s.connection.Begin(ctx)
+	if err != nil {
+		s.logger.Error("Failed to begin transaction", "error", err)
+		return 0, errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		from     domain.Status
+		slotID   *int
+		doctorID int
+	)
+	err = tx.QueryRow(ctx, `
+	SELECT status_id, schedule_slot_id, coalesce(doctor_id, 0) FROM appointments
+	WHERE id = $1
+	FOR UPDATE
+`, appointmentID).Scan(&from, &slotID, &doctorID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, errors.Wrapf(repository.ErrorAppointmentNotFound, "appointment with id %d", appointmentID)
+	}
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("Error get appointment with id=%v in database", appointmentID))
+		return 0, errors.Wrap(err, "failed to get appointment")
+	}
+
+	if actor.Role == domain.ActorDoctor && actor.ID != doctorID {
+		return from, errors.Wrapf(repository.ErrorNotAppointmentDoctor, "appointment with id %d", appointmentID)
+	}
+
+	if err = from.Transition(to); err != nil {
+		return from, errors.Wrapf(err, "appointment with id %d", appointmentID)
+	}
+
+	_, err = tx.Exec(ctx, `
+	UPDATE appointments
+	SET status_id = $2
+	WHERE id = $1
+`, appointmentID, to)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("Error update status of appointment with id=%v in database", appointmentID), "error", err)
+		return from, errors.Wrap(err, "failed to update appointment status")
+	}
+
+	// Слот освобождается только при выходе из занимающего статуса, иначе его мог уже занять другой пациент
+	if from.Occupies() && !to.Occupies() && slotID != nil {
+		_, err = tx.Exec(ctx, `
+		UPDATE doctor_schedules
+		SET is_available = true
+		WHERE id = $1
+`, *slotID)
+		if err != nil {
+			s.logger.Error("Failed to update doctor schedule availability", "slotID", *slotID, "error", err)
+			return from, errors.Wrap(err, "failed to release doctor schedule slot")
+		}
+	}
+
+	if err = s.recordTransition(ctx, tx, appointmentID, &from, to, actor); err != nil {
+		return from, err
+	}
+
+	// Завершаем транзакцию
+	if err = tx.Commit(ctx); err != nil {
+		s.logger.Error("Failed to commit transaction", "error", err)
+		return from, errors.Wrap(err, "failed to commit transaction")
+	}
+
+	s.logger.Info("Appointment status changed", "id", appointmentID, "from", from.Code(), "to", to.Code())
+
+	return from, nil
+}
+
+// recordTransition сохраняет смену статуса в историю. from = nil означает создание записи
+func (s *Storage) recordTransition(ctx context.Context, tx pgx.Tx, appointmentID int, from *domain.Status, to domain.Status, actor domain.Actor) error {
+	var actorID *int
+	if actor.ID != 0 {
+		actorID = &actor.ID
+	}
+
+	_, err := tx.Exec(ctx, `
+	INSERT INTO appointment_status_transitions (appointment_id, from_status_id, to_status_id, actor_role, actor_id)
+	VALUES ($1, $2, $3, $4, $5)
+`, appointmentID, from, to, actor.Role, actorID)
+	if err != nil {
+		s.logger.Error("Failed to save appointment status transition", "appointmentID", appointmentID, "error", err)
+		return errors.Wrap(err, "failed to save appointment status transition")
+	}
+
+	return nil
+}
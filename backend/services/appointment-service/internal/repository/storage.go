@@ -5,4 +5,10 @@ import "errors"
 var (
 	ErrorAlreadyExists = errors.New("patient with this data already exists")
 	ErrorNotFound      = errors.New("patient is not found")
+
+	ErrorAppointmentNotFound  = errors.New("appointment is not found")
+	ErrorSlotNotFound         = errors.New("schedule slot is not found")
+	ErrorSlotBusy             = errors.New("schedule slot is already booked")
+	ErrorSlotNotAllowed       = errors.New("schedule slot is not allowed")
+	ErrorNotAppointmentDoctor = errors.New("appointment belongs to another doctor")
 )
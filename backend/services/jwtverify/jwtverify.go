@@ -0,0 +1,251 @@
+package jwtverify
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Проверка access токенов api-gateway без обращения к нему на каждый запрос.
+// Открытые ключи берутся из JWKS gateway и кешируются. Пакет общий для всех сервисов за gateway.
+
+const typeAccess = "at+jwt"
+
+const requestIDHeader = "X-Request-ID"
+
+// Не чаще этого интервала набор ключей перечитывается из-за неизвестного kid
+const minRefreshInterval = 30 * time.Second
+
+var ErrInvalidToken = errors.New("invalid token")
+
+type Options struct {
+	JWKSURL  string
+	Issuer   string
+	Audience string
+	// Допустимое расхождение часов при проверке exp, nbf и iat
+	ClockSkew time.Duration
+	// Как часто перечитывать набор ключей
+	RefreshInterval time.Duration
+	HTTPClient      *http.Client
+	// Возвращает id текущего запроса, он передается gateway при загрузке JWKS
+	RequestID func(context.Context) string
+}
+
+// Claims - личность вызывающего из проверенного токена
+type Claims struct {
+	Role      string
+	SubjectID int
+	ExpiresAt time.Time
+}
+
+type Verifier struct {
+	opts Options
+
+	mu          sync.RWMutex
+	keys        map[string]publicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+type publicKey struct {
+	algorithm string
+	key       crypto.PublicKey
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role"`
+}
+
+func New(opts Options) (*Verifier, error) {
+	if opts.JWKSURL == "" {
+		return nil, errors.New("jwks url is required")
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 5 * time.Second}
+	}
+	if opts.RefreshInterval <= 0 {
+		opts.RefreshInterval = 10 * time.Minute
+	}
+
+	return &Verifier{opts: opts, keys: map[string]publicKey{}}, nil
+}
+
+// Verify проверяет подпись и claims access токена
+func (v *Verifier) Verify(ctx context.Context, tokenString string) (Claims, error) {
+	var c claims
+	_, err := jwt.ParseWithClaims(tokenString, &c, func(token *jwt.Token) (interface{}, error) {
+		if token.Header["typ"] != typeAccess {
+			return nil, fmt.Errorf("unexpected token type %v", token.Header["typ"])
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		key, err := v.key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != key.algorithm {
+			return nil, fmt.Errorf("alg %s does not match key %q", token.Method.Alg(), kid)
+		}
+		return key.key, nil
+	},
+		jwt.WithValidMethods([]string{"RS256", "EdDSA"}),
+		jwt.WithIssuer(v.opts.Issuer),
+		jwt.WithAudience(v.opts.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(v.opts.ClockSkew),
+	)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	// subject имеет вид "patient:5", роль дублируется в claim role
+	role, id, found := strings.Cut(c.Subject, ":")
+	subjectID, err := strconv.Atoi(id)
+	if !found || err != nil || subjectID <= 0 {
+		return Claims{}, fmt.Errorf("%w: invalid sub", ErrInvalidToken)
+	}
+	if c.Role != role {
+		return Claims{}, fmt.Errorf("%w: role claim does not match subject", ErrInvalidToken)
+	}
+
+	return Claims{Role: role, SubjectID: subjectID, ExpiresAt: c.ExpiresAt.Time}, nil
+}
+
+func (v *Verifier) key(ctx context.Context, kid string) (publicKey, error) {
+	v.mu.RLock()
+	key, ok := v.keys[kid]
+	stale := time.Since(v.fetchedAt) > v.opts.RefreshInterval
+	v.mu.RUnlock()
+
+	if ok && !stale {
+		return key, nil
+	}
+
+	if err := v.refresh(ctx); err != nil && !ok {
+		return publicKey{}, err
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if key, ok = v.keys[kid]; !ok {
+		return publicKey{}, fmt.Errorf("unknown kid %q", kid)
+	}
+	return key, nil
+}
+
+// refresh перечитывает JWKS. При недоступности gateway продолжают работать ранее загруженные ключи.
+func (v *Verifier) refresh(ctx context.Context) error {
+	v.mu.Lock()
+	if time.Since(v.attemptedAt) < minRefreshInterval {
+		v.mu.Unlock()
+		return errors.New("jwks refresh throttled")
+	}
+	v.attemptedAt = time.Now()
+	v.mu.Unlock()
+
+	keys, err := v.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	v.mu.Unlock()
+
+	return nil
+}
+
+type jwk struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+}
+
+func (v *Verifier) fetch(ctx context.Context) (map[string]publicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.opts.JWKSURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if v.opts.RequestID != nil {
+		if id := v.opts.RequestID(ctx); id != "" {
+			req.Header.Set(requestIDHeader, id)
+		}
+	}
+
+	resp, err := v.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch jwks: status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode jwks: %w", err)
+	}
+
+	keys := make(map[string]publicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := parseJWK(k)
+		if err != nil {
+			return nil, err
+		}
+		keys[k.KeyID] = key
+	}
+
+	return keys, nil
+}
+
+func parseJWK(k jwk) (publicKey, error) {
+	switch {
+	case k.KeyType == "RSA" && k.Algorithm == "RS256":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return publicKey{}, fmt.Errorf("key %q: invalid n: %w", k.KeyID, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return publicKey{}, fmt.Errorf("key %q: invalid e: %w", k.KeyID, err)
+		}
+		return publicKey{algorithm: k.Algorithm, key: &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}}, nil
+	case k.KeyType == "OKP" && k.Curve == "Ed25519" && k.Algorithm == "EdDSA":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return publicKey{}, fmt.Errorf("key %q: invalid x", k.KeyID)
+		}
+		return publicKey{algorithm: k.Algorithm, key: ed25519.PublicKey(x)}, nil
+	default:
+		return publicKey{}, fmt.Errorf("key %q: unsupported key type %s/%s", k.KeyID, k.KeyType, k.Algorithm)
+	}
+}
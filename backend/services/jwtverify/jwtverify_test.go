@@ -0,0 +1,135 @@
+package jwtverify
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type testKey struct {
+	kid     string
+	private ed25519.PrivateKey
+}
+
+func newTestKey(t *testing.T, kid string) testKey {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	return testKey{kid: kid, private: private}
+}
+
+// newJWKSServer отдает открытый ключ key и запоминает X-Request-ID последнего запроса
+func newJWKSServer(t *testing.T, key testKey, requestID *string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requestID = r.Header.Get(requestIDHeader)
+		json.NewEncoder(w).Encode(map[string][]jwk{"keys": {{
+			KeyType:   "OKP",
+			KeyID:     key.kid,
+			Use:       "sig",
+			Algorithm: "EdDSA",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(key.private.Public().(ed25519.PublicKey)),
+		}}})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func sign(t *testing.T, key testKey, edit func(*claims)) string {
+	t.Helper()
+	now := time.Now()
+	c := claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "myhelp-api-gateway",
+			Audience:  jwt.ClaimStrings{"myhelp"},
+			Subject:   "doctor:12",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+		Role: "doctor",
+	}
+	if edit != nil {
+		edit(&c)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, c)
+	token.Header["kid"] = key.kid
+	token.Header["typ"] = typeAccess
+	value, err := token.SignedString(key.private)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	return value
+}
+
+func newTestVerifier(t *testing.T, url string) *Verifier {
+	t.Helper()
+	verifier, err := New(Options{
+		JWKSURL:   url,
+		Issuer:    "myhelp-api-gateway",
+		Audience:  "myhelp",
+		RequestID: func(context.Context) string { return "req-1" },
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return verifier
+}
+
+func TestVerify(t *testing.T) {
+	key := newTestKey(t, "k1")
+	var requestID string
+	verifier := newTestVerifier(t, newJWKSServer(t, key, &requestID).URL)
+
+	got, err := verifier.Verify(context.Background(), sign(t, key, nil))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if got.Role != "doctor" || got.SubjectID != 12 {
+		t.Fatalf("Verify() = %+v, want doctor 12", got)
+	}
+	if requestID != "req-1" {
+		t.Fatalf("X-Request-ID = %q, want req-1", requestID)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	key := newTestKey(t, "k1")
+	var requestID string
+	verifier := newTestVerifier(t, newJWKSServer(t, key, &requestID).URL)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"wrong audience", sign(t, key, func(c *claims) { c.Audience = jwt.ClaimStrings{"other"} })},
+		{"expired", sign(t, key, func(c *claims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) })},
+		{"role differs from sub", sign(t, key, func(c *claims) { c.Role = "admin" })},
+		{"sub without id", sign(t, key, func(c *claims) { c.Subject = "doctor" })},
+		{"key outside jwks", sign(t, newTestKey(t, "k1"), nil)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := verifier.Verify(context.Background(), tt.token); !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("Verify() error = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestNewRequiresJWKSURL(t *testing.T) {
+	if _, err := New(Options{}); err == nil {
+		t.Fatal("New() error = nil, want error for empty jwks url")
+	}
+}
@@ -0,0 +1,3 @@
+-- Refresh токены, выданные раньше tokens_revoked_at, считаются отозванными
+ALTER TABLE patients ADD COLUMN tokens_revoked_at TIMESTAMPTZ;
+ALTER TABLE admins ADD COLUMN tokens_revoked_at TIMESTAMPTZ;
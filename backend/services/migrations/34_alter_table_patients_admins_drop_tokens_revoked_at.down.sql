@@ -0,0 +1,2 @@
+ALTER TABLE patients DROP COLUMN tokens_revoked_at;
+ALTER TABLE admins DROP COLUMN tokens_revoked_at;
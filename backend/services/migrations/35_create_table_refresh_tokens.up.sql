@@ -0,0 +1,19 @@
+-- Таблица refresh_tokens (хранится только хеш токена)
+-- family_id объединяет цепочку ротаций одного входа (сессию)
+CREATE TABLE refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    jti VARCHAR(64) UNIQUE NOT NULL,
+    family_id VARCHAR(64) NOT NULL,
+    subject_role VARCHAR(16) NOT NULL,
+    subject_id INT NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    user_agent VARCHAR(512),
+    ip VARCHAR(64),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    replaced_by VARCHAR(64)
+);
+
+CREATE INDEX refresh_tokens_subject_idx ON refresh_tokens (subject_role, subject_id);
+CREATE INDEX refresh_tokens_family_idx ON refresh_tokens (family_id);
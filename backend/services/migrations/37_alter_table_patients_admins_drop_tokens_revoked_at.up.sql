@@ -0,0 +1,3 @@
+-- Отзыв refresh токенов теперь выполняется через таблицу refresh_tokens
+ALTER TABLE patients DROP COLUMN tokens_revoked_at;
+ALTER TABLE admins DROP COLUMN tokens_revoked_at;
@@ -0,0 +1,2 @@
+ALTER TABLE patients ADD COLUMN tokens_revoked_at TIMESTAMPTZ;
+ALTER TABLE admins ADD COLUMN tokens_revoked_at TIMESTAMPTZ;
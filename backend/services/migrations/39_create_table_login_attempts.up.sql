@@ -0,0 +1,8 @@
+-- Таблица login_attempts (счетчики неудачных попыток входа)
+-- key имеет вид "patient:<email>", "admin:<email>" или "ip:<адрес>"
+CREATE TABLE login_attempts (
+    key VARCHAR(320) PRIMARY KEY,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    locked_until TIMESTAMPTZ
+);
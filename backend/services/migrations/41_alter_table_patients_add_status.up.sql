@@ -0,0 +1,7 @@
+-- Статус пациента: pending - email не подтвержден, active - подтвержден.
+-- Уже существующие пациенты считаются подтвержденными, новые создаются в pending.
+ALTER TABLE patients ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('pending', 'active'));
+ALTER TABLE patients ADD COLUMN email_verified_at TIMESTAMPTZ;
+UPDATE patients SET email_verified_at = now();
+ALTER TABLE patients ALTER COLUMN status SET DEFAULT 'pending';
@@ -0,0 +1,2 @@
+ALTER TABLE patients DROP COLUMN email_verified_at;
+ALTER TABLE patients DROP COLUMN status;
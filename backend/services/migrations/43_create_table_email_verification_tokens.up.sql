@@ -0,0 +1,10 @@
+-- Таблица email_verification_tokens (хранится только хеш токена)
+CREATE TABLE email_verification_tokens (
+    id SERIAL PRIMARY KEY,
+    patient_id INT NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE CASCADE
+);
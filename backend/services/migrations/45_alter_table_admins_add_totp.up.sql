@@ -0,0 +1,6 @@
+-- Двухфакторная аутентификация администраторов (TOTP).
+-- totp_secret заполняется при подключении, totp_enabled - после подтверждения первым кодом.
+-- totp_last_counter - шаг последнего принятого кода, защищает от повторного использования кода.
+ALTER TABLE admins ADD COLUMN totp_secret VARCHAR(64);
+ALTER TABLE admins ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE admins ADD COLUMN totp_last_counter BIGINT;
@@ -0,0 +1,3 @@
+ALTER TABLE admins DROP COLUMN totp_last_counter;
+ALTER TABLE admins DROP COLUMN totp_enabled;
+ALTER TABLE admins DROP COLUMN totp_secret;
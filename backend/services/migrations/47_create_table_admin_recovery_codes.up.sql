@@ -0,0 +1,11 @@
+-- Таблица admin_recovery_codes (резервные коды 2FA, хранится только хеш)
+CREATE TABLE admin_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    admin_id INT NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    FOREIGN KEY (admin_id) REFERENCES admins(id) ON DELETE CASCADE
+);
+
+CREATE INDEX admin_recovery_codes_admin_idx ON admin_recovery_codes (admin_id);
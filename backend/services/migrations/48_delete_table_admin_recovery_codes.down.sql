@@ -0,0 +1 @@
+DROP TABLE admin_recovery_codes;
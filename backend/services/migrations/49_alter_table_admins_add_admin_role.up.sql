@@ -0,0 +1,4 @@
+-- Роль администратора: super_admin управляет администраторами, clinic_admin - данными поликлиники
+ALTER TABLE admins ADD COLUMN admin_role VARCHAR(16) NOT NULL DEFAULT 'clinic_admin'
+    CHECK (admin_role IN ('super_admin', 'clinic_admin'));
+UPDATE admins SET admin_role = 'super_admin' WHERE username = 'superadmin';
@@ -0,0 +1 @@
+ALTER TABLE admins DROP COLUMN admin_role;
@@ -0,0 +1,10 @@
+-- Таблица admin_invites (приглашения администраторов, хранится только хеш токена)
+CREATE TABLE admin_invites (
+    id SERIAL PRIMARY KEY,
+    admin_id INT NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    FOREIGN KEY (admin_id) REFERENCES admins(id) ON DELETE CASCADE
+);
@@ -0,0 +1,37 @@
+-- Таблица audit_events (журнал аудита, только добавление).
+-- changes - изменившиеся поля в виде {"поле": {"before": ..., "after": ...}}.
+CREATE TABLE audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    service VARCHAR(32) NOT NULL,
+    actor_role VARCHAR(16) NOT NULL,
+    actor_id INT,
+    action VARCHAR(64) NOT NULL,
+    target_type VARCHAR(32) NOT NULL,
+    target_id VARCHAR(64) NOT NULL,
+    changes JSONB,
+    request_id VARCHAR(64),
+    ip VARCHAR(64)
+);
+
+CREATE INDEX audit_events_occurred_at_idx ON audit_events (occurred_at);
+CREATE INDEX audit_events_actor_idx ON audit_events (actor_role, actor_id);
+CREATE INDEX audit_events_target_idx ON audit_events (target_type, target_id);
+
+-- Записи нельзя изменять. Удалять можно только задаче очистки, которая включает audit.purge в своей транзакции.
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' AND current_setting('audit.purge', true) = 'on' THEN
+        RETURN OLD;
+    END IF;
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER audit_events_no_truncate
+    BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
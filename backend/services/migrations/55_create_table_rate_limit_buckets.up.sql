@@ -0,0 +1,10 @@
+-- Таблица rate_limit_buckets (общие ведра token bucket для нескольких экземпляров gateway).
+-- allowed - результат последнего списания токена.
+CREATE UNLOGGED TABLE rate_limit_buckets (
+    key VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX rate_limit_buckets_updated_at_idx ON rate_limit_buckets (updated_at);
@@ -0,0 +1,4 @@
+-- На один слот врача допускается только одна активная (status_id = 1, запланирована) запись.
+-- Отмененные и завершенные записи слот не занимают.
+CREATE UNIQUE INDEX appointments_active_slot_idx ON appointments (doctor_id, date, time)
+    WHERE status_id = 1;
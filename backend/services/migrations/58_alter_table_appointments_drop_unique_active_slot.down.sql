@@ -0,0 +1 @@
+DROP INDEX appointments_active_slot_idx;
@@ -0,0 +1,15 @@
+-- Запись ссылается на конкретный слот расписания вместо сопоставления по дате и времени.
+-- doctor_id, date и time остаются копией данных слота для выборок по записям.
+ALTER TABLE appointments ADD COLUMN schedule_slot_id INT REFERENCES doctor_schedules(id);
+
+UPDATE appointments
+SET schedule_slot_id = doctor_schedules.id
+FROM doctor_schedules
+WHERE doctor_schedules.doctor_id = appointments.doctor_id
+  AND doctor_schedules.date = appointments.date
+  AND doctor_schedules.start_time = appointments.time;
+
+-- Уникальность активной записи теперь обеспечивается по слоту
+DROP INDEX appointments_active_slot_idx;
+CREATE UNIQUE INDEX appointments_active_schedule_slot_idx ON appointments (schedule_slot_id)
+    WHERE status_id = 1;
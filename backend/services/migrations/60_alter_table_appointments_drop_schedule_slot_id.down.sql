@@ -0,0 +1,4 @@
+DROP INDEX appointments_active_schedule_slot_idx;
+CREATE UNIQUE INDEX appointments_active_slot_idx ON appointments (doctor_id, date, time)
+    WHERE status_id = 1;
+ALTER TABLE appointments DROP COLUMN schedule_slot_id;
@@ -0,0 +1,15 @@
+-- Таблица appointment_reschedules (история переносов записей).
+-- from_slot_id пуст у записей, не сопоставленных со слотом при переходе на слоты,
+-- поэтому прежние врач, дата и время хранятся отдельно.
+CREATE TABLE appointment_reschedules (
+    id SERIAL PRIMARY KEY,
+    appointment_id INT NOT NULL REFERENCES appointments(id) ON DELETE CASCADE,
+    from_slot_id INT REFERENCES doctor_schedules(id),
+    from_doctor_id INT NOT NULL,
+    from_date DATE NOT NULL,
+    from_time TIME NOT NULL,
+    to_slot_id INT NOT NULL REFERENCES doctor_schedules(id),
+    rescheduled_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX appointment_reschedules_appointment_idx ON appointment_reschedules (appointment_id);
@@ -0,0 +1,28 @@
+-- Статусы записи для явного жизненного цикла: подтверждение, приход пациента, неявка
+-- и отмена с указанием стороны. Прежний CANCELED (id 3) становится отменой пациентом:
+-- до этого записи отменяли через DELETE, который вызывали в основном пациенты.
+UPDATE status_appointment SET code = 'CANCELED_BY_PATIENT', description = 'Отменен пациентом' WHERE id = 3;
+INSERT INTO status_appointment (id, code, description) VALUES
+(4, 'CONFIRMED', 'Подтвержден'),
+(5, 'CHECKED_IN', 'Пациент пришел'),
+(6, 'NO_SHOW', 'Неявка'),
+(7, 'CANCELED_BY_CLINIC', 'Отменен клиникой');
+
+-- Слот занимают запланированные, подтвержденные и начатые приемы
+DROP INDEX appointments_active_schedule_slot_idx;
+CREATE UNIQUE INDEX appointments_active_schedule_slot_idx ON appointments (schedule_slot_id)
+    WHERE status_id IN (1, 4, 5);
+
+-- Таблица appointment_status_transitions (история смены статусов записи).
+-- from_status_id пуст у перехода, которым запись создана.
+CREATE TABLE appointment_status_transitions (
+    id SERIAL PRIMARY KEY,
+    appointment_id INT NOT NULL REFERENCES appointments(id) ON DELETE CASCADE,
+    from_status_id INT REFERENCES status_appointment(id),
+    to_status_id INT NOT NULL REFERENCES status_appointment(id),
+    actor_role VARCHAR(16) NOT NULL,
+    actor_id INT,
+    transitioned_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX appointment_status_transitions_appointment_idx ON appointment_status_transitions (appointment_id);
@@ -0,0 +1,13 @@
+DROP TABLE appointment_status_transitions;
+
+-- Новые статусы сводятся к ближайшим прежним
+UPDATE appointments SET status_id = 1 WHERE status_id IN (4, 5);
+UPDATE appointments SET status_id = 2 WHERE status_id = 6;
+UPDATE appointments SET status_id = 3 WHERE status_id = 7;
+
+DROP INDEX appointments_active_schedule_slot_idx;
+CREATE UNIQUE INDEX appointments_active_schedule_slot_idx ON appointments (schedule_slot_id)
+    WHERE status_id = 1;
+
+DELETE FROM status_appointment WHERE id IN (4, 5, 6, 7);
+UPDATE status_appointment SET code = 'CANCELED', description = 'Отменен' WHERE id = 3;
@@ -0,0 +1,4 @@
+-- Учетные данные врача для входа в систему. Врач без email и пароля войти не может,
+-- учетные данные задает администратор.
+ALTER TABLE doctors ADD COLUMN email VARCHAR(255) UNIQUE;
+ALTER TABLE doctors ADD COLUMN password TEXT;
@@ -0,0 +1,2 @@
+ALTER TABLE doctors DROP COLUMN password;
+ALTER TABLE doctors DROP COLUMN email;
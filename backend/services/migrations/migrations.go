@@ -0,0 +1,26 @@
+// Package migrations встраивает SQL-миграции, чтобы сервисы могли сверить с ними версию схемы БД
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// Latest возвращает номер последней миграции наката (NN_*.up.sql)
+func Latest() int {
+	names, _ := fs.Glob(FS, "*.up.sql")
+
+	latest := 0
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+		if version, err := strconv.Atoi(prefix); err == nil {
+			latest = max(latest, version)
+		}
+	}
+	return latest
+}
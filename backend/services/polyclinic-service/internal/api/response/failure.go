@@ -14,9 +14,6 @@ type FailureResponse struct {
 
 func SendFailureResponse(w http.ResponseWriter, message string, statusCode int) {
 	w.Header().Set("Content-Type", ContentTypeJSON)
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(FailureResponse{
 		Status:  "failure",
@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/daariikk/MyHelp/services/polyclinic-service/internal/api/response"
 	"github.com/daariikk/MyHelp/services/polyclinic-service/internal/domain"
+	"github.com/daariikk/MyHelp/services/polyclinic-service/internal/lib/audit"
+	"github.com/daariikk/MyHelp/services/polyclinic-service/internal/lib/metrics"
 	"github.com/daariikk/MyHelp/services/polyclinic-service/internal/use_cases"
 	"github.com/go-chi/chi/v5"
 	"log/slog"
@@ -14,105 +17,128 @@ import (
 )
 
 type ControlDoctorsWrapper interface {
-	NewDoctor(domain.Doctor) (domain.Doctor, error)
-	DeleteDoctor(int) (bool, error)
-	GetDoctorById(int) (domain.Doctor, error)
-	GetScheduleForDoctor(int, time.Time) ([]domain.Record, error)
-	CreateNewScheduleForDoctor(int, []domain.Record) error
+	NewDoctor(context.Context, domain.Doctor) (domain.Doctor, error)
+	DeleteDoctor(context.Context, int) (bool, error)
+	GetDoctorById(context.Context, int) (domain.Doctor, error)
+	GetScheduleForDoctor(context.Context, int, time.Time) ([]domain.Record, error)
+	CreateNewScheduleForDoctor(context.Context, int, []domain.Record) error
 }
 
-func NewDoctorHandler(logger *slog.Logger, wrapper ControlDoctorsWrapper) func(http.ResponseWriter, *http.Request) {
+func NewDoctorHandler(logger *slog.Logger, wrapper ControlDoctorsWrapper, recorder *audit.Recorder) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Info("NewDoctorHandler starting...")
+		logger.InfoContext(r.Context(), "NewDoctorHandler starting...")
 
 		var newDoctor domain.Doctor
 		err := json.NewDecoder(r.Body).Decode(&newDoctor)
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error parse body: %e", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error parse body: %e", err))
 			response.SendFailureResponse(w, "Error parse body", http.StatusBadRequest)
 			return
 		}
 
-		logger.Debug("newDoctor", newDoctor)
+		logger.DebugContext(r.Context(), "newDoctor", "newDoctor", newDoctor)
 
-		doctor, err := wrapper.NewDoctor(newDoctor)
+		doctor, err := wrapper.NewDoctor(r.Context(), newDoctor)
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error create doctor: %e", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error create doctor: %e", err))
 			response.SendFailureResponse(w, "Error create doctor", http.StatusInternalServerError)
 			return
 		}
 
-		logger.Debug("New doctor: ", "doctor", doctor)
-		logger.Info("NewDoctorHandler works successful")
+		recorder.Record(r, audit.Event{
+			Action:     "doctor.create",
+			TargetType: "doctor",
+			TargetID:   strconv.Itoa(doctor.Id),
+			Changes:    audit.Diff(nil, doctor),
+		})
+
+		logger.DebugContext(r.Context(), "New doctor: ", "doctor", doctor)
+		logger.InfoContext(r.Context(), "NewDoctorHandler works successful")
 
 		response.SendSuccessResponse(w, doctor, http.StatusOK)
 	}
 }
 
-func DeleteDoctorHandler(logger *slog.Logger, wrapper ControlDoctorsWrapper) func(http.ResponseWriter, *http.Request) {
+func DeleteDoctorHandler(logger *slog.Logger, wrapper ControlDoctorsWrapper, recorder *audit.Recorder) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Info("DeleteDoctorHandler starting...")
+		logger.InfoContext(r.Context(), "DeleteDoctorHandler starting...")
 
 		doctorIDStr := chi.URLParam(r, "doctorID")
 		doctorID, err := strconv.ParseInt(doctorIDStr, 10, 64)
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error parse doctorID: %e", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error parse doctorID: %e", err))
 			response.SendFailureResponse(w, "Error parse doctorID", http.StatusBadRequest)
+			return
+		}
+
+		// Данные врача до удаления нужны для журнала аудита
+		var before interface{}
+		if doctor, err := wrapper.GetDoctorById(r.Context(), int(doctorID)); err == nil {
+			before = doctor
 		}
 
-		isDeleted, err := wrapper.DeleteDoctor(int(doctorID))
+		isDeleted, err := wrapper.DeleteDoctor(r.Context(), int(doctorID))
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error delete doctor: %e", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error delete doctor: %e", err))
 			response.SendFailureResponse(w, "Error delete doctor", http.StatusInternalServerError)
+			return
 		}
 
 		if !isDeleted {
-			logger.Info(fmt.Sprintf("Delete doctor successfully, but doctor with doctorID=%v not found", doctorID))
+			logger.InfoContext(r.Context(), fmt.Sprintf("Delete doctor successfully, but doctor with doctorID=%v not found", doctorID))
 			response.SendSuccessResponse(w, fmt.Sprintf("Delete doctor successfully, but doctor with doctorID=%v", doctorID), http.StatusOK)
+			return
 		}
 
-		logger.Info("DeleteDoctorHandler works successful")
+		recorder.Record(r, audit.Event{
+			Action:     "doctor.delete",
+			TargetType: "doctor",
+			TargetID:   strconv.Itoa(int(doctorID)),
+			Changes:    audit.Diff(before, nil),
+		})
+
+		logger.InfoContext(r.Context(), "DeleteDoctorHandler works successful")
 		response.SendSuccessResponse(w, "Delete doctor successfully", http.StatusOK)
 	}
 }
 
 func GetScheduleDoctorByIdHandler(logger *slog.Logger, wrapper ControlDoctorsWrapper) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Info("GetScheduleDoctorByIdHandler starting...")
+		logger.InfoContext(r.Context(), "GetScheduleDoctorByIdHandler starting...")
 
 		doctorIDStr := chi.URLParam(r, "doctorID")
 		doctorID, err := strconv.ParseInt(doctorIDStr, 10, 64)
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error parse doctorID: %e", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error parse doctorID: %e", err))
 			response.SendFailureResponse(w, "Error parse doctorID", http.StatusBadRequest)
 			return
 		}
 		dateStr := r.URL.Query().Get("date")
 		if dateStr == "" {
-			logger.Error("Date parameter is required")
+			logger.ErrorContext(r.Context(), "Date parameter is required")
 			response.SendFailureResponse(w, "Date parameter is required", http.StatusBadRequest)
 			return
 		}
 
 		date, err := time.Parse("2006-01-02", dateStr) // Формат даты: ГГГГ-ММ-ДД
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error parsing date: %v", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error parsing date: %v", err))
 			response.SendFailureResponse(w, "Invalid date format. Expected format: YYYY-MM-DD", http.StatusBadRequest)
 			return
 		}
 
-		logger.Info(fmt.Sprintf("Parsed date: %v", date))
+		logger.InfoContext(r.Context(), fmt.Sprintf("Parsed date: %v", date))
 
-		doctor, err := wrapper.GetDoctorById(int(doctorID))
+		doctor, err := wrapper.GetDoctorById(r.Context(), int(doctorID))
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error get doctor: %e", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error get doctor: %e", err))
 			response.SendFailureResponse(w, "Error get doctor", http.StatusInternalServerError)
 			return
 		}
 
-		schedule, err := wrapper.GetScheduleForDoctor(doctor.Id, date)
+		schedule, err := wrapper.GetScheduleForDoctor(r.Context(), doctor.Id, date)
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error get doctor schedule: %e", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error get doctor schedule: %e", err))
 			response.SendFailureResponse(w, "Error get doctor schedule", http.StatusInternalServerError)
 			return
 		}
@@ -127,55 +153,76 @@ func GetScheduleDoctorByIdHandler(logger *slog.Logger, wrapper ControlDoctorsWra
 	}
 }
 
-func NewScheduleHandler(logger *slog.Logger, wrapperDB ControlDoctorsWrapper, wrapper use_cases.NewScheduleWrapper) func(http.ResponseWriter, *http.Request) {
+func NewScheduleHandler(logger *slog.Logger, wrapperDB ControlDoctorsWrapper, wrapper use_cases.NewScheduleWrapper, recorder *audit.Recorder) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Info("NewScheduleHandler starting...")
-		logger.Debug("URL", r.URL)
+		logger.InfoContext(r.Context(), "NewScheduleHandler starting...")
+		logger.DebugContext(r.Context(), "URL", "url", r.URL)
 		doctorIDStr := chi.URLParam(r, "doctorID")
 		doctorID, err := strconv.ParseInt(doctorIDStr, 10, 64)
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error parse doctorID: %e", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error parse doctorID: %e", err))
 			response.SendFailureResponse(w, "Error parse doctorID", http.StatusBadRequest)
+			return
 		}
 
 		dateStr := r.URL.Query().Get("date")
 		date, err := time.Parse("2006-01-02", dateStr)
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error parse date: %e", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error parse date: %e", err))
 			response.SendFailureResponse(w, "Error parse date", http.StatusBadRequest)
+			return
 		}
 		startTimeStr := r.URL.Query().Get("start_time")
 		startTime, err := time.Parse("15:04:05", startTimeStr)
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error parse start_time: %e", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error parse start_time: %e", err))
 			response.SendFailureResponse(w, "Error parse start_time", http.StatusBadRequest)
+			return
 		}
 
 		endTimeStr := r.URL.Query().Get("end_time")
 		endTime, err := time.Parse("15:04:05", endTimeStr)
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error parse end_time: %e", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error parse end_time: %e", err))
 			response.SendFailureResponse(w, "Error parse end_time", http.StatusBadRequest)
+			return
 		}
 
 		receptionTimeStr := r.URL.Query().Get("reception_time")
 		receptionTime, err := strconv.ParseInt(receptionTimeStr, 10, 64)
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error parse reception_time: %e", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error parse reception_time: %e", err))
 			response.SendFailureResponse(w, "Error parse reception_time", http.StatusBadRequest)
+			return
 		}
 
 		newSchedule, err := wrapper.CreateScheduleForDoctorById(int(doctorID), date, startTime, endTime, int(receptionTime))
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error create doctor schedule: %e", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error create doctor schedule: %e", err))
 			response.SendFailureResponse(w, "Error create doctor schedule", http.StatusInternalServerError)
+			return
 		}
 
-		err = wrapperDB.CreateNewScheduleForDoctor(int(doctorID), newSchedule.Records)
+		err = wrapperDB.CreateNewScheduleForDoctor(r.Context(), int(doctorID), newSchedule.Records)
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error create doctor schedule: %e", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error create doctor schedule: %e", err))
 			response.SendFailureResponse(w, "Error create doctor schedule", http.StatusInternalServerError)
+			return
 		}
+		metrics.ScheduleSlotsPublished.Add(float64(len(newSchedule.Records)))
+
+		recorder.Record(r, audit.Event{
+			Action:     "schedule.create",
+			TargetType: "doctor",
+			TargetID:   strconv.Itoa(int(doctorID)),
+			Changes: audit.Diff(nil, map[string]interface{}{
+				"date":           dateStr,
+				"start_time":     startTimeStr,
+				"end_time":       endTimeStr,
+				"reception_time": receptionTime,
+				"records":        len(newSchedule.Records),
+			}),
+		})
 
 		response.SendSuccessResponse(w, newSchedule, http.StatusOK)
 	}
@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/daariikk/MyHelp/services/polyclinic-service/internal/api/response"
 	"github.com/daariikk/MyHelp/services/polyclinic-service/internal/domain"
+	"github.com/daariikk/MyHelp/services/polyclinic-service/internal/lib/audit"
 	"github.com/go-chi/chi/v5"
 	"log/slog"
 	"net/http"
@@ -12,24 +14,24 @@ import (
 )
 
 type SpecializationWrapper interface {
-	GetAllSpecializations() ([]domain.Specialization, error)
-	GetSpecializationAllDoctor(int) ([]domain.Doctor, error)
-	CreateNewSpecialization(specialization domain.Specialization) (int, error)
-	DeleteSpecialization(int) (bool, error)
+	GetAllSpecializations(context.Context) ([]domain.Specialization, error)
+	GetSpecializationAllDoctor(context.Context, int) ([]domain.Doctor, error)
+	CreateNewSpecialization(ctx context.Context, specialization domain.Specialization) (int, error)
+	DeleteSpecialization(context.Context, int) (bool, error)
 }
 
 func GetPolyclinicInfoHandler(logger *slog.Logger, wrapper SpecializationWrapper) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Debug("GetPolyclinicInfoHandler starting...")
-		schedule, err := wrapper.GetAllSpecializations()
+		logger.DebugContext(r.Context(), "GetPolyclinicInfoHandler starting...")
+		schedule, err := wrapper.GetAllSpecializations(r.Context())
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error get specialization: %s", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error get specialization: %s", err))
 			response.SendFailureResponse(w, "Error get specialization", http.StatusInternalServerError)
 			return
 		}
 
-		logger.Debug("Schedule: ", "schedule", schedule)
-		logger.Info("GetPolyclinicInfoHandler works successful")
+		logger.DebugContext(r.Context(), "Schedule: ", "schedule", schedule)
+		logger.InfoContext(r.Context(), "GetPolyclinicInfoHandler works successful")
 
 		response.SendSuccessResponse(w, schedule, http.StatusOK)
 	}
@@ -37,75 +39,88 @@ func GetPolyclinicInfoHandler(logger *slog.Logger, wrapper SpecializationWrapper
 
 func GetSpecializationDoctorHandler(logger *slog.Logger, wrapper SpecializationWrapper) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Debug("GetSpecializationDoctorHandler starting...")
+		logger.DebugContext(r.Context(), "GetSpecializationDoctorHandler starting...")
 		specializationIDStr := chi.URLParam(r, "specializationID")
 		specializationID, err := strconv.ParseInt(specializationIDStr, 10, 64)
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error parse specializationID: %s", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error parse specializationID: %s", err))
 			response.SendFailureResponse(w, "Error parse specializationID", http.StatusBadRequest)
 			return
 		}
 
-		doctors, err := wrapper.GetSpecializationAllDoctor(int(specializationID))
+		doctors, err := wrapper.GetSpecializationAllDoctor(r.Context(), int(specializationID))
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error get list doctors for specialization with specializationID=%v: %s", specializationID, err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error get list doctors for specialization with specializationID=%v: %s", specializationID, err))
 			response.SendFailureResponse(w, "Error get list doctors", http.StatusInternalServerError)
 			return
 		}
 
-		logger.Debug("doctors list", "doctors", doctors)
-		logger.Info("GetPolyclinicInfoHandler works successful")
+		logger.DebugContext(r.Context(), "doctors list", "doctors", doctors)
+		logger.InfoContext(r.Context(), "GetPolyclinicInfoHandler works successful")
 		response.SendSuccessResponse(w, doctors, http.StatusOK)
 	}
 }
 
-func CreateNewSpecializationHandler(logger *slog.Logger, wrapper SpecializationWrapper) func(http.ResponseWriter, *http.Request) {
+func CreateNewSpecializationHandler(logger *slog.Logger, wrapper SpecializationWrapper, recorder *audit.Recorder) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Info("CreateNewSpecializationHandler starting...")
+		logger.InfoContext(r.Context(), "CreateNewSpecializationHandler starting...")
 
 		var newSpecialization domain.Specialization
 		err := json.NewDecoder(r.Body).Decode(&newSpecialization)
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error parse body: %s", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error parse body: %s", err))
 			response.SendFailureResponse(w, "Error parse body", http.StatusBadRequest)
 			return
 		}
-		logger.Debug("newSpecialization", "newSpecialization", newSpecialization)
+		logger.DebugContext(r.Context(), "newSpecialization", "newSpecialization", newSpecialization)
 
-		specializationID, err := wrapper.CreateNewSpecialization(newSpecialization)
+		specializationID, err := wrapper.CreateNewSpecialization(r.Context(), newSpecialization)
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error create specialization: %s", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error create specialization: %s", err))
 			response.SendFailureResponse(w, "Error create specialization", http.StatusInternalServerError)
 			return
 		}
-		logger.Debug("specializationID", "specializationID", specializationID)
+		logger.DebugContext(r.Context(), "specializationID", "specializationID", specializationID)
 
-		logger.Info("CreateNewSpecializationHandler works successful")
+		newSpecialization.ID = specializationID
+		recorder.Record(r, audit.Event{
+			Action:     "specialization.create",
+			TargetType: "specialization",
+			TargetID:   strconv.Itoa(specializationID),
+			Changes:    audit.Diff(nil, newSpecialization),
+		})
+
+		logger.InfoContext(r.Context(), "CreateNewSpecializationHandler works successful")
 		response.SendSuccessResponse(w, specializationID, http.StatusCreated)
 	}
 }
 
-func DeleteSpecializationHandler(logger *slog.Logger, wrapper SpecializationWrapper) func(http.ResponseWriter, *http.Request) {
+func DeleteSpecializationHandler(logger *slog.Logger, wrapper SpecializationWrapper, recorder *audit.Recorder) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Debug("DeleteSpecializationHandler starting...")
+		logger.DebugContext(r.Context(), "DeleteSpecializationHandler starting...")
 
 		specializationIDStr := chi.URLParam(r, "specializationID")
 		specializationID, err := strconv.ParseInt(specializationIDStr, 10, 64)
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error parse specializationID: %s", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error parse specializationID: %s", err))
 			response.SendFailureResponse(w, "Error parse specializationID", http.StatusBadRequest)
 			return
 		}
 
-		logger.Debug("specializationID", "specializationID", specializationID)
-		isDeleted, err := wrapper.DeleteSpecialization(int(specializationID))
+		logger.DebugContext(r.Context(), "specializationID", "specializationID", specializationID)
+		isDeleted, err := wrapper.DeleteSpecialization(r.Context(), int(specializationID))
 		if err != nil {
-			logger.Error(fmt.Sprintf("Error delete specialization: %s", err))
+			logger.ErrorContext(r.Context(), fmt.Sprintf("Error delete specialization: %s", err))
 			response.SendFailureResponse(w, "Error delete specialization", http.StatusInternalServerError)
 			return
 		}
 
 		if isDeleted {
+			recorder.Record(r, audit.Event{
+				Action:     "specialization.delete",
+				TargetType: "specialization",
+				TargetID:   strconv.Itoa(int(specializationID)),
+			})
 			response.SendSuccessResponse(w, "Deleted specialization", http.StatusNoContent)
 		} else {
 			response.SendSuccessResponse(w, fmt.Sprintf("Not found specialization with specializationID=%v", specializationID), http.StatusNotFound)
@@ -3,46 +3,67 @@ package api
 import (
 	"github.com/daariikk/MyHelp/services/polyclinic-service/internal/api/rest/handlers"
 	"github.com/daariikk/MyHelp/services/polyclinic-service/internal/config"
+	"github.com/daariikk/MyHelp/services/polyclinic-service/internal/lib/audit"
+	"github.com/daariikk/MyHelp/services/polyclinic-service/internal/lib/health"
+	"github.com/daariikk/MyHelp/services/jwtverify"
+	"github.com/daariikk/MyHelp/services/polyclinic-service/internal/lib/metrics"
+	"github.com/daariikk/MyHelp/services/polyclinic-service/internal/lib/requestid"
+	"github.com/daariikk/MyHelp/services/polyclinic-service/internal/lib/tracing"
 	"github.com/daariikk/MyHelp/services/polyclinic-service/internal/repository/postgres"
 	"github.com/daariikk/MyHelp/services/polyclinic-service/internal/use_cases"
 	"github.com/go-chi/chi/v5"
 	"log/slog"
 )
 
-func NewRouter(cfg *config.Config, logger *slog.Logger, storage *postgres.Storage, scheduleUseCase use_cases.NewScheduleWrapper) *chi.Mux {
+// Имя сервиса в журнале аудита
+const auditService = "polyclinic-service"
+
+func NewRouter(cfg *config.Config, logger *slog.Logger, storage *postgres.Storage, checker *health.Checker, scheduleUseCase use_cases.NewScheduleWrapper, verifier *jwtverify.Verifier) *chi.Mux {
 	router := chi.NewRouter()
-	router.Use(handlers.CorsMiddleware)
+	router.Use(requestid.Middleware)
+	router.Use(tracing.Middleware)
+	router.Use(metrics.Middleware)
 
-	router.Route("/MyHelp/specializations", func(r chi.Router) {
-		// Получить список специализаций
-		r.Get("/", handlers.GetPolyclinicInfoHandler(logger, storage))
+	// Пробы оркестратора проходят без токена
+	router.Get("/healthz", checker.LivenessHandler())
+	router.Get("/readyz", checker.ReadinessHandler())
 
-		// Получить список врачей определенной специализации
-		r.Get("/{specializationID}", handlers.GetSpecializationDoctorHandler(logger, storage))
+	router.Group(func(r chi.Router) {
+		r.Use(handlers.AuthMiddleware(logger, verifier))
 
-		// Создать новую специализацию
-		r.Post("/", handlers.CreateNewSpecializationHandler(logger, storage))
+		recorder := audit.New(auditService, storage, logger, cfg.TrustedProxies)
 
-		// Удалить специализацию (и всех врачей этой специализации)
-		r.Delete("/{specializationID}", handlers.DeleteSpecializationHandler(logger, storage))
+		r.Route("/MyHelp/specializations", func(r chi.Router) {
+			// Получить список специализаций
+			r.Get("/", handlers.GetPolyclinicInfoHandler(logger, storage))
 
-	})
+			// Получить список врачей определенной специализации
+			r.Get("/{specializationID}", handlers.GetSpecializationDoctorHandler(logger, storage))
 
-	router.Route("/MyHelp/doctors", func(r chi.Router) {
-		// Создать нового врача
-		r.Post("/", handlers.NewDoctorHandler(logger, storage))
+			// Создать новую специализацию
+			r.Post("/", handlers.CreateNewSpecializationHandler(logger, storage, recorder))
 
-		// Удалить врача
-		r.Delete("/{doctorID}", handlers.DeleteDoctorHandler(logger, storage))
+			// Удалить специализацию (и всех врачей этой специализации)
+			r.Delete("/{specializationID}", handlers.DeleteSpecializationHandler(logger, storage, recorder))
 
-	})
+		})
+
+		r.Route("/MyHelp/doctors", func(r chi.Router) {
+			// Создать нового врача
+			r.Post("/", handlers.NewDoctorHandler(logger, storage, recorder))
+
+			// Удалить врача
+			r.Delete("/{doctorID}", handlers.DeleteDoctorHandler(logger, storage, recorder))
+
+		})
 
-	router.Route("/MyHelp/schedule/doctors", func(r chi.Router) {
-		// Получить расписание врача
-		r.Get("/{doctorID}", handlers.GetScheduleDoctorByIdHandler(logger, storage))
+		r.Route("/MyHelp/schedule/doctors", func(r chi.Router) {
+			// Получить расписание врача
+			r.Get("/{doctorID}", handlers.GetScheduleDoctorByIdHandler(logger, storage))
 
-		// Добавить расписание
-		r.Post("/{doctorID}", handlers.NewScheduleHandler(logger, storage, scheduleUseCase))
+			// Добавить расписание
+			r.Post("/{doctorID}", handlers.NewScheduleHandler(logger, storage, scheduleUseCase, recorder))
+		})
 	})
 
 	return router
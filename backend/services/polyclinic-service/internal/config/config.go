@@ -2,6 +2,7 @@ package config
 
 import (
 	"log"
+	"net/netip"
 	"os"
 	"time"
 
@@ -12,12 +13,42 @@ type Config struct {
 	Env             string `yaml:"env" env-default:"local"`
 	DatabaseBaseUrl string `yaml:"database_connection_url" env-required:"true"`
 	HTTPServer      `yaml:"http_server"`
+	Auth            `yaml:"auth"`
+	Tracing         `yaml:"tracing"`
 }
 
 type HTTPServer struct {
 	Address     string        `yaml:"address" env-default:"localhost:8080"`
 	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
+	// Отдельный адрес для /metrics, чтобы метрики не были доступны снаружи вместе с API.
+	// Пустое значение отключает метрики.
+	MetricsAddress string `yaml:"metrics_address" env:"METRICS_ADDRESS" env-default:"localhost:9084"`
+	// Сколько /readyz отвечает отказом до остановки сервера, чтобы балансировщик успел
+	// снять экземпляр с ротации
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" env-default:"0s"`
+	// Адреса api-gateway. X-Forwarded-For учитывается только в запросах с этих адресов,
+	// по умолчанию не учитывается совсем
+	TrustedProxies []netip.Prefix `yaml:"trusted_proxies" env:"TRUSTED_PROXIES" env-separator:","`
+}
+
+// Проверка access токенов в самом сервисе. Заголовкам личности без проверенного токена
+// сервис не доверяет, поэтому jwks_url обязателен.
+type Auth struct {
+	JWKSURL         string        `yaml:"jwks_url" env:"JWKS_URL" env-required:"true"`
+	Issuer          string        `yaml:"issuer" env-default:"myhelp-api-gateway"`
+	Audience        string        `yaml:"audience" env-default:"myhelp"`
+	ClockSkew       time.Duration `yaml:"clock_skew" env-default:"30s"`
+	RefreshInterval time.Duration `yaml:"jwks_refresh_interval" env-default:"10m"`
+}
+
+// Трассировка OpenTelemetry. Exporter: otlp (коллектор по OTLP/HTTP), stdout или file для
+// локального запуска, none - спаны не записываются, но traceparent пробрасывается дальше.
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER" env-default:"none"`
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" env-default:"http://localhost:4318"`
+	FilePath    string  `yaml:"file_path" env-default:"traces.json"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" env-default:"1"`
 }
 
 func MustLoad() *Config {
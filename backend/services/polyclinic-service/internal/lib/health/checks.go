@@ -0,0 +1,36 @@
+package health
+
+import (
+	"context"
+	"fmt"
+)
+
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type MigrationSource interface {
+	// MigrationVersion возвращает примененную версию схемы и признак незавершенной миграции
+	MigrationVersion(ctx context.Context) (int, bool, error)
+}
+
+func Database(db Pinger) Check {
+	return db.Ping
+}
+
+// Migrations проверяет, что схема БД не старше expected и последняя миграция завершилась
+func Migrations(source MigrationSource, expected int) Check {
+	return func(ctx context.Context) error {
+		version, dirty, err := source.MigrationVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("migration %d is dirty", version)
+		}
+		if version < expected {
+			return fmt.Errorf("schema version %d, expected at least %d", version, expected)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,9 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var ScheduleSlotsPublished = factory.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "schedule_slots_published_total",
+	Help:      "Schedule slots published for booking.",
+})
@@ -6,11 +6,12 @@ import (
 	"fmt"
 	"github.com/daariikk/MyHelp/services/polyclinic-service/internal/domain"
 	"github.com/pkg/errors"
+	"log/slog"
 	"time"
 )
 
-func (s *Storage) CalculateRating(doctorID *int, specializationID *int) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+func (s *Storage) CalculateRating(ctx context.Context, doctorID *int, specializationID *int) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
 	// Определяем условие WHERE в зависимости от входных параметров
@@ -52,7 +53,7 @@ func (s *Storage) CalculateRating(doctorID *int, specializationID *int) error {
 
 	// Для каждого врача рассчитываем и обновляем рейтинг
 	for _, id := range doctorIDs {
-		if err := s.calculateAndUpdateRatingForDoctor(id); err != nil {
+		if err := s.calculateAndUpdateRatingForDoctor(ctx, id); err != nil {
 			s.logger.Error(fmt.Sprintf("Failed to update rating for doctor %d: %v", id, err))
 			// Продолжаем для остальных врачей, даже если один не удался
 			continue
@@ -62,15 +63,15 @@ func (s *Storage) CalculateRating(doctorID *int, specializationID *int) error {
 	return nil
 }
 
-func (s *Storage) calculateAndUpdateRatingForDoctor(doctorID int) error {
-	s.logger.Debug("Calculating rating for doctor", doctorID)
+func (s *Storage) calculateAndUpdateRatingForDoctor(ctx context.Context, doctorID int) error {
+	s.logger.DebugContext(ctx, "Calculating rating for doctor", slog.Int("doctor_id", doctorID))
 	query := `
 	SELECT avg(rating) FROM appointments
 	WHERE doctor_id = $1 AND rating IS NOT NULL ;
 `
 	var avgRatingRaw sql.NullFloat64
 
-	err := s.connection.QueryRow(context.Background(), query, doctorID).Scan(&avgRatingRaw)
+	err := s.connection.QueryRow(ctx, query, doctorID).Scan(&avgRatingRaw)
 	if err != nil {
 		s.logger.Error(fmt.Sprintf("Error query in database for calculate rating for doctorID=%v", doctorID))
 		return fmt.Errorf("failed to calculate average rating: %w", err)
@@ -87,7 +88,7 @@ func (s *Storage) calculateAndUpdateRatingForDoctor(doctorID int) error {
 	SET rating = $1
 	WHERE id = $2
 `
-	_, err = s.connection.Exec(context.Background(), query, avgRatingRaw.Float64, doctorID)
+	_, err = s.connection.Exec(ctx, query, avgRatingRaw.Float64, doctorID)
 	if err != nil {
 		s.logger.Error(fmt.Sprintf("Error updating rating for doctorID=%v", doctorID))
 		return fmt.Errorf("failed to update doctor rating: %w", err)
@@ -96,13 +97,13 @@ func (s *Storage) calculateAndUpdateRatingForDoctor(doctorID int) error {
 	return nil
 }
 
-func (s *Storage) NewDoctor(newDoctor domain.Doctor) (domain.Doctor, error) {
+func (s *Storage) NewDoctor(ctx context.Context, newDoctor domain.Doctor) (domain.Doctor, error) {
 	subQuery := `
 	select id from specialization
 	where specialization_doctor=$1
 `
 	var specializationID int
-	err := s.connection.QueryRow(context.Background(), subQuery, newDoctor.Specialization).Scan(
+	err := s.connection.QueryRow(ctx, subQuery, newDoctor.Specialization).Scan(
 		&specializationID,
 	)
 	s.logger.Info("specializationID", "specializationID", specializationID)
@@ -117,7 +118,7 @@ func (s *Storage) NewDoctor(newDoctor domain.Doctor) (domain.Doctor, error) {
 	RETURNING id
 `
 	var doctorID int64
-	err = s.connection.QueryRow(context.Background(), query,
+	err = s.connection.QueryRow(ctx, query,
 		newDoctor.Surname,
 		newDoctor.Name,
 		newDoctor.Patronymic,
@@ -132,7 +133,7 @@ func (s *Storage) NewDoctor(newDoctor domain.Doctor) (domain.Doctor, error) {
 
 	s.logger.Info("New doctor", "id", doctorID)
 
-	createdDoctor, err := s.GetDoctorById(int(doctorID))
+	createdDoctor, err := s.GetDoctorById(ctx, int(doctorID))
 	if err != nil {
 		s.logger.Error(fmt.Sprintf("Not found doctor=%v", doctorID))
 		return domain.Doctor{}, errors.Wrapf(err, "doctor with id %d not found", doctorID)
@@ -141,14 +142,14 @@ func (s *Storage) NewDoctor(newDoctor domain.Doctor) (domain.Doctor, error) {
 	return createdDoctor, nil
 }
 
-func (s *Storage) DeleteDoctor(doctorID int) (bool, error) {
+func (s *Storage) DeleteDoctor(ctx context.Context, doctorID int) (bool, error) {
 	var isDeleted bool
 
 	query := `
 	DELETE FROM doctors
 	WHERE id = $1
 `
-	_, err := s.connection.Exec(context.Background(), query, doctorID)
+	_, err := s.connection.Exec(ctx, query, doctorID)
 	if err != nil {
 		s.logger.Error("Failed to deleted doctor", "doctorID", doctorID, "error", err)
 		return false, err
@@ -159,8 +160,8 @@ func (s *Storage) DeleteDoctor(doctorID int) (bool, error) {
 
 }
 
-func (s *Storage) GetDoctorById(doctorID int) (domain.Doctor, error) {
-	err := s.CalculateRating(&doctorID, nil)
+func (s *Storage) GetDoctorById(ctx context.Context, doctorID int) (domain.Doctor, error) {
+	err := s.CalculateRating(ctx, &doctorID, nil)
 	if err != nil {
 		s.logger.Error(err.Error())
 	}
@@ -181,7 +182,7 @@ func (s *Storage) GetDoctorById(doctorID int) (domain.Doctor, error) {
 `
 	var doctor domain.Doctor
 	var surname, name, patronymic, photoPath sql.NullString
-	err = s.connection.QueryRow(context.Background(), query, doctorID).Scan(
+	err = s.connection.QueryRow(ctx, query, doctorID).Scan(
 		&doctor.Id,
 		&surname,
 		&name,
@@ -8,12 +8,11 @@ import (
 	"time"
 )
 
-func (s *Storage) CreateNewScheduleForDoctor(doctorID int, records []domain.Record) error {
+func (s *Storage) CreateNewScheduleForDoctor(ctx context.Context, doctorID int, records []domain.Record) error {
 	query := `
 		INSERT INTO doctor_schedules (doctor_id, date, start_time, end_time, is_available)
 		VALUES ($1, $2, $3, $4, $5)
 	`
-	ctx := context.Background()
 
 	tx, err := s.connection.Begin(ctx)
 	if err != nil {
@@ -43,9 +42,9 @@ func (s *Storage) CreateNewScheduleForDoctor(doctorID int, records []domain.Reco
 	return nil
 }
 
-func (s *Storage) GetScheduleForDoctor(doctorID int, date time.Time) ([]domain.Record, error) {
+func (s *Storage) GetScheduleForDoctor(ctx context.Context, doctorID int, date time.Time) ([]domain.Record, error) {
 	// Создаем контекст с таймаутом
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	query := `
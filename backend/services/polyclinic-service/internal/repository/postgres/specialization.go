@@ -5,27 +5,30 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/daariikk/MyHelp/services/polyclinic-service/internal/domain"
-	"github.com/jackc/pgx/v5"
+	"github.com/daariikk/MyHelp/services/polyclinic-service/internal/lib/tracing"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pkg/errors"
 	"log/slog"
-	"time"
 )
 
 type Storage struct {
-	connection *pgx.Conn
+	connection *pgxpool.Pool
 	logger     *slog.Logger
 }
 
 func New(ctx context.Context, logger *slog.Logger, url string) (*Storage, error) {
 	// Парсим URL для получения конфигурации
-	config, err := pgx.ParseConfig(url)
+	config, err := pgxpool.ParseConfig(url)
 	if err != nil {
 		logger.Error("Failed to parse postgres connection string", "error", err)
 		return nil, errors.Wrap(err, "failed to parse postgres connection string")
 	}
 
+	// Каждый запрос попадает в трассу отдельным спаном
+	config.ConnConfig.Tracer = tracing.QueryTracer{}
+
 	// Устанавливаем соединение с конфигурацией
-	conn, err := pgx.ConnectConfig(ctx, config)
+	conn, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		logger.Error("Failed to connect to postgres", "error", err)
 		return nil, errors.Wrapf(err, "failed to connect to postgres")
@@ -33,6 +36,7 @@ func New(ctx context.Context, logger *slog.Logger, url string) (*Storage, error)
 
 	// Проверяем соединение
 	if err := conn.Ping(ctx); err != nil {
+		conn.Close()
 		logger.Error("Failed to ping postgres", "error", err)
 		return nil, errors.Wrap(err, "failed to ping postgres")
 	}
@@ -46,19 +50,18 @@ func (s *Storage) Close() error {
 		return nil
 	}
 
-	// Создаем контекст с таймаутом для закрытия соединения
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if err := s.connection.Close(ctx); err != nil {
-		s.logger.Error("Failed to close postgres connection", "error", err)
-		return errors.Wrap(err, "failed to close postgres connection")
-	}
+	// Ждет возврата занятых соединений в пул
+	s.connection.Close()
 
 	s.logger.Info("Postgres connection closed successfully")
 	return nil
 }
 
+// Stat возвращает статистику пула соединений для метрик
+func (s *Storage) Stat() *pgxpool.Stat {
+	return s.connection.Stat()
+}
+
 // Дополнительный метод для проверки соединения
 func (s *Storage) Ping(ctx context.Context) error {
 	if s.connection == nil {
@@ -67,14 +70,27 @@ func (s *Storage) Ping(ctx context.Context) error {
 	return s.connection.Ping(ctx)
 }
 
-func (s *Storage) GetAllSpecializations() ([]domain.Specialization, error) {
+// MigrationVersion возвращает версию схемы из таблицы schema_migrations, которую ведет migrate
+func (s *Storage) MigrationVersion(ctx context.Context) (int, bool, error) {
+	var (
+		version int
+		dirty   bool
+	)
+	err := s.connection.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		return 0, false, errors.Wrap(err, "failed to get migration version")
+	}
+	return version, dirty, nil
+}
+
+func (s *Storage) GetAllSpecializations(ctx context.Context) ([]domain.Specialization, error) {
 	query := `
 		SELECT id, specialization, specialization_doctor, description
 		FROM specialization 
 `
 	var specializations []domain.Specialization
 
-	rows, err := s.connection.Query(context.Background(), query)
+	rows, err := s.connection.Query(ctx, query)
 	if err != nil {
 		s.logger.Error(fmt.Sprintf("Error execution sql query: %v", err))
 		return nil, errors.Wrapf(err, "Error executing sql query: %v", query)
@@ -101,8 +117,8 @@ func (s *Storage) GetAllSpecializations() ([]domain.Specialization, error) {
 	return specializations, nil
 }
 
-func (s *Storage) GetSpecializationAllDoctor(specializationID int) ([]domain.Doctor, error) {
-	err := s.CalculateRating(nil, &specializationID)
+func (s *Storage) GetSpecializationAllDoctor(ctx context.Context, specializationID int) ([]domain.Doctor, error) {
+	err := s.CalculateRating(ctx, nil, &specializationID)
 	if err != nil {
 		s.logger.Error(fmt.Sprintf("Error calculating rating: %v", err))
 	}
@@ -121,7 +137,7 @@ func (s *Storage) GetSpecializationAllDoctor(specializationID int) ([]domain.Doc
 		WHERE specialization_id = $1
 `
 	var doctors []domain.Doctor
-	rows, err := s.connection.Query(context.Background(), query, specializationID)
+	rows, err := s.connection.Query(ctx, query, specializationID)
 	if err != nil {
 		s.logger.Error(fmt.Sprintf("Error execution sql query: %v", err))
 		return nil, errors.Wrapf(err, "Error executing sql query: %v", query)
@@ -166,7 +182,7 @@ func (s *Storage) GetSpecializationAllDoctor(specializationID int) ([]domain.Doc
 	return doctors, nil
 }
 
-func (s *Storage) CreateNewSpecialization(specialization domain.Specialization) (int, error) {
+func (s *Storage) CreateNewSpecialization(ctx context.Context, specialization domain.Specialization) (int, error) {
 	query := `
 	INSERT INTO specialization (specialization, specialization_doctor, description ) 
 	VALUES ($1, $2, $3)
@@ -174,7 +190,7 @@ func (s *Storage) CreateNewSpecialization(specialization domain.Specialization)
 `
 	var specializationId int
 
-	err := s.connection.QueryRow(context.Background(), query,
+	err := s.connection.QueryRow(ctx, query,
 		specialization.Specialization,
 		specialization.SpecializationDoctor,
 		specialization.Description,
@@ -187,11 +203,11 @@ func (s *Storage) CreateNewSpecialization(specialization domain.Specialization)
 
 	return specializationId, nil
 }
-func (s *Storage) DeleteSpecialization(specializationID int) (bool, error) {
+func (s *Storage) DeleteSpecialization(ctx context.Context, specializationID int) (bool, error) {
 	query := `
 	DELETE FROM specialization WHERE id = $1
 `
-	_, err := s.connection.Exec(context.Background(), query, specializationID)
+	_, err := s.connection.Exec(ctx, query, specializationID)
 	if err != nil {
 		s.logger.Error(fmt.Sprintf("Error executing sql query: %v", err))
 		return false, errors.Wrapf(err, "Error executing sql query: %v", query)